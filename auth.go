@@ -0,0 +1,270 @@
+package gopcxmlda
+
+import (
+	"cmp"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maxAuthAttempts limits the requests sent for one operation of an Authenticator,
+// enough for a challenge/response scheme like Digest and the three legs of NTLM.
+const maxAuthAttempts = 4
+
+// Authenticator sets the credentials of the HTTP requests sent to a server.
+type Authenticator interface {
+	// Authorize sets the credentials of req before it is sent. challenge is nil for the first attempt.
+	// If the server answers 401 Unauthorized, Authorize is called with that response for a new
+	// attempt and returns false if it can not answer the challenge, e.g. for wrong credentials.
+	// Returning false for the first attempt fails the request without sending it.
+	Authorize(req *http.Request, challenge *http.Response) (bool, error)
+}
+
+// WithAuthenticator sets the Authenticator of all requests sent to the server.
+func WithAuthenticator(authenticator Authenticator) ServerOption {
+	return func(c *serverConfig) {
+		c.authenticator = authenticator
+	}
+}
+
+// BasicAuth sends the username and password with every request, see RFC 7617.
+// It should only be used with HTTPS.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authorize sets the Authorization header. A challenge is not answered, the credentials were wrong.
+func (a BasicAuth) Authorize(req *http.Request, challenge *http.Response) (bool, error) {
+	if challenge != nil {
+		return false, nil
+	}
+	req.SetBasicAuth(a.Username, a.Password)
+	return true, nil
+}
+
+// BearerToken sends a static token with every request, see RFC 6750.
+type BearerToken string
+
+// Authorize sets the Authorization header. A challenge is not answered, the token was rejected.
+func (t BearerToken) Authorize(req *http.Request, challenge *http.Response) (bool, error) {
+	if challenge != nil {
+		return false, nil
+	}
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return true, nil
+}
+
+// DigestAuth answers the Digest challenges of a server, see RFC 7616. The nonce of the last challenge
+// is reused for the following requests, so only the first request and requests after the nonce became
+// stale are sent twice. The algorithms MD5 and SHA-256 are supported, with or without "-sess", and
+// the qop "auth". A DigestAuth is safe for concurrent use and must not be copied.
+type DigestAuth struct {
+	Username string
+	Password string
+
+	mu    sync.Mutex
+	param map[string]string // parameters of the last challenge
+	nc    int               // nonce count of the last request with the nonce
+}
+
+// NewDigestAuth returns a DigestAuth with the given credentials.
+func NewDigestAuth(username, password string) *DigestAuth {
+	return &DigestAuth{Username: username, Password: password}
+}
+
+// Authorize sets the Authorization header for the nonce of the last challenge. Without a challenge
+// no header is set. The challenge of a request with credentials is only answered if the nonce was stale.
+func (a *DigestAuth) Authorize(req *http.Request, challenge *http.Response) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if challenge != nil {
+		param, ok := authChallenge(challenge, "Digest")
+		if !ok {
+			return false, nil
+		}
+		authorized := challenge.Request != nil && challenge.Request.Header.Get("Authorization") != ""
+		if authorized && !strings.EqualFold(param["stale"], "true") {
+			// the credentials were rejected, not only the nonce
+			return false, nil
+		}
+		a.param, a.nc = param, 0
+	}
+	if a.param == nil {
+		return true, nil
+	}
+	header, err := a.response(req)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", header)
+	return true, nil
+}
+
+// response returns the Authorization header for req with the next nonce count.
+func (a *DigestAuth) response(req *http.Request) (string, error) {
+	algorithm := cmp.Or(a.param["algorithm"], "MD5")
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+	h := func(s string) string {
+		digest := newHash()
+		digest.Write([]byte(s))
+		return hex.EncodeToString(digest.Sum(nil))
+	}
+
+	qop := ""
+	if offered := a.param["qop"]; offered != "" {
+		for _, q := range strings.Split(offered, ",") {
+			if strings.TrimSpace(q) == "auth" {
+				qop = "auth"
+			}
+		}
+		if qop == "" {
+			return "", fmt.Errorf("unsupported digest qop %q", offered)
+		}
+	}
+	cnonce, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	a.nc++
+	nc := fmt.Sprintf("%08x", a.nc)
+	realm, nonce, uri := a.param["realm"], a.param["nonce"], req.URL.RequestURI()
+
+	ha1 := h(a.Username + ":" + realm + ":" + a.Password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(req.Method + ":" + uri)
+	var response string
+	if qop == "" {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":" + qop + ":" + ha2)
+	}
+
+	header := fmt.Sprintf(`Digest username=%q, realm=%q, nonce=%q, uri=%q, algorithm=%s, response=%q`,
+		a.Username, realm, nonce, uri, algorithm, response)
+	if qop != "" {
+		header += fmt.Sprintf(`, qop=%s, nc=%s, cnonce=%q`, qop, nc, cnonce)
+	}
+	if opaque, ok := a.param["opaque"]; ok {
+		header += fmt.Sprintf(`, opaque=%q`, opaque)
+	}
+	return header, nil
+}
+
+// NegotiateAuth is a hook for connection based handshakes like NTLM or Negotiate (SPNEGO), whose tokens
+// are created by an external package. The token of the first request is sent without a challenge,
+// following tokens answer the challenges of the server. The HTTP transport must keep the connection
+// alive between the requests of a handshake, like the default transport does.
+type NegotiateAuth struct {
+	Scheme string // scheme of the WWW-Authenticate and Authorization headers, e.g. "NTLM" or "Negotiate"
+	// Token returns the next token of the handshake for the token of the server's challenge,
+	// which is nil for the first request.
+	Token func(challenge []byte) ([]byte, error)
+}
+
+// Authorize sets the Authorization header with the next token of the handshake.
+// A challenge without token is not answered, the handshake failed.
+func (a NegotiateAuth) Authorize(req *http.Request, challenge *http.Response) (bool, error) {
+	var serverToken []byte
+	if challenge != nil {
+		param, ok := authChallenge(challenge, a.Scheme)
+		if !ok || param[""] == "" {
+			return false, nil
+		}
+		var err error
+		if serverToken, err = base64.StdEncoding.DecodeString(param[""]); err != nil {
+			return false, fmt.Errorf("%s challenge: %w", a.Scheme, err)
+		}
+	}
+	token, err := a.Token(serverToken)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", a.Scheme+" "+base64.StdEncoding.EncodeToString(token))
+	return true, nil
+}
+
+// authChallenge returns the parameters of the WWW-Authenticate challenge of scheme. A token like
+// the one of NTLM is returned with an empty name.
+func authChallenge(resp *http.Response, scheme string) (map[string]string, bool) {
+	for _, header := range resp.Header.Values("WWW-Authenticate") {
+		name, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+		if !strings.EqualFold(name, scheme) {
+			continue
+		}
+		return parseAuthParams(strings.TrimSpace(rest)), true
+	}
+	return nil, false
+}
+
+// parseAuthParams parses the comma separated name=value pairs of a challenge, values may be quoted.
+// A parameter without "=" is returned with an empty name.
+func parseAuthParams(s string) map[string]string {
+	param := make(map[string]string)
+	for s != "" {
+		var name, value string
+		i := strings.IndexAny(s, "=,")
+		if i < 0 || s[i] == ',' || isToken68(s) {
+			// a token like base64, which may end with "="
+			token, rest, _ := strings.Cut(s, ",")
+			param[""] = strings.TrimSpace(token)
+			s = strings.TrimSpace(rest)
+			continue
+		}
+		name, s = strings.ToLower(strings.TrimSpace(s[:i])), strings.TrimSpace(s[i+1:])
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i = 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value = b.String()
+			_, s, _ = strings.Cut(s[min(i+1, len(s)):], ",")
+		} else {
+			value, s, _ = strings.Cut(s, ",")
+			value = strings.TrimSpace(value)
+		}
+		param[name] = value
+		s = strings.TrimSpace(s)
+	}
+	return param
+}
+
+// isToken68 reports whether s is a single token68 of RFC 7235, i.e. base64 which may end with "=".
+func isToken68(s string) bool {
+	trimmed := strings.TrimRight(s, "=")
+	if trimmed == "" || len(trimmed) == len(s) && strings.ContainsAny(s, "=") {
+		return false
+	}
+	return !strings.ContainsAny(trimmed, `=, "`)
+}
+
+// randomHex returns n random bytes as hex.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Join(errors.New("no random bytes"), err)
+	}
+	return hex.EncodeToString(b), nil
+}
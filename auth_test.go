@@ -0,0 +1,214 @@
+package gopcxmlda_test
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dernate/gopcxmlda"
+	"github.com/dernate/gopcxmlda/opctest"
+)
+
+// newAuthServer starts a server which passes the requests accepted by authorize to a simulated
+// server and answers the others with 401 Unauthorized and the header WWW-Authenticate.
+func newAuthServer(t *testing.T, authorize func(w http.ResponseWriter, r *http.Request) bool) string {
+	t.Helper()
+	srv := opctest.NewServer(offlineTags)
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorize(w, r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(auth.Close)
+	return auth.URL
+}
+
+// getStatus calls GetStatus of the server at rawURL with the authenticator.
+func getStatus(rawURL string, authenticator gopcxmlda.Authenticator) error {
+	u, _ := url.Parse(rawURL)
+	s := gopcxmlda.NewServer(u, "en-US", gopcxmlda.WithAuthenticator(authenticator))
+	var ClientRequestHandle string
+	_, err := s.GetStatus(context.Background(), &ClientRequestHandle, "")
+	return err
+}
+
+func TestBasicAuth(t *testing.T) {
+	rawURL := newAuthServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("WWW-Authenticate", `Basic realm="opc"`)
+		user, pass, ok := r.BasicAuth()
+		return ok && user == "operator" && pass == "secret"
+	})
+	if err := getStatus(rawURL, gopcxmlda.BasicAuth{Username: "operator", Password: "secret"}); err != nil {
+		t.Error(err)
+	}
+	if err := getStatus(rawURL, gopcxmlda.BasicAuth{Username: "operator", Password: "wrong"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("wrong password: %v", err)
+	}
+	if err := getStatus(rawURL, nil); err == nil {
+		t.Error("no error without authenticator")
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	rawURL := newAuthServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="opc"`)
+		return r.Header.Get("Authorization") == "Bearer t0ken"
+	})
+	if err := getStatus(rawURL, gopcxmlda.BearerToken("t0ken")); err != nil {
+		t.Error(err)
+	}
+	if err := getStatus(rawURL, gopcxmlda.BearerToken("other")); err == nil {
+		t.Error("no error for wrong token")
+	}
+}
+
+// denyAuth declines to authorize any request.
+type denyAuth struct{}
+
+func (denyAuth) Authorize(*http.Request, *http.Response) (bool, error) {
+	return false, nil
+}
+
+func TestDeclinedAuth(t *testing.T) {
+	rawURL := newAuthServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		t.Error("request sent although authorization was declined")
+		return true
+	})
+	if err := getStatus(rawURL, denyAuth{}); err == nil || !strings.Contains(err.Error(), "authorization declined") {
+		t.Errorf("got %v", err)
+	}
+}
+
+// digestServer enforces Digest authentication with qop auth. It issues a new nonce for every
+// challenge and rejects nonces after maxUses requests as stale.
+type digestServer struct {
+	algorithm string
+	maxUses   int
+
+	mu         sync.Mutex
+	challenges int
+	nonces     map[string]int // last nonce count by nonce
+}
+
+var digestParam = regexp.MustCompile(`(\w+)=("([^"]*)"|[^,\s]*)`)
+
+func (d *digestServer) authorize(w http.ResponseWriter, r *http.Request) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	param := make(map[string]string)
+	if auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Digest "); ok {
+		for _, m := range digestParam.FindAllStringSubmatch(auth, -1) {
+			param[m[1]] = strings.Trim(m[2], `"`)
+		}
+	}
+	stale := false
+	if last, ok := d.nonces[param["nonce"]]; ok {
+		var nc int
+		fmt.Sscanf(param["nc"], "%x", &nc)
+		newHash := md5.New
+		if strings.HasPrefix(d.algorithm, "SHA-256") {
+			newHash = sha256.New
+		}
+		h := func(s string) string {
+			digest := newHash()
+			digest.Write([]byte(s))
+			return hex.EncodeToString(digest.Sum(nil))
+		}
+		ha1 := h("operator:opc:secret")
+		if strings.HasSuffix(d.algorithm, "-sess") {
+			ha1 = h(ha1 + ":" + param["nonce"] + ":" + param["cnonce"])
+		}
+		ha2 := h(r.Method + ":" + r.URL.RequestURI())
+		expected := h(ha1 + ":" + param["nonce"] + ":" + param["nc"] + ":" + param["cnonce"] + ":auth:" + ha2)
+		switch {
+		case param["response"] != expected || param["opaque"] != "0pa" || param["uri"] != r.URL.RequestURI():
+		case nc <= last:
+			// replayed nonce count
+		case nc > d.maxUses:
+			stale = true
+		default:
+			d.nonces[param["nonce"]] = nc
+			return true
+		}
+	}
+	d.challenges++
+	nonce := fmt.Sprintf("n%d", d.challenges)
+	d.nonces[nonce] = 0
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="opc", qop="auth,auth-int", nonce=%q, opaque="0pa", algorithm=%s, stale=%v`,
+		nonce, d.algorithm, stale))
+	return false
+}
+
+func TestDigestAuth(t *testing.T) {
+	for _, algorithm := range []string{"MD5", "SHA-256", "MD5-sess"} {
+		t.Run(algorithm, func(t *testing.T) {
+			d := &digestServer{algorithm: algorithm, maxUses: 3, nonces: make(map[string]int)}
+			rawURL := newAuthServer(t, d.authorize)
+			auth := gopcxmlda.NewDigestAuth("operator", "secret")
+			for i := 0; i < 5; i++ {
+				if err := getStatus(rawURL, auth); err != nil {
+					t.Fatal(i, err)
+				}
+			}
+			// the first request and the fourth with the stale nonce are challenged
+			if d.challenges != 2 {
+				t.Errorf("%d challenges, the nonce was not reused", d.challenges)
+			}
+			if err := getStatus(rawURL, gopcxmlda.NewDigestAuth("operator", "wrong")); err == nil || !strings.Contains(err.Error(), "401") {
+				t.Errorf("wrong password: %v", err)
+			}
+		})
+	}
+}
+
+func TestNegotiateAuth(t *testing.T) {
+	// a handshake in the style of NTLM: negotiate, challenge and authenticate message
+	rawURL := newAuthServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "NTLM ")
+		message, _ := base64.StdEncoding.DecodeString(token)
+		switch string(message) {
+		case "negotiate":
+			w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString([]byte("challenge1")))
+		case "authenticate challenge1":
+			return true
+		default:
+			w.Header().Set("WWW-Authenticate", "NTLM")
+		}
+		return false
+	})
+	var challenges []string
+	auth := gopcxmlda.NegotiateAuth{Scheme: "NTLM", Token: func(challenge []byte) ([]byte, error) {
+		challenges = append(challenges, string(challenge))
+		if challenge == nil {
+			return []byte("negotiate"), nil
+		}
+		return append([]byte("authenticate "), challenge...), nil
+	}}
+	if err := getStatus(rawURL, auth); err != nil {
+		t.Fatal(err)
+	}
+	if len(challenges) != 2 || challenges[1] != "challenge1" {
+		t.Errorf("challenges %q", challenges)
+	}
+
+	auth.Token = func([]byte) ([]byte, error) { return []byte("invalid"), nil }
+	if err := getStatus(rawURL, auth); err == nil {
+		t.Error("no error for failed handshake")
+	}
+}
@@ -0,0 +1,127 @@
+package gopcxmlda
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// BrowseIterator iterates over the elements of a browse request and issues further Browse requests
+// with the ContinuationPoint of the server until all elements are returned. The MaxElementsReturned
+// of the options is used as page size.
+//
+// Example:
+//
+//	it := s.BrowseIter(ctx, "My/Path", "", TBrowseOptions{MaxElementsReturned: 100})
+//	for it.Next() {
+//		element := it.Element()
+//		// do something with the element
+//	}
+//	if err := it.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+type BrowseIterator struct {
+	ctx       context.Context
+	server    *Server
+	itemPath  string
+	namespace string
+	options   TBrowseOptions
+
+	elements []TBrowseElement
+	element  TBrowseElement
+	started  bool
+	done     bool
+	err      error
+}
+
+// BrowseIter returns a BrowseIterator over the children of itemPath and options.ItemName.
+// No request is sent before the first call of Next.
+func (s *Server) BrowseIter(ctx context.Context, itemPath string, namespace string, options TBrowseOptions) *BrowseIterator {
+	return &BrowseIterator{
+		ctx:       ctx,
+		server:    s,
+		itemPath:  itemPath,
+		namespace: namespace,
+		options:   options,
+	}
+}
+
+// Next advances to the next element, requesting the next page from the server if needed.
+// It returns false when all elements are returned, ctx is canceled or an error occurred.
+func (it *BrowseIterator) Next() bool {
+	for len(it.elements) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		it.fetch()
+	}
+	it.element = it.elements[0]
+	it.elements = it.elements[1:]
+	return true
+}
+
+// Element returns the current element.
+func (it *BrowseIterator) Element() TBrowseElement {
+	return it.element
+}
+
+// Err returns the error which ended the iteration, if any.
+func (it *BrowseIterator) Err() error {
+	return it.err
+}
+
+// fetch requests the next page of elements.
+func (it *BrowseIterator) fetch() {
+	if it.started && it.options.ContinuationPoint == "" {
+		it.done = true
+		return
+	}
+	it.started = true
+
+	var ClientRequestHandle string
+	response, err := it.server.Browse(it.ctx, it.itemPath, &ClientRequestHandle, it.namespace, it.options)
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.elements = response.Response.Elements
+
+	moreElements, _ := strconv.ParseBool(response.Response.MoreElements)
+	continuationPoint := response.Response.ContinuationPoint
+	switch {
+	case !moreElements || continuationPoint == "":
+		// without ContinuationPoint the server can not return the remaining elements
+		it.options.ContinuationPoint = ""
+		it.done = true
+	case continuationPoint == it.options.ContinuationPoint:
+		it.err = fmt.Errorf("server returned the same ContinuationPoint %q again: %w",
+			continuationPoint, ErrInvalidContinuationPoint)
+	default:
+		it.options.ContinuationPoint = continuationPoint
+	}
+}
+
+// BrowseAll returns all children of itemPath and options.ItemName, following the ContinuationPoints
+// of the server. See BrowseIter to process the elements while they are received.
+//
+// Parameters:
+// - ctx (context.Context): The context of the requests.
+// - itemPath (string): The path of the item to browse.
+// - namespace (string): The namespace to use for the requests.
+// - options (TBrowseOptions): The options of the requests, MaxElementsReturned is the page size.
+//
+// Returns:
+// - ([]TBrowseElement): All elements, or the elements received until an error occurred.
+// - (error): An error if any issues occur during the requests.
+func (s *Server) BrowseAll(ctx context.Context, itemPath string, namespace string, options TBrowseOptions) ([]TBrowseElement, error) {
+	var elements []TBrowseElement
+	it := s.BrowseIter(ctx, itemPath, namespace, options)
+	for it.Next() {
+		elements = append(elements, it.Element())
+	}
+	return elements, it.Err()
+}
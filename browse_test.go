@@ -0,0 +1,64 @@
+package gopcxmlda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// attrOf returns the value of the first attribute name in the request body.
+func attrOf(body string, name string) string {
+	i := strings.Index(body, " "+name+`="`)
+	if i < 0 {
+		return ""
+	}
+	return strings.SplitN(body[i+len(name)+3:], `"`, 2)[0]
+}
+
+func TestBrowseAll(t *testing.T) {
+	var requests []string
+	s, _ := newTestServer(t, func(action string, body string) string {
+		requests = append(requests, attrOf(body, "ContinuationPoint"))
+		pageSize, _ := strconv.Atoi(attrOf(body, "MaxElementsReturned"))
+		start, _ := strconv.Atoi(attrOf(body, "ContinuationPoint"))
+		end := min(start+pageSize, 5)
+		var elements strings.Builder
+		for i := start; i < end; i++ {
+			elements.WriteString(fmt.Sprintf(`<Elements Name="e%d" ItemName="Plant/e%d" IsItem="true"/>`, i, i))
+		}
+		more := ""
+		if end < 5 {
+			more = fmt.Sprintf(` MoreElements="true" ContinuationPoint="%d"`, end)
+		}
+		return soapEnvelope(`<BrowseResponse xmlns="http://opcfoundation.org/webservices/XMLDA/1.0/"` + more + `>` +
+			`<BrowseResult RcvTime="2024-01-01T00:00:00Z" ReplyTime="2024-01-01T00:00:00Z" ServerState="running"/>` +
+			elements.String() + `</BrowseResponse>`)
+	})
+
+	elements, err := s.BrowseAll(context.Background(), "Plant", "", TBrowseOptions{MaxElementsReturned: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 5 || elements[4].Name != "e4" {
+		t.Fatalf("unexpected elements %+v", elements)
+	}
+	if fmt.Sprint(requests) != "[ 2 4]" {
+		t.Fatalf("unexpected continuation points %q", requests)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	requests = nil
+	it := s.BrowseIter(ctx, "Plant", "", TBrowseOptions{MaxElementsReturned: 2})
+	count := 0
+	for it.Next() {
+		count++
+		cancel()
+	}
+	if count != 2 || !errors.Is(it.Err(), context.Canceled) || len(requests) != 1 {
+		t.Fatalf("expected iteration to stop after the first page, got %d elements, %d requests, %v", count, len(requests), it.Err())
+	}
+}
@@ -62,7 +62,7 @@ func GenerateClientHandles(count int) (string, []string, error) {
 // Example:
 //
 //		  _url, _ := url.Parse("http://opc-addr-or-IP.local:8080")
-//			 s := Server{_url, "en-US", 10}
+//			 s := NewServer(_url, "en-US")
 //	         var ClientRequestHandle string
 //				response, ClientRequestHandle, err := s.GetStatus(context.Background, &ClientRequestHandle, "")
 //				if err != nil {
@@ -82,7 +82,11 @@ func (s *Server) GetStatus(ctx context.Context, ClientRequestHandle *string, nam
 		}
 		*ClientRequestHandle = clientRequestHandle
 	}
-	payload := buildGetStatusPayload(s, namespace, ClientRequestHandle)
+	payload, err := buildGetStatusPayload(s, namespace, ClientRequestHandle)
+	if err != nil {
+		logError(err, "GetStatus")
+		return TGetStatus{}, err
+	}
 
 	var errReturn error
 	response, err := send(ctx, s, payload, "GetStatus")
@@ -99,22 +103,7 @@ func (s *Server) GetStatus(ctx context.Context, ClientRequestHandle *string, nam
 		return TGetStatus{}, errReturn
 	}
 
-	if Status.Fault.FaultCode != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Faultcode: %s, Faultstring: %s, Detail: %s",
-				Status.Fault.FaultCode, Status.Fault.FaultString, Status.Fault.Detail,
-			)),
-		)
-	}
-	if Status.Response.Errors.Id != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Id: %s, Text: %s, Type: %s",
-				Status.Response.Errors.Id, Status.Response.Errors.Text, Status.Response.Errors.Type,
-			)),
-		)
-	}
+	errReturn = errors.Join(errReturn, responseError(Status.Fault, Status.Response.Errors))
 
 	if errReturn != nil {
 		logError(errReturn, "GetStatus")
@@ -132,7 +121,7 @@ func (s *Server) GetStatus(ctx context.Context, ClientRequestHandle *string, nam
 // - ClientRequestHandle (*string): The client request handle to use for the request.
 // - ClientItemHandles (*[]string): The client item handles to use for the request.
 // - namespace (string): The namespace to use for the request.
-// - options (map[string]string): The options to use for the request.
+// - options (TRequestOptions): The options to use for the request.
 //
 // Returns:
 // - (TRead): The read result as a TRead struct.
@@ -141,15 +130,15 @@ func (s *Server) GetStatus(ctx context.Context, ClientRequestHandle *string, nam
 // Example:
 //
 //		  _url, _ := url.Parse("http://opc-addr-or-IP.local:8080")
-//			 s := Server{_url, "en-US", 10}
+//			 s := NewServer(_url, "en-US")
 //				items := []TItem{
 //					{
 //						ItemName: "My/Item",
 //					},
 //				}
-//				options := map[string]string{
-//					"ReturnItemTime": "true",
-//					"returnItemPath": "true",
+//				options := TRequestOptions{
+//					ReturnItemTime: true,
+//					ReturnItemPath: true,
 //				}
 //	         var ClientRequestHandle string
 //				response, err := s.Read(context.Background, items, &ClientRequestHandle, options)
@@ -159,7 +148,7 @@ func (s *Server) GetStatus(ctx context.Context, ClientRequestHandle *string, nam
 //					// do something with the response-object TRead
 //				}
 func (s *Server) Read(ctx context.Context, items []TItem, ClientRequestHandle *string, ClientItemHandles *[]string,
-	namespace string, options map[string]interface{}) (TRead, error) {
+	namespace string, options TRequestOptions) (TRead, error) {
 	if namespace == "" {
 		namespace = "ns0"
 	}
@@ -176,7 +165,11 @@ func (s *Server) Read(ctx context.Context, items []TItem, ClientRequestHandle *s
 			*ClientItemHandles = clientItemHandles
 		}
 	}
-	payload := buildReadPayload(s, ClientRequestHandle, ClientItemHandles, namespace, items, options)
+	payload, err := buildReadPayload(s, ClientRequestHandle, ClientItemHandles, namespace, items, options)
+	if err != nil {
+		logError(err, "Read")
+		return TRead{}, err
+	}
 
 	var errReturn error
 	response, err := send(ctx, s, payload, "Read")
@@ -193,22 +186,7 @@ func (s *Server) Read(ctx context.Context, items []TItem, ClientRequestHandle *s
 		return TRead{}, errReturn
 	}
 
-	if R.Fault.FaultCode != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Faultcode: %s, Faultstring: %s, Detail: %s",
-				R.Fault.FaultCode, R.Fault.FaultString, R.Fault.Detail,
-			)),
-		)
-	}
-	if R.Response.Errors.Id != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Id: %s, Text: %s, Type: %s",
-				R.Response.Errors.Id, R.Response.Errors.Text, R.Response.Errors.Type,
-			)),
-		)
-	}
+	errReturn = errors.Join(errReturn, responseError(R.Fault, R.Response.Errors))
 
 	if errReturn != nil {
 		logError(errReturn, "Read")
@@ -236,7 +214,7 @@ func (s *Server) Read(ctx context.Context, items []TItem, ClientRequestHandle *s
 // Example:
 //
 //		  _url, _ := url.Parse("http://opc-addr-or-IP.local:8080")
-//			 s := Server{_url, "en-US", 10}
+//			 s := NewServer(_url, "en-US")
 //	         var ClientRequestHandle string
 //				response, err := s.Browse(context.Background, "My/Item", &ClientRequestHandle, TBrowseOptions{})
 //				if err != nil {
@@ -257,7 +235,11 @@ func (s *Server) Browse(ctx context.Context, itemPath string, ClientRequestHandl
 		}
 		*ClientRequestHandle = clientRequestHandle
 	}
-	payload := buildBrowsePayload(s, ClientRequestHandle, itemPath, namespace, options)
+	payload, err := buildBrowsePayload(s, ClientRequestHandle, itemPath, namespace, options)
+	if err != nil {
+		logError(err, "Browse")
+		return TBrowse{}, err
+	}
 
 	var errReturn error
 	response, err := send(ctx, s, payload, "Browse")
@@ -274,22 +256,7 @@ func (s *Server) Browse(ctx context.Context, itemPath string, ClientRequestHandl
 		return TBrowse{}, errReturn
 	}
 
-	if B.Fault.FaultCode != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Faultcode: %s, Faultstring: %s, Detail: %s",
-				B.Fault.FaultCode, B.Fault.FaultString, B.Fault.Detail,
-			)),
-		)
-	}
-	if B.Response.Errors.Id != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Id: %s, Text: %s, Type: %s",
-				B.Response.Errors.Id, B.Response.Errors.Text, B.Response.Errors.Type,
-			)),
-		)
-	}
+	errReturn = errors.Join(errReturn, responseError(B.Fault, B.Response.Errors))
 
 	if errReturn != nil {
 		logError(errReturn, "Browse")
@@ -308,7 +275,7 @@ func (s *Server) Browse(ctx context.Context, itemPath string, ClientRequestHandl
 // - ClientRequestHandle (*string): The client request handle to use for the request.
 // - ClientItemHandles (*[]string): The client item handles to use for the request.
 // - namespace (string): The namespace to use for the request.
-// - options (map[string]string): The options to use for the request.
+// - options (TRequestOptions): The options to use for the request.
 //
 // Returns:
 // - (T_Write): The write result as a T_Write struct.
@@ -317,7 +284,7 @@ func (s *Server) Browse(ctx context.Context, itemPath string, ClientRequestHandl
 // Example:
 //
 //		  _url, _ := url.Parse("http://opc-addr-or-IP.local:8080")
-//			 s := Server{_url, "en-US", 10}
+//			 s := NewServer(_url, "en-US")
 //			 items := []TItem{
 //				{
 //					ItemName: "My/Item",
@@ -333,17 +300,24 @@ func (s *Server) Browse(ctx context.Context, itemPath string, ClientRequestHandl
 //			 	},
 //			 }
 //	      var ClientRequestHandle string
-//			 response, err := s.Write(context.Background, items, &ClientRequestHandle, map[string]string{})
+//			 response, err := s.Write(context.Background, items, &ClientRequestHandle, TRequestOptions{})
 //			 if err != nil {
 //				t.Fatal(err)
 //			 } else {
 //			 	t.Log(response)
 //			 }
 func (s *Server) Write(ctx context.Context, items []TItem, ClientRequestHandle *string, ClientItemHandles *[]string,
-	namespace string, options map[string]interface{}) (TWrite, error) {
+	namespace string, options TRequestOptions) (TWrite, error) {
 	if namespace == "" {
 		namespace = "ns0"
 	}
+	if s.validator != nil {
+		validated, err := s.ValidateWrite(ctx, items, namespace)
+		if err != nil {
+			return TWrite{}, err
+		}
+		items = validated
+	}
 	if *ClientRequestHandle == "" || len(*ClientItemHandles) == 0 {
 		clientRequestHandle, clientItemHandles, err := GenerateClientHandles(len(items))
 		if err != nil {
@@ -357,7 +331,11 @@ func (s *Server) Write(ctx context.Context, items []TItem, ClientRequestHandle *
 			*ClientItemHandles = clientItemHandles
 		}
 	}
-	payload := buildWritePayload(s, namespace, items, ClientRequestHandle, ClientItemHandles, options)
+	payload, err := buildWritePayload(s, namespace, items, ClientRequestHandle, ClientItemHandles, options)
+	if err != nil {
+		logError(err, "Write")
+		return TWrite{}, err
+	}
 
 	var errReturn error
 	response, err := send(ctx, s, payload, "Write")
@@ -374,22 +352,7 @@ func (s *Server) Write(ctx context.Context, items []TItem, ClientRequestHandle *
 		return TWrite{}, errReturn
 	}
 
-	if W.Fault.FaultCode != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Faultcode: %s, Faultstring: %s, Detail: %s",
-				W.Fault.FaultCode, W.Fault.FaultString, W.Fault.Detail,
-			)),
-		)
-	}
-	if W.Response.Errors.Id != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Id: %s, Text: %s, Type: %s",
-				W.Response.Errors.Id, W.Response.Errors.Text, W.Response.Errors.Type,
-			)),
-		)
-	}
+	errReturn = errors.Join(errReturn, responseError(W.Fault, W.Response.Errors))
 
 	if errReturn != nil {
 		logError(errReturn, "Write")
@@ -409,7 +372,7 @@ func (s *Server) Write(ctx context.Context, items []TItem, ClientRequestHandle *
 // - returnValuesOnReply: A boolean indicating whether to return values on reply.
 // - subscriptionPingRate: An unsigned integer representing the subscription ping rate.
 // - enableBuffering: A boolean indicating whether buffering is enabled.
-// - options: A map of additional options for the subscription.
+// - options: The TRequestOptions for the subscription.
 //
 // Returns:
 // - T_Subscribe: The subscription object.
@@ -418,14 +381,14 @@ func (s *Server) Write(ctx context.Context, items []TItem, ClientRequestHandle *
 // Example:
 //
 //		  _url, _ := url.Parse("http://opc-addr-or-IP.local:8080")
-//			 s := Server{_url, "en-US", 10}
+//			 s := NewServer(_url, "en-US")
 //			 items := []TItem{
 //				 {
 //					 ItemName: "My/Item",
 //				 },
 //			 }
 //	  	 var ClientRequestHandle string
-//			 response, err := s.Subscribe(context.Background, items, &ClientRequestHandle, "", "", false, 0, false, map[string]interface{})
+//			 response, err := s.Subscribe(context.Background, items, &ClientRequestHandle, "", "", false, 0, false, TRequestOptions{})
 //			 if err != nil {
 //				 log.Fatal(err)
 //			 } else {
@@ -433,7 +396,7 @@ func (s *Server) Write(ctx context.Context, items []TItem, ClientRequestHandle *
 //			 }
 func (s *Server) Subscribe(ctx context.Context, items []TItem, ClientRequestHandle *string, ClientItemHandles *[]string,
 	namespace string, returnValuesOnReply bool, subscriptionPingRate uint,
-	options map[string]interface{}) (TSubscribe, error) {
+	options TRequestOptions) (TSubscribe, error) {
 	if namespace == "" {
 		namespace = "ns0"
 	}
@@ -450,8 +413,12 @@ func (s *Server) Subscribe(ctx context.Context, items []TItem, ClientRequestHand
 			*ClientItemHandles = clientItemHandles
 		}
 	}
-	payload := buildSubscribePayload(namespace, items, ClientRequestHandle, ClientItemHandles,
+	payload, err := buildSubscribePayload(s, namespace, items, ClientRequestHandle, ClientItemHandles,
 		returnValuesOnReply, subscriptionPingRate, options)
+	if err != nil {
+		logError(err, "Subscribe")
+		return TSubscribe{}, err
+	}
 
 	var errReturn error
 	response, err := send(ctx, s, payload, "Subscribe")
@@ -468,22 +435,7 @@ func (s *Server) Subscribe(ctx context.Context, items []TItem, ClientRequestHand
 		return TSubscribe{}, errReturn
 	}
 
-	if Sub.Fault.FaultCode != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Faultcode: %s, Faultstring: %s, Detail: %s",
-				Sub.Fault.FaultCode, Sub.Fault.FaultString, Sub.Fault.Detail,
-			)),
-		)
-	}
-	if Sub.Response.Errors.Id != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Id: %s, Text: %s, Type: %s",
-				Sub.Response.Errors.Id, Sub.Response.Errors.Text, Sub.Response.Errors.Type,
-			)),
-		)
-	}
+	errReturn = errors.Join(errReturn, responseError(Sub.Fault, Sub.Response.Errors))
 
 	if errReturn != nil {
 		logError(errReturn, "Subscribe")
@@ -507,7 +459,7 @@ func (s *Server) Subscribe(ctx context.Context, items []TItem, ClientRequestHand
 // Example:
 //
 //		    _url, _ := url.Parse("http://opc-addr-or-IP.local:8080")
-//	     s := Server{_url, "en-US", 10}
+//	     s := NewServer(_url, "en-US")
 //	     var clientRequestHandle string
 //			success, err := s.SubscriptionCancel(context.Background, "subHandle123", "ns1", &ClientRequestHandle)
 //			if err != nil {
@@ -528,7 +480,11 @@ func (s *Server) SubscriptionCancel(ctx context.Context, serverSubHandle string,
 		}
 		*ClientRequestHandle = clientRequestHandle
 	}
-	payload := buildSubscriptionCancelPayload(serverSubHandle, namespace, ClientRequestHandle)
+	payload, err := buildSubscriptionCancelPayload(s, serverSubHandle, namespace, ClientRequestHandle)
+	if err != nil {
+		logError(err, "SubscriptionCancel")
+		return false, err
+	}
 
 	var errReturn error
 	response, err := send(ctx, s, payload, "SubscriptionCancel")
@@ -545,28 +501,13 @@ func (s *Server) SubscriptionCancel(ctx context.Context, serverSubHandle string,
 		return false, errReturn
 	}
 
-	if SC.Fault.FaultCode != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Faultcode: %s, Faultstring: %s, Detail: %s",
-				SC.Fault.FaultCode, SC.Fault.FaultString, SC.Fault.Detail,
-			)),
-		)
-	}
-	if SC.Response.Errors.Id != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Id: %s, Text: %s, Type: %s",
-				SC.Response.Errors.Id, SC.Response.Errors.Text, SC.Response.Errors.Type,
-			)),
-		)
-	}
+	errReturn = errors.Join(errReturn, responseError(SC.Fault, SC.Response.Errors))
 
 	if errReturn != nil {
 		logError(errReturn, "SubscriptionCancel")
 	}
 
-	return true, nil
+	return errReturn == nil, errReturn
 }
 
 // SubscriptionPolledRefresh is a method of the Server struct that refreshes a subscription
@@ -578,7 +519,7 @@ func (s *Server) SubscriptionCancel(ctx context.Context, serverSubHandle string,
 // - SubscriptionPingRate (uint): The rate at which the subscription should be pinged.
 // - namespace (string): The namespace to be used for the subscription. If empty, defaults to "ns0".
 // - ClientRequestHandle (*string): A pointer to a string representing the client request handle. If empty, a new handle will be generated.
-// - options (map[string]interface{}): A map of additional options for the subscription refresh request.
+// - options (TRequestOptions): The options for the subscription refresh request.
 // - ServerTime (T_ServerTime): The server time to be used in the request.
 //
 // Returns:
@@ -588,16 +529,16 @@ func (s *Server) SubscriptionCancel(ctx context.Context, serverSubHandle string,
 // Example:
 //
 //		  _url, _ := url.Parse("http://opc-addr-or-IP.local:8080")
-//			 s := Server{_url, "en-US", 10}
+//			 s := NewServer(_url, "en-US")
 //	      var ClientRequestHandle string
-//			 response, err := s.SubscriptionPolledRefresh(context.Background, "subHandle123", 1000, "ns1", &ClientRequestHandle, map[string]interface{}{}, T_ServerTime{})
+//			 response, err := s.SubscriptionPolledRefresh(context.Background, "subHandle123", 1000, "ns1", &ClientRequestHandle, TRequestOptions{}, T_ServerTime{})
 //			 if err != nil {
 //				 log.Fatal(err)
 //			 } else {
 //				 // do something with the response-object T_SubscriptionPolledRefresh
 //			 }
 func (s *Server) SubscriptionPolledRefresh(ctx context.Context, serverSubHandle string, SubscriptionPingRate uint, namespace string,
-	ClientRequestHandle *string, options map[string]interface{}, ServerTime TServerTime) (TSubscriptionPolledRefresh, error) {
+	ClientRequestHandle *string, options TRequestOptions, ServerTime TServerTime) (TSubscriptionPolledRefresh, error) {
 	if namespace == "" {
 		namespace = "ns0"
 	}
@@ -609,7 +550,7 @@ func (s *Server) SubscriptionPolledRefresh(ctx context.Context, serverSubHandle
 		}
 		*ClientRequestHandle = clientRequestHandle
 	}
-	payload, err := buildSubscriptionPolledRefreshPayload(serverSubHandle, namespace, ClientRequestHandle,
+	payload, err := buildSubscriptionPolledRefreshPayload(s, serverSubHandle, namespace, ClientRequestHandle,
 		SubscriptionPingRate, options, ServerTime)
 	if err != nil {
 		logError(err, "SubscriptionPolledRefresh")
@@ -631,28 +572,10 @@ func (s *Server) SubscriptionPolledRefresh(ctx context.Context, serverSubHandle
 		return TSubscriptionPolledRefresh{}, errReturn
 	}
 
-	if SPR.Fault.FaultCode != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Faultcode: %s, Faultstring: %s, Detail: %s",
-				SPR.Fault.FaultCode, SPR.Fault.FaultString, SPR.Fault.Detail,
-			)),
-		)
-	}
-	if SPR.Response.Errors.Id != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Id: %s, Text: %s, Type: %s",
-				SPR.Response.Errors.Id, SPR.Response.Errors.Text, SPR.Response.Errors.Type,
-			)),
-		)
-	}
+	errReturn = errors.Join(errReturn, responseError(SPR.Fault, SPR.Response.Errors))
 	if len(SPR.Response.InvalidServerSubHandles) > 0 {
 		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"InvalidServerSubHandles: %v",
-				SPR.Response.InvalidServerSubHandles,
-			)),
+			fmt.Errorf("InvalidServerSubHandles: %v: %w", SPR.Response.InvalidServerSubHandles, ErrNoSubscription),
 		)
 	}
 
@@ -679,7 +602,7 @@ func (s *Server) SubscriptionPolledRefresh(ctx context.Context, serverSubHandle
 // Example:
 //
 //		     _url, _ := url.Parse("http://opc-addr-or-IP.local:8080")
-//			 s := Server{_url, "en-US", 10}
+//			 s := NewServer(_url, "en-US")
 //			 items := []TItem{
 //				 {
 //					 ItemName: "My/Item",
@@ -712,7 +635,11 @@ func (s *Server) GetProperties(ctx context.Context, items []TItem, PropertyOptio
 			*ClientRequestHandle = clientRequestHandle
 		}
 	}
-	payload := buildGetPropertiesPayload(s, ClientRequestHandle, namespace, items, PropertyOptions)
+	payload, err := buildGetPropertiesPayload(s, ClientRequestHandle, namespace, items, PropertyOptions)
+	if err != nil {
+		logError(err, "GetProperties")
+		return TGetProperties{}, err
+	}
 
 	var errReturn error
 	response, err := send(ctx, s, payload, "GetProperties")
@@ -729,22 +656,7 @@ func (s *Server) GetProperties(ctx context.Context, items []TItem, PropertyOptio
 		return TGetProperties{}, errReturn
 	}
 
-	if P.Fault.FaultCode != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Faultcode: %s, Faultstring: %s, Detail: %s",
-				P.Fault.FaultCode, P.Fault.FaultString, P.Fault.Detail,
-			)),
-		)
-	}
-	if P.Response.Errors.Id != "" {
-		errReturn = errors.Join(errReturn,
-			errors.New(fmt.Sprintf(
-				"Id: %s, Text: %s, Type: %s",
-				P.Response.Errors.Id, P.Response.Errors.Text, P.Response.Errors.Type,
-			)),
-		)
-	}
+	errReturn = errors.Join(errReturn, responseError(P.Fault, P.Response.Errors))
 
 	if errReturn != nil {
 		logError(errReturn, "GetProperties")
@@ -3,48 +3,56 @@ package gopcxmlda
 import (
 	"bytes"
 	"context"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"reflect"
-	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
-func buildHeader(builder *strings.Builder, namespace string) string {
+// buildHeader writes the envelope up to the opening body element. The SOAP header contains the
+// elements of the header providers of s for operation, s may be nil.
+func buildHeader(builder *strings.Builder, s *Server, namespace string, operation string) error {
 	builder.WriteString(EnvelopeOpen1)
 	builder.WriteString(namespace)
-	builder.WriteString(EnvelopeHeaderToBody)
+	builder.WriteString(EnvelopeOpen2)
+	builder.WriteString(EnvelopeHeaderOpen)
+	if s != nil {
+		encoder := xml.NewEncoder(builder)
+		for _, provider := range s.HeaderProviders {
+			element, err := provider.SOAPHeader(operation)
+			if err != nil {
+				return fmt.Errorf("SOAP header of %s request: %w", operation, err)
+			}
+			if element == nil {
+				continue
+			}
+			if err := encoder.Encode(element); err != nil {
+				return fmt.Errorf("encoding SOAP header of %s request: %w", operation, err)
+			}
+		}
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+	}
+	builder.WriteString(EnvelopeHeaderClose)
+	builder.WriteString(EnvelopeBodyOpenNs1)
 	builder.WriteString(namespace)
 	builder.WriteString(EnvelopeBodyOpenNs2)
-
-	return builder.String()
+	return nil
 }
 
 // send sends a payload to the server and returns the byte response and an error if any.
+// It does not modify s and may be called concurrently.
 func send(ctx context.Context, s *Server, payload string, SOAPAction string) ([]byte, error) {
-	if s.Timeout == 0 {
-		s.Timeout = 10
-	}
 	if _, ok := HeadersSoap[fmt.Sprintf("SOAPAction-%s", SOAPAction)]; !ok {
 		return []byte(""), fmt.Errorf("unknown SOAPAction: %s", SOAPAction)
 	}
 
-	postbody := bytes.NewBuffer([]byte(payload))
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Url.String(), postbody)
-	if err != nil {
-		return []byte(""), err
-	}
-	req.Header.Set("Content-Type", HeadersSoap["content-type"])
-	req.Header.Set("SOAPAction", HeadersSoap[fmt.Sprintf("SOAPAction-%s", SOAPAction)])
-	httpClient := &http.Client{
-		Timeout: s.Timeout,
-	}
-
-	resp, err := httpClient.Do(req)
+	resp, err := sendAuthorized(ctx, s, payload, SOAPAction)
 	if err != nil {
 		return []byte(""), err
 	}
@@ -67,280 +75,227 @@ func send(ctx context.Context, s *Server, payload string, SOAPAction string) ([]
 	return respbody, errReturn
 }
 
-func buildGetStatusPayload(s *Server, namespace string, ClientRequestHandle *string) string {
-	var payload strings.Builder
-	//header
-	payload.WriteString(XmlVersion)
-	buildHeader(&payload, namespace)
-	//body
-	payload.WriteString(fmt.Sprintf(
-		"<%s:GetStatus LocaleID=\"%s\" ClientRequestHandle=\"%s\"></%s:GetStatus>",
-		namespace, s.LocaleID, *ClientRequestHandle, namespace,
-	))
-
-	payload.WriteString(Footer)
-
-	return payload.String()
-}
-
-func buildReadPayload(s *Server, ClientRequestHandle *string, ClientItemHandles *[]string, namespace string,
-	items []TItem, options map[string]interface{}) string {
-	var payload strings.Builder
-	//header
-	payload.WriteString(XmlVersion)
-	buildHeader(&payload, namespace)
-	//body
-	payload.WriteString(fmt.Sprintf(
-		"<%s:Read LocaleID=\"%s\" ClientRequestHandle=\"%s\">", namespace, s.LocaleID, *ClientRequestHandle,
-	))
-	buildOptionItems(&payload, options, namespace)
-	payload.WriteString(fmt.Sprintf("<%s:ItemList>", namespace))
-	payload.WriteString(buildReadItems(items, ClientItemHandles, namespace))
-	payload.WriteString(fmt.Sprintf("</%s:ItemList></%s:Read>", namespace, namespace))
-	payload.WriteString(Footer)
-
-	return payload.String()
-}
-
-func buildOptionItems(optionPayload *strings.Builder, options map[string]interface{}, namespace string) string {
-
-	if len(options) > 0 {
-		optionPayload.WriteString(fmt.Sprintf("<%s:Options", namespace))
-		for key, value := range options {
-			if reflect.TypeOf(value).Kind() == reflect.Bool {
-				optionPayload.WriteString(
-					fmt.Sprintf(" %s=\"%s\"", key, strings.ToLower(fmt.Sprintf("%v", value))),
-				)
-			} else {
-				optionPayload.WriteString(fmt.Sprintf(" %s=\"%v\"", key, value))
+// sendAuthorized posts payload to the server with the credentials of its Authenticator. The request
+// is sent again as long as the server answers 401 Unauthorized and the Authenticator answers the
+// challenge, at most maxAuthAttempts times. The caller must close the body of the response.
+func sendAuthorized(ctx context.Context, s *Server, payload string, SOAPAction string) (*http.Response, error) {
+	var challenge *http.Response
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Url.String(), bytes.NewBufferString(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", HeadersSoap["content-type"])
+		req.Header.Set("SOAPAction", HeadersSoap[fmt.Sprintf("SOAPAction-%s", SOAPAction)])
+		if s.Authenticator != nil {
+			ok, err := s.Authenticator.Authorize(req, challenge)
+			if ok || err != nil {
+				discardBody(challenge)
+			}
+			if err != nil {
+				return nil, errors.Join(fmt.Errorf("authorization failed"), err)
+			}
+			if !ok && challenge == nil {
+				return nil, errors.New("authorization declined")
+			}
+			if !ok {
+				// the last response is returned with its status
+				return challenge, nil
 			}
 		}
-		optionPayload.WriteString("/>")
-	}
 
-	return optionPayload.String()
-}
-
-func buildReadItems(items []TItem, ClientItemHandles *[]string, namespace string) string {
-	var readItems strings.Builder
-
-	for i, item := range items {
-		readItems.WriteString(fmt.Sprintf("<%s:Items ", namespace))
-		if item.ItemName != "" {
-			readItems.WriteString(fmt.Sprintf("ItemName=\"%s\" ", item.ItemName))
+		resp, err := s.httpClient().Do(req)
+		if err != nil {
+			return nil, err
 		}
-		if item.ItemPath != "" {
-			readItems.WriteString(fmt.Sprintf("ItemPath=\"%s\" ", item.ItemPath))
+		if resp.StatusCode != http.StatusUnauthorized || s.Authenticator == nil || attempt == maxAuthAttempts {
+			return resp, nil
 		}
-		readItems.WriteString(fmt.Sprintf("ClientItemHandle=\"%s\"></%s:Items>", (*ClientItemHandles)[i], namespace))
+		challenge = resp
 	}
-
-	return readItems.String()
 }
 
-func buildBrowsePayload(s *Server, ClientRequestHandle *string,
-	itemPath string, namespace string, options TBrowseOptions) string {
-	var payload strings.Builder
-
-	// Header
-	payload.WriteString(XmlVersion)
-	buildHeader(&payload, namespace)
-
-	// Body start
-	payload.WriteString(fmt.Sprintf("<%s:Browse LocaleID=\"%s\" ", namespace, s.LocaleID))
-
-	// Adding parameters
-	payload.WriteString(fmt.Sprintf("ItemPath=\"%s\" ", itemPath))
-
-	payload.WriteString(fmt.Sprintf("ClientRequestHandle=\"%s\" ", *ClientRequestHandle))
-	payload.WriteString(fmt.Sprintf("ItemName=\"%s\" ", options.ItemName))
-	payload.WriteString(fmt.Sprintf("ContinuationPoint=\"%s\" ", options.ContinuationPoint))
-	payload.WriteString(fmt.Sprintf("MaxElementsReturned=\"%d\" ", options.MaxElementsReturned))
-	payload.WriteString(fmt.Sprintf("BrowseFilter=\"%s\" ", options.BrowseFilter))
-	payload.WriteString(fmt.Sprintf("ElementNameFilter=\"%s\" ", options.ElementNameFilter))
-	payload.WriteString(fmt.Sprintf("VendorFilter=\"%s\" ", options.VendorFilter))
-	payload.WriteString(fmt.Sprintf("ReturnAllProperties=\"%s\" ", strconv.FormatBool(options.ReturnAllProperties)))
-	payload.WriteString(fmt.Sprintf("ReturnPropertyValues=\"%s\" ", strconv.FormatBool(options.ReturnPropertyValues)))
-	payload.WriteString(fmt.Sprintf("ReturnErrorText=\"%s\"", strconv.FormatBool(options.ReturnErrorText)))
-
-	// Body end
-	payload.WriteString(fmt.Sprintf("></%s:Browse>", namespace))
-
-	// Footer
-	payload.WriteString(Footer)
-
-	return payload.String()
+// discardBody reads and closes the body of resp if not nil, so its connection is reused, as
+// required by connection based handshakes like NTLM.
+func discardBody(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
 }
 
-func buildWritePayload(s *Server, namespace string, items []TItem, ClientRequestHandle *string, ClientItemHandles *[]string, options map[string]interface{}) string {
-	// make sure all items have a (correct) opc-xml-da type
-	items = setOpcXmlDaTypes(items)
-
+// buildPayload returns the SOAP envelope of a request with body as content of the SOAP body. The body
+// is encoded with encoding/xml as element operation in the namespace of OPC XML-DA, so item names, handles
+// and values are escaped. The namespace prefix is declared for the types of the values, e.g. "ns0:ArrayOfInt".
+// The SOAP header contains the elements of the HeaderProviders of s, see buildHeader.
+func buildPayload(s *Server, namespace string, operation string, body interface{}) (string, error) {
+	if !isNCName(namespace) {
+		return "", fmt.Errorf("invalid namespace prefix %q", namespace)
+	}
 	var payload strings.Builder
 	//header
 	payload.WriteString(XmlVersion)
-	buildHeader(&payload, namespace)
+	if err := buildHeader(&payload, s, namespace, operation); err != nil {
+		return "", err
+	}
 	//body
-	payload.WriteString(fmt.Sprintf("<%s:Write ReturnValuesOnReply=\"true\">", namespace))
-	options["ClientRequestHandle"] = *ClientRequestHandle
-	options["LocaleID"] = s.LocaleID
-	buildOptionItems(&payload, options, namespace)
-	payload.WriteString(fmt.Sprintf("<%s:ItemList>", namespace))
-	payload.WriteString(buildWriteItems(items, namespace, ClientItemHandles))
-	payload.WriteString(fmt.Sprintf("</%s:ItemList></%s:Write>", namespace, namespace))
+	encoder := xml.NewEncoder(&payload)
+	start := xml.StartElement{Name: xml.Name{Space: OpcNamespace, Local: operation}}
+	if err := encoder.EncodeElement(body, start); err != nil {
+		return "", fmt.Errorf("encoding %s request: %w", operation, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
 	payload.WriteString(Footer)
 
-	return payload.String()
+	return payload.String(), nil
 }
 
-func buildWriteItems(items []TItem, namespace string, ClientItemHandles *[]string) string {
-	var writeItems strings.Builder
-
-	for i, item := range items {
-		writeItems.WriteString(fmt.Sprintf("<%s:Items ", namespace))
-		if item.ItemName != "" {
-			writeItems.WriteString(fmt.Sprintf("ItemName=\"%s\" ", item.ItemName))
+// isNCName reports whether name can be used as namespace prefix.
+func isNCName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
 		}
-		writeItems.WriteString(fmt.Sprintf("ClientItemHandle=\"%s\">", (*ClientItemHandles)[i]))
-		writeItems.WriteString(fmt.Sprintf("<%s:Value xsi:type=\"%s:%s\">", namespace, namespace, item.Value.Type))
-		writeItems.WriteString(fmt.Sprintf("%s</%s:Value>", buildWriteItemsValue(item.Value, namespace), namespace))
-		writeItems.WriteString(fmt.Sprintf("</%s:Items>", namespace))
 	}
-
-	return writeItems.String()
+	return name != ""
 }
 
-func buildWriteItemsValue(value TValue, namespace string) string {
-	var writeItemsValue strings.Builder
+func buildGetStatusPayload(s *Server, namespace string, ClientRequestHandle *string) (string, error) {
+	return buildPayload(s, namespace, "GetStatus", GetStatusRequest{
+		LocaleID:            s.LocaleID,
+		ClientRequestHandle: *ClientRequestHandle,
+	})
+}
 
-	if valueIsArrayOrSlice(value.Value) {
-		vo := reflect.ValueOf(value.Value)
-		for i := 0; i < vo.Len(); i++ {
-			v := vo.Index(i).Interface()
-			valueType, err := getOpcXmlDaType(v)
-			if err != nil {
-				logError(err, "buildWriteItems_Value")
-			}
-			writeItemsValue.WriteString(
-				fmt.Sprintf("<%s:%s>%v</%s:%s>", namespace, valueType, v, namespace, valueType),
-			)
-		}
-	} else {
-		writeItemsValue.WriteString(fmt.Sprintf("%v", value.Value))
+func buildReadPayload(s *Server, ClientRequestHandle *string, ClientItemHandles *[]string, namespace string,
+	items []TItem, options TRequestOptions) (string, error) {
+	request := ReadRequest{Options: requestOptions(s, ClientRequestHandle, options)}
+	for i, item := range items {
+		request.ItemList.Items = append(request.ItemList.Items, ReadRequestItem{
+			ItemPath:         item.ItemPath,
+			ItemName:         item.ItemName,
+			ClientItemHandle: (*ClientItemHandles)[i],
+		})
 	}
-
-	return writeItemsValue.String()
+	return buildPayload(s, namespace, "Read", request)
 }
 
-func buildSubscribePayload(namespace string, items []TItem, ClientRequestHandle *string, ClientItemHandles *[]string,
-	returnValuesOnReply bool, subscriptionPingRate uint, options map[string]interface{}) string {
-	var payload strings.Builder
-	//header
-	payload.WriteString(XmlVersion)
-	buildHeader(&payload, namespace)
-	//body
-
-	payload.WriteString(fmt.Sprintf("<%s:Subscribe ReturnValuesOnReply=\"%s\" SubscriptionPingRate=\"%d\" ClientRequestHandle=\"%s\">",
-		namespace, strings.ToLower(fmt.Sprintf("%v", returnValuesOnReply)), subscriptionPingRate, *ClientRequestHandle))
-	buildOptionItems(&payload, options, namespace)
-	payload.WriteString(fmt.Sprintf("<%s:ItemList xsi:type=\"SubscribeRequestItemList\" ItemPath=\"\">", namespace))
-	payload.WriteString(buildSubscribeItems(items, ClientItemHandles, namespace))
-	payload.WriteString(fmt.Sprintf("</%s:ItemList></%s:Subscribe>", namespace, namespace))
-	payload.WriteString(Footer)
+// requestOptions fills in the ClientRequestHandle and LocaleID of options, if not set by the caller.
+func requestOptions(s *Server, ClientRequestHandle *string, options TRequestOptions) TRequestOptions {
+	if options.ClientRequestHandle == "" {
+		options.ClientRequestHandle = *ClientRequestHandle
+	}
+	if options.LocaleID == "" && s != nil {
+		options.LocaleID = s.LocaleID
+	}
+	return options
+}
 
-	return payload.String()
+func buildBrowsePayload(s *Server, ClientRequestHandle *string,
+	itemPath string, namespace string, options TBrowseOptions) (string, error) {
+	return buildPayload(s, namespace, "Browse", BrowseRequest{
+		LocaleID:             s.LocaleID,
+		ClientRequestHandle:  *ClientRequestHandle,
+		ItemPath:             itemPath,
+		ItemName:             options.ItemName,
+		ContinuationPoint:    options.ContinuationPoint,
+		MaxElementsReturned:  options.MaxElementsReturned,
+		BrowseFilter:         options.BrowseFilter,
+		ElementNameFilter:    options.ElementNameFilter,
+		VendorFilter:         options.VendorFilter,
+		ReturnAllProperties:  options.ReturnAllProperties,
+		ReturnPropertyValues: options.ReturnPropertyValues,
+		ReturnErrorText:      options.ReturnErrorText,
+	})
 }
 
-func buildSubscribeItems(items []TItem, ClientItemHandles *[]string, namespace string) string {
-	var subscribeItems strings.Builder
+func buildWritePayload(s *Server, namespace string, items []TItem, ClientRequestHandle *string, ClientItemHandles *[]string, options TRequestOptions) (string, error) {
+	// make sure all items have a (correct) opc-xml-da type, without touching the caller's items
+	items = setOpcXmlDaTypes(append([]TItem(nil), items...))
 
+	request := WriteRequest{
+		ReturnValuesOnReply: true,
+		Options:             requestOptions(s, ClientRequestHandle, options),
+	}
 	for i, item := range items {
-		subscribeItems.WriteString(fmt.Sprintf("<%s:Items xsi:type=\"%s:SubscribeRequestItem\" Deadband=\"%.0f\" ", namespace, namespace, item.DeadBand))
-		subscribeItems.WriteString(fmt.Sprintf(
-			"RequestedSamplingRate=\"%d\" EnableBuffering=\"%s\"",
-			item.RequestedSamplingRate, strings.ToLower(fmt.Sprintf("%t", item.EnableBuffering)),
-		))
-		if item.ItemName != "" {
-			subscribeItems.WriteString(fmt.Sprintf(" ItemName=\"%s\"", item.ItemName))
-		}
-		if item.ItemPath != "" {
-			subscribeItems.WriteString(fmt.Sprintf(" ItemPath=\"%s\"", item.ItemPath))
-		}
-		subscribeItems.WriteString(fmt.Sprintf(" ClientItemHandle=\"%s\"></%s:Items>", (*ClientItemHandles)[i], namespace))
+		value := item.Value
+		// the prefix of the OPC XML-DA types, declared by the envelope
+		value.Namespace = namespace
+		request.ItemList.Items = append(request.ItemList.Items, WriteRequestItem{
+			ItemPath:         item.ItemPath,
+			ItemName:         item.ItemName,
+			ClientItemHandle: (*ClientItemHandles)[i],
+			Value:            value,
+		})
 	}
-
-	return subscribeItems.String()
+	return buildPayload(s, namespace, "Write", request)
 }
 
-func buildSubscriptionCancelPayload(serverSubHandle string, namespace string, ClientRequestHandle *string) string {
-	var payload strings.Builder
-	//header
-	payload.WriteString(XmlVersion)
-	buildHeader(&payload, namespace)
-	//body
-	payload.WriteString(fmt.Sprintf("<%s:SubscriptionCancel ServerSubHandle=\"%s\" ClientRequestHandle=\"%s\"></%s:SubscriptionCancel>",
-		namespace, serverSubHandle, *ClientRequestHandle, namespace))
-	payload.WriteString(Footer)
-
-	return payload.String()
+func buildSubscribePayload(s *Server, namespace string, items []TItem, ClientRequestHandle *string, ClientItemHandles *[]string,
+	returnValuesOnReply bool, subscriptionPingRate uint, options TRequestOptions) (string, error) {
+	request := SubscribeRequest{
+		ReturnValuesOnReply:  returnValuesOnReply,
+		SubscriptionPingRate: subscriptionPingRate,
+		Options:              requestOptions(s, ClientRequestHandle, options),
+	}
+	for i, item := range items {
+		request.ItemList.Items = append(request.ItemList.Items, SubscribeRequestItem{
+			ItemPath:              item.ItemPath,
+			ItemName:              item.ItemName,
+			ClientItemHandle:      (*ClientItemHandles)[i],
+			Deadband:              item.DeadBand,
+			RequestedSamplingRate: item.RequestedSamplingRate,
+			EnableBuffering:       item.EnableBuffering,
+		})
+	}
+	return buildPayload(s, namespace, "Subscribe", request)
 }
 
-func buildSubscriptionPolledRefreshPayload(serverSubHandle string, namespace string, ClientRequestHandle *string,
-	SubscriptionPingRate uint, options map[string]interface{}, ServerTime TServerTime) (string, error) {
-	var payload strings.Builder
-	//header
-	payload.WriteString(XmlVersion)
-	buildHeader(&payload, namespace)
-	//body
-	holdTime, err := calcHoldTime(SubscriptionPingRate, ServerTime)
-	if err != nil {
-		return "", err
-	}
-	payload.WriteString(fmt.Sprintf("<%s:SubscriptionPolledRefresh HoldTime=\"%s\" ReturnAllItems=\"false\" WaitTime=\"500\">", namespace, holdTime))
-	options["ClientRequestHandle"] = *ClientRequestHandle
-	buildOptionItems(&payload, options, namespace)
-	payload.WriteString(fmt.Sprintf("<%s:ServerSubHandles>%s</%s:ServerSubHandles>", namespace, serverSubHandle, namespace))
-	payload.WriteString(fmt.Sprintf("</%s:SubscriptionPolledRefresh>", namespace))
-	payload.WriteString(Footer)
+func buildSubscriptionCancelPayload(s *Server, serverSubHandle string, namespace string, ClientRequestHandle *string) (string, error) {
+	return buildPayload(s, namespace, "SubscriptionCancel", SubscriptionCancelRequest{
+		ServerSubHandle:     serverSubHandle,
+		ClientRequestHandle: *ClientRequestHandle,
+	})
+}
 
-	return payload.String(), nil
+func buildSubscriptionPolledRefreshPayload(s *Server, serverSubHandle string, namespace string, ClientRequestHandle *string,
+	SubscriptionPingRate uint, options TRequestOptions, ServerTime TServerTime) (string, error) {
+	holdTime := calcHoldTime(SubscriptionPingRate, ServerTime)
+	return buildPayload(s, namespace, "SubscriptionPolledRefresh", SubscriptionPolledRefreshRequest{
+		HoldTime:         &holdTime,
+		WaitTime:         500,
+		Options:          requestOptions(nil, ClientRequestHandle, options),
+		ServerSubHandles: []string{serverSubHandle},
+	})
 }
 
-func calcHoldTime(subscriptionPingRate uint, ServerTime TServerTime) (string, error) {
+// calcHoldTime returns the HoldTime of a SubscriptionPolledRefresh, one SubscriptionPingRate after the
+// time of the server, truncated to seconds.
+func calcHoldTime(subscriptionPingRate uint, ServerTime TServerTime) time.Time {
+	now := ServerTime.ServerTime
 	if ServerTime.UseClientTime {
-		now := time.Now()
-		next := now.Add(time.Duration(subscriptionPingRate) * time.Millisecond)
-		return next.Format(time.RFC3339), nil
-	} else {
-		next := ServerTime.ServerTime.Add(time.Duration(subscriptionPingRate) * time.Millisecond)
-		return next.Format(time.RFC3339), nil
+		now = time.Now()
 	}
-
+	return now.Add(time.Duration(subscriptionPingRate) * time.Millisecond).Truncate(time.Second)
 }
 
-func buildGetPropertiesPayload(s *Server, ClientRequestHandle *string, namespace string, items []TItem, PropertyOptions TPropertyOptions) string {
-	var payload strings.Builder
-	//header
-	payload.WriteString(XmlVersion)
-	buildHeader(&payload, namespace)
-	//body
-	payload.WriteString(fmt.Sprintf(
-		"<%s:GetProperties LocaleID=\"%s\" ClientRequestHandle=\"%s\" ",
-		namespace, s.LocaleID, *ClientRequestHandle,
-	))
-	payload.WriteString(fmt.Sprintf(
-		"ReturnAllProperties=\"%s\" ReturnPropertyValues=\"%s\" ReturnErrorText=\"%s\">",
-		strconv.FormatBool(PropertyOptions.ReturnAllProperties), strconv.FormatBool(PropertyOptions.ReturnPropertyValues),
-		strconv.FormatBool(PropertyOptions.ReturnErrorText),
-	))
-	for _, item := range items {
-		payload.WriteString(fmt.Sprintf("<%s:ItemIDs ItemName=\"%s\" ItemPath=\"%s\"/>", namespace, item.ItemName, item.ItemPath))
+func buildGetPropertiesPayload(s *Server, ClientRequestHandle *string, namespace string, items []TItem, PropertyOptions TPropertyOptions) (string, error) {
+	request := GetPropertiesRequest{
+		LocaleID:             s.LocaleID,
+		ClientRequestHandle:  *ClientRequestHandle,
+		ReturnAllProperties:  PropertyOptions.ReturnAllProperties,
+		ReturnPropertyValues: PropertyOptions.ReturnPropertyValues,
+		ReturnErrorText:      PropertyOptions.ReturnErrorText,
+		PropertyNames:        PropertyOptions.PropertyNames,
 	}
-	for _, propertyName := range PropertyOptions.PropertyNames {
-		payload.WriteString(fmt.Sprintf("<%s:PropertyNames>%s</%s:PropertyNames>", namespace, propertyName, namespace))
+	for _, item := range items {
+		request.ItemIDs = append(request.ItemIDs, ItemIdentifier{ItemPath: item.ItemPath, ItemName: item.ItemName})
 	}
-	payload.WriteString(fmt.Sprintf("</%s:GetProperties>", namespace))
-	payload.WriteString(Footer)
-
-	return payload.String()
+	return buildPayload(s, namespace, "GetProperties", request)
 }
@@ -0,0 +1,217 @@
+package gopcxmlda
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestOptionsMarshalXML(t *testing.T) {
+	deadline := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	payload, err := xml.Marshal(TRequestOptions{
+		ReturnErrorText:     true,
+		ReturnItemName:      true,
+		RequestDeadline:     deadline,
+		ClientRequestHandle: "handle",
+		LocaleID:            "de-DE",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<TRequestOptions ReturnErrorText="true" ReturnDiagnosticInfo="false" ReturnItemTime="false" ` +
+		`ReturnItemPath="false" ReturnItemName="true" RequestDeadline="2024-01-02T03:04:05Z" ` +
+		`ClientRequestHandle="handle" LocaleID="de-DE"></TRequestOptions>`
+	if string(payload) != expected {
+		t.Fatalf("unexpected options:\n%s\nexpected:\n%s", payload, expected)
+	}
+	if payload, _ := xml.Marshal(TRequestOptions{}); strings.Contains(string(payload), "RequestDeadline") {
+		t.Errorf("zero RequestDeadline encoded: %s", payload)
+	}
+
+	// default options leave ReturnErrorText to the server, which returns error texts by default
+	handle := "1"
+	write, err := buildWritePayload(&Server{}, "ns0", []TItem{{ItemName: "a", Value: TValue{Value: 1}}}, &handle, &[]string{"1"}, TRequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(write, "ReturnErrorText") {
+		t.Errorf("ReturnErrorText sent by default: %s", write)
+	}
+	var request WriteRequest
+	decodeBody(t, write, &request)
+	if !request.Options.ReturnErrorText || request.Options.ReturnItemTime {
+		t.Errorf("unexpected default options: %+v", request.Options)
+	}
+
+	// OmitErrorText asks the server explicitly not to return error texts
+	write, err = buildWritePayload(&Server{}, "ns0", []TItem{{ItemName: "a", Value: TValue{Value: 1}}}, &handle, &[]string{"1"}, TRequestOptions{OmitErrorText: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(write, `ReturnErrorText="false"`) {
+		t.Errorf("ReturnErrorText=\"false\" not sent: %s", write)
+	}
+	decodeBody(t, write, &request)
+	if request.Options.ReturnErrorText || !request.Options.OmitErrorText {
+		t.Errorf("unexpected options without error texts: %+v", request.Options)
+	}
+
+	s := &Server{LocaleID: "en-US"}
+	handle = "generated"
+	options := requestOptions(s, &handle, TRequestOptions{})
+	if options.ClientRequestHandle != "generated" || options.LocaleID != "en-US" {
+		t.Fatalf("defaults not applied: %+v", options)
+	}
+}
+
+// hostile contains the characters that break XML built by string concatenation.
+const hostile = `a"b'c<d>e&f]]>g</ns0:Items><x/>`
+
+// decodeBody checks that payload is well-formed and decodes the operation in its SOAP body into body.
+func decodeBody(t *testing.T, payload string, body interface{}) {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(payload))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			t.Fatalf("no operation in payload: %v\n%s", err, payload)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Space == OpcNamespace {
+			if err := decoder.DecodeElement(body, &start); err != nil {
+				t.Fatalf("invalid operation: %v\n%s", err, payload)
+			}
+			break
+		}
+	}
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("invalid payload: %v\n%s", err, payload)
+		}
+	}
+}
+
+func TestBuildPayloadEscaping(t *testing.T) {
+	s := &Server{LocaleID: hostile}
+	handle := hostile
+	handles := []string{hostile}
+	items := []TItem{{ItemName: hostile, ItemPath: hostile, Value: TValue{Value: hostile}}}
+
+	payload, err := buildReadPayload(s, &handle, &handles, "ns1", items, TRequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read ReadRequest
+	decodeBody(t, payload, &read)
+	if read.Options.LocaleID != hostile || read.Options.ClientRequestHandle != hostile ||
+		len(read.ItemList.Items) != 1 || read.ItemList.Items[0] != (ReadRequestItem{ItemPath: hostile, ItemName: hostile, ClientItemHandle: hostile}) {
+		t.Errorf("unexpected request: %+v", read)
+	}
+
+	payload, err = buildWritePayload(s, "ns1", append(items, TItem{ItemName: "array", Value: TValue{Value: []string{hostile, "<"}}}),
+		&handle, &[]string{hostile, "2"}, TRequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var write WriteRequest
+	decodeBody(t, payload, &write)
+	if len(write.ItemList.Items) != 2 || write.ItemList.Items[0].ItemName != hostile || write.ItemList.Items[0].Value.Value != hostile {
+		t.Fatalf("unexpected request: %+v", write)
+	}
+	if array, ok := write.ItemList.Items[1].Value.Value.([]interface{}); !ok || len(array) != 2 || array[0] != hostile || array[1] != "<" {
+		t.Errorf("unexpected array: %#v", write.ItemList.Items[1].Value)
+	}
+
+	payload, err = buildBrowsePayload(s, &handle, hostile, "ns1", TBrowseOptions{ItemName: hostile, ElementNameFilter: hostile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var browse BrowseRequest
+	decodeBody(t, payload, &browse)
+	if browse.ItemPath != hostile || browse.ItemName != hostile || browse.ElementNameFilter != hostile || browse.LocaleID != hostile {
+		t.Errorf("unexpected request: %+v", browse)
+	}
+
+	payload, err = buildGetPropertiesPayload(s, &handle, "ns1", items, TPropertyOptions{PropertyNames: []string{hostile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var properties GetPropertiesRequest
+	decodeBody(t, payload, &properties)
+	if len(properties.ItemIDs) != 1 || properties.ItemIDs[0].ItemName != hostile || len(properties.PropertyNames) != 1 || properties.PropertyNames[0] != hostile {
+		t.Errorf("unexpected request: %+v", properties)
+	}
+
+	payload, err = buildSubscribePayload(s, "ns1", items, &handle, &handles, true, 1000, TRequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subscribe SubscribeRequest
+	decodeBody(t, payload, &subscribe)
+	if len(subscribe.ItemList.Items) != 1 || subscribe.ItemList.Items[0].ItemName != hostile || subscribe.SubscriptionPingRate != 1000 {
+		t.Errorf("unexpected request: %+v", subscribe)
+	}
+
+	payload, err = buildSubscriptionPolledRefreshPayload(s, hostile, "ns1", &handle, 1000, TRequestOptions{}, TServerTime{UseClientTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refresh SubscriptionPolledRefreshRequest
+	decodeBody(t, payload, &refresh)
+	if len(refresh.ServerSubHandles) != 1 || refresh.ServerSubHandles[0] != hostile || refresh.HoldTime == nil || refresh.HoldTime.IsZero() {
+		t.Errorf("unexpected request: %+v", refresh)
+	}
+	if data, _ := xml.Marshal(SubscriptionPolledRefreshRequest{}); strings.Contains(string(data), "HoldTime") {
+		t.Errorf("nil HoldTime encoded: %s", data)
+	}
+
+	payload, err = buildSubscriptionCancelPayload(s, hostile, "ns1", &handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cancel SubscriptionCancelRequest
+	decodeBody(t, payload, &cancel)
+	if cancel.ServerSubHandle != hostile || cancel.ClientRequestHandle != hostile {
+		t.Errorf("unexpected request: %+v", cancel)
+	}
+
+	for _, namespace := range []string{"", `ns0="x" y`, "1ns", "ns:0"} {
+		if _, err := buildGetStatusPayload(s, namespace, &handle); err == nil {
+			t.Errorf("no error for namespace prefix %q", namespace)
+		}
+	}
+}
+
+func TestRequestOptionsFromMap(t *testing.T) {
+	options, err := RequestOptionsFromMap(map[string]interface{}{
+		"ReturnItemTime":  true,
+		"ReturnErrorText": "true",
+		"LocaleID":        "en-US",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !options.ReturnItemTime || !options.ReturnErrorText || options.OmitErrorText || options.LocaleID != "en-US" {
+		t.Fatalf("unexpected options: %+v", options)
+	}
+	if options, err = RequestOptionsFromMap(map[string]interface{}{"ReturnErrorText": false}); err != nil || !options.OmitErrorText {
+		t.Fatalf("ReturnErrorText false: %+v, %v", options, err)
+	}
+
+	_, err = RequestOptionsFromMap(map[string]interface{}{
+		"returnItemPath": true,
+		"ReturnItemName": true,
+		"ReturnItemTme":  true,
+	})
+	if err == nil || err.Error() != "unknown options: ReturnItemTme, returnItemPath" {
+		t.Fatalf("expected unknown options error, got %v", err)
+	}
+
+	_, err = RequestOptionsFromMap(map[string]interface{}{"ReturnItemPath": 1})
+	if err == nil {
+		t.Fatal("expected type error")
+	}
+}
@@ -0,0 +1,295 @@
+package gopcxmlda_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+	"github.com/dernate/gopcxmlda/opctest"
+)
+
+// The tests in this file run the client against the simulated server of the opctest package,
+// the tests in client_test.go need a real server configured in .env.
+
+var offlineTags = []opctest.Tag{
+	{Name: "Loc/Wec/Plant1/P", Value: 1200.5, Properties: map[string]interface{}{
+		"engineeringUnits": "kW", "highEU": 3000.0, "lowEU": 0.0,
+	}},
+	{Name: "Loc/Wec/Plant1/Status/St", Value: 2, ReadOnly: true},
+	{Name: "Loc/Wec/Plant1/Ctrl/SetP", Value: 2000.0},
+	{Name: "Loc/Wec/Plant1/Wind", Generator: opctest.Step(time.Hour, 7.5)},
+}
+
+func newOfflineServer(t *testing.T) (*opctest.Server, *gopcxmlda.Server) {
+	t.Helper()
+	srv := opctest.NewServer(offlineTags)
+	t.Cleanup(srv.Close)
+	return srv, srv.Client()
+}
+
+func TestOfflineGetStatus(t *testing.T) {
+	_, s := newOfflineServer(t)
+	var ClientRequestHandle string
+	status, err := s.GetStatus(context.Background(), &ClientRequestHandle, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Response.Result.ServerState != "running" || status.Response.Status.ProductVersion != "opctest" {
+		t.Errorf("unexpected status: %+v", status.Response)
+	}
+}
+
+func TestOfflineSOAPHeader(t *testing.T) {
+	srv := opctest.NewServer(offlineTags)
+	t.Cleanup(srv.Close)
+	var operations []string
+	s := srv.Client(gopcxmlda.WithHeaderProviders(
+		gopcxmlda.UsernameToken{Username: "operator", Password: "secret", Digest: true},
+		gopcxmlda.HeaderProviderFunc(func(operation string) (interface{}, error) {
+			operations = append(operations, operation)
+			if operation == "Browse" {
+				return nil, errors.New("no header for Browse")
+			}
+			return nil, nil
+		}),
+	))
+	var ClientRequestHandle string
+	if _, err := s.GetStatus(context.Background(), &ClientRequestHandle, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Browse(context.Background(), "", &ClientRequestHandle, "", gopcxmlda.TBrowseOptions{}); err == nil {
+		t.Error("no error of the header provider")
+	}
+	if len(operations) != 2 || operations[0] != "GetStatus" || operations[1] != "Browse" || srv.RequestCount("Browse") != 0 {
+		t.Errorf("operations %q, %d Browse requests", operations, srv.RequestCount("Browse"))
+	}
+}
+
+func TestOfflineRead(t *testing.T) {
+	_, s := newOfflineServer(t)
+	var ClientRequestHandle string
+	var ClientItemHandles []string
+	items := []gopcxmlda.TItem{{ItemName: "Loc/Wec/Plant1/P"}, {ItemName: "Loc/Wec/Plant1/Status/St"}, {ItemName: "Loc/Wec/Plant1/Wind"}}
+	R, err := s.Read(context.Background(), items, &ClientRequestHandle, &ClientItemHandles, "",
+		gopcxmlda.TRequestOptions{ReturnItemName: true, ReturnItemTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := R.Response.ItemList.Items
+	if len(got) != 3 || got[0].Value.Value != 1200.5 || got[1].Value.Value != 2 || got[2].Value.Value != 7.5 {
+		t.Errorf("unexpected items: %+v", got)
+	}
+	if got[0].ItemName != "Loc/Wec/Plant1/P" || got[0].Timestamp.IsZero() || got[0].Quality.QualityField != "good" {
+		t.Errorf("unexpected item: %+v", got[0])
+	}
+}
+
+func TestOfflineWrite(t *testing.T) {
+	srv, s := newOfflineServer(t)
+	var ClientRequestHandle string
+	var ClientItemHandles []string
+	items := []gopcxmlda.TItem{
+		{ItemName: "Loc/Wec/Plant1/Ctrl/SetP", Value: gopcxmlda.TValue{Value: 1500.0}},
+		{ItemName: "Loc/Wec/Plant1/Status/St", Value: gopcxmlda.TValue{Value: 1}},
+	}
+	W, err := s.Write(context.Background(), items, &ClientRequestHandle, &ClientItemHandles, "", gopcxmlda.TRequestOptions{})
+	if !errors.Is(err, gopcxmlda.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	if got := W.Response.ItemList.Items; len(got) != 2 || got[0].Err() != nil || got[0].Value.Value != 1500.0 {
+		t.Errorf("unexpected items: %+v", got)
+	}
+	if v, _ := srv.Value("Loc/Wec/Plant1/Ctrl/SetP"); v != 1500.0 {
+		t.Errorf("value not written: %v", v)
+	}
+}
+
+func TestOfflineEscaping(t *testing.T) {
+	const name = `Loc/"Wec" & <Plant1>/Name`
+	const value = `</Value></Items><Items ItemName="x">&amp;`
+	srv := opctest.NewServer([]opctest.Tag{{Name: name, Value: ""}})
+	t.Cleanup(srv.Close)
+	s := srv.Client()
+	s.LocaleID = `en"US`
+
+	var ClientRequestHandle string
+	var ClientItemHandles []string
+	items := []gopcxmlda.TItem{{ItemName: name, Value: gopcxmlda.TValue{Value: value}}}
+	if _, err := s.Write(context.Background(), items, &ClientRequestHandle, &ClientItemHandles, "", gopcxmlda.TRequestOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := srv.Value(name); v != value {
+		t.Errorf("unexpected value written: %q", v)
+	}
+	ClientRequestHandle, ClientItemHandles = "", nil
+	R, err := s.Read(context.Background(), items, &ClientRequestHandle, &ClientItemHandles, "", gopcxmlda.TRequestOptions{ReturnItemName: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := R.Response.ItemList.Items; len(got) != 1 || got[0].ItemName != name || got[0].Value.Value != value {
+		t.Errorf("unexpected items: %+v", got)
+	}
+}
+
+func TestOfflineBrowse(t *testing.T) {
+	_, s := newOfflineServer(t)
+	var ClientRequestHandle string
+	B, err := s.Browse(context.Background(), "", &ClientRequestHandle, "", gopcxmlda.TBrowseOptions{ItemName: "Loc/Wec/Plant1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elements := B.Response.Elements; len(elements) != 4 || elements[1].Name != "Status" || !elements[1].HasChildren {
+		t.Errorf("unexpected elements: %+v", elements)
+	}
+
+	tree, err := s.BrowseTree(context.Background(), "", "", gopcxmlda.WalkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Children) != 1 || tree.Children[0].Element.Name != "Loc" {
+		t.Errorf("unexpected tree: %+v", tree.Children)
+	}
+}
+
+func TestOfflineGetProperties(t *testing.T) {
+	_, s := newOfflineServer(t)
+	as, err := s.ExportAddressSpace(context.Background(), "", "", gopcxmlda.ExportOptions{Properties: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, element := range as.Elements {
+		if element.ItemName != "Loc/Wec/Plant1/P" {
+			continue
+		}
+		found = true
+		p := element.Properties
+		if p == nil || p.DataType != "xsd:double" || p.EngineeringUnits != "kW" || p.HighEU == nil || *p.HighEU != 3000 {
+			t.Errorf("unexpected properties: %+v", p)
+		}
+	}
+	if !found {
+		t.Error("item not exported")
+	}
+}
+
+func TestOfflineItemProperties(t *testing.T) {
+	_, s := newOfflineServer(t)
+	var ClientRequestHandle string
+	items := []gopcxmlda.TItem{{ItemName: "Loc/Wec/Plant1/P"}, {ItemName: "Loc/Wec/Plant1/Status/St"}, {ItemName: "Loc/Wec/Plant1/X"}}
+	response, err := s.GetProperties(context.Background(), items, gopcxmlda.TPropertyOptions{
+		ReturnAllProperties:  true,
+		ReturnPropertyValues: true,
+	}, &ClientRequestHandle, "")
+	if !errors.Is(err, gopcxmlda.ErrUnknownItemName) {
+		t.Fatalf("expected ErrUnknownItemName, got %v", err)
+	}
+	properties, err := response.ItemProperties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(properties) != 3 {
+		t.Fatalf("unexpected properties: %+v", properties)
+	}
+	p := properties[0]
+	if p.DataType != "xsd:double" || p.EngineeringUnits != "kW" || *p.HighEU != 3000 || *p.LowEU != 0 ||
+		p.AccessRights != gopcxmlda.AccessReadWritable || p.Value.Value != 1200.5 || !p.Quality.IsGood() || p.Timestamp.IsZero() {
+		t.Errorf("unexpected properties: %+v", p)
+	}
+	if properties[1].AccessRights.CanWrite() {
+		t.Errorf("read-only item is writable: %+v", properties[1])
+	}
+	if properties[2].Error != string(gopcxmlda.ErrUnknownItemName) {
+		t.Errorf("unexpected error: %+v", properties[2])
+	}
+}
+
+func TestOfflineWriteValidation(t *testing.T) {
+	srv := opctest.NewServer(offlineTags)
+	t.Cleanup(srv.Close)
+	srv.AddTag(opctest.Tag{Name: "Loc/Wec/Plant1/Ctrl/Limit", Value: 10.0, Properties: map[string]interface{}{
+		"lowIR": 0.0, "highIR": 100.0,
+	}})
+	s := srv.Client(gopcxmlda.WithWriteValidation(0))
+	write := func(name string, value interface{}) error {
+		var ClientRequestHandle string
+		var ClientItemHandles []string
+		items := []gopcxmlda.TItem{{ItemName: name, Value: gopcxmlda.TValue{Value: value}}}
+		_, err := s.Write(context.Background(), items, &ClientRequestHandle, &ClientItemHandles, "", gopcxmlda.TRequestOptions{})
+		return err
+	}
+
+	if err := write("Loc/Wec/Plant1/Ctrl/Limit", 50); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := srv.Value("Loc/Wec/Plant1/Ctrl/Limit"); v != 50.0 {
+		t.Errorf("value not written as double: %#v", v)
+	}
+	for _, test := range []struct {
+		name  string
+		value interface{}
+		err   error
+	}{
+		{"Loc/Wec/Plant1/Status/St", 1, gopcxmlda.ErrReadOnly},
+		{"Loc/Wec/Plant1/Ctrl/Limit", 100.5, gopcxmlda.ErrRange},
+		{"Loc/Wec/Plant1/Ctrl/Limit", "50", gopcxmlda.ErrBadType},
+		{"Loc/Wec/Plant1/Ctrl/Limit", []interface{}{1.0}, gopcxmlda.ErrBadType},
+		{"Loc/Wec/Plant1/Unknown", 1.0, gopcxmlda.ErrUnknownItemName},
+	} {
+		err := write(test.name, test.value)
+		var itemErr *gopcxmlda.ItemError
+		if !errors.Is(err, test.err) || !errors.As(err, &itemErr) || itemErr.ItemName != test.name {
+			t.Errorf("%s = %v: expected %v, got %v", test.name, test.value, test.err, err)
+		}
+	}
+	if n := srv.RequestCount("Write"); n != 1 {
+		t.Errorf("%d Write requests sent, want 1", n)
+	}
+	// the properties of the known items are cached
+	if n := srv.RequestCount("GetProperties"); n != 3 {
+		t.Errorf("%d GetProperties requests sent, want 3", n)
+	}
+	s.InvalidateProperties()
+	if err := write("Loc/Wec/Plant1/Ctrl/Limit", 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.RequestCount("GetProperties"); n != 4 {
+		t.Errorf("properties not invalidated, %d GetProperties requests sent", n)
+	}
+}
+
+func TestOfflineSubscription(t *testing.T) {
+	srv, s := newOfflineServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sub, err := s.StartSubscription(ctx, []gopcxmlda.TItem{{ItemName: "Loc/Wec/Plant1/P"}}, gopcxmlda.SubscriptionOptions{
+		PingRate:            200,
+		ReturnValuesOnReply: true,
+		EventBuffer:         10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event := <-sub.Events(); event.Type != gopcxmlda.EventItems || event.Items[0].Value.Value != 1200.5 {
+		t.Fatalf("unexpected first event: %+v", event)
+	}
+
+	srv.SetValue("Loc/Wec/Plant1/P", 1300.0)
+	for event := range sub.Events() {
+		if event.Type == gopcxmlda.EventItems && event.Items[0].Value.Value == 1300.0 {
+			break
+		}
+	}
+	handle := sub.ServerSubHandle()
+	if err := sub.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var ClientRequestHandle string
+	if _, err := s.SubscriptionCancel(context.Background(), handle, "", &ClientRequestHandle); !errors.Is(err, gopcxmlda.ErrNoSubscription) {
+		t.Errorf("subscription not canceled on the server: %v", err)
+	}
+}
@@ -22,7 +22,7 @@ func TestGetStatus(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	s := Server{_url, "en-US", 10 * time.Second}
+	s := Server{Url: _url, LocaleID: "en-US", Timeout: 10 * time.Second}
 	var ClientRequestHandle string
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -45,7 +45,7 @@ func TestRead(t *testing.T) {
 	}
 	OpcUrl := os.Getenv("OPC_URL")
 	_url, err := url.Parse(OpcUrl)
-	s := Server{_url, "en-US", 10 * time.Second}
+	s := Server{Url: _url, LocaleID: "en-US", Timeout: 10 * time.Second}
 	items := []TItem{
 		{
 			ItemName: "Loc/Wec/Plant1/P",
@@ -57,10 +57,10 @@ func TestRead(t *testing.T) {
 			ItemName: "Loc/Wec/Plant1/Status/St",
 		},
 	}
-	options := map[string]interface{}{
-		"ReturnItemTime": true,
-		"returnItemPath": true,
-		"returnItemName": true,
+	options := TRequestOptions{
+		ReturnItemTime: true,
+		ReturnItemPath: true,
+		ReturnItemName: true,
 	}
 	var ClientRequestHandle string
 	var ClientItemHandles []string
@@ -85,7 +85,7 @@ func TestBrowse(t *testing.T) {
 	}
 	OpcUrl := os.Getenv("OPC_URL")
 	_url, err := url.Parse(OpcUrl)
-	s := Server{_url, "en-US", 10 * time.Second}
+	s := Server{Url: _url, LocaleID: "en-US", Timeout: 10 * time.Second}
 	var ClientRequestHandle string
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -111,7 +111,7 @@ func TestWrite(t *testing.T) {
 	}
 	OpcUrl := os.Getenv("OPC_URL")
 	_url, err := url.Parse(OpcUrl)
-	s := Server{_url, "en-US", 10 * time.Second}
+	s := Server{Url: _url, LocaleID: "en-US", Timeout: 10 * time.Second}
 	items := []TItem{
 		{
 			ItemName: "Loc/Wec/Plant1/Ctrl/SessionRequest",
@@ -122,10 +122,10 @@ func TestWrite(t *testing.T) {
 	}
 	var ClientRequestHandle string
 	var ClientItemHandles []string
-	options := map[string]interface{}{
-		"ReturnErrorText": true,
-		"ReturnItemName":  true,
-		"ReturnItemPath":  true,
+	options := TRequestOptions{
+		ReturnErrorText: true,
+		ReturnItemName:  true,
+		ReturnItemPath:  true,
 	}
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -148,7 +148,7 @@ func TestSubscribe(t *testing.T) {
 	}
 	OpcUrl := os.Getenv("OPC_URL")
 	_url, err := url.Parse(OpcUrl)
-	s := Server{_url, "en-US", 30 * time.Second}
+	s := Server{Url: _url, LocaleID: "en-US", Timeout: 30 * time.Second}
 
 	items := []TItem{
 		{
@@ -167,10 +167,10 @@ func TestSubscribe(t *testing.T) {
 			RequestedSamplingRate: 5000,
 		},
 	}
-	options := map[string]interface{}{
-		"ReturnItemTime": true,
-		"ReturnItemPath": true,
-		"ReturnItemName": true,
+	options := TRequestOptions{
+		ReturnItemTime: true,
+		ReturnItemPath: true,
+		ReturnItemName: true,
 	}
 	var ClientRequestHandle string
 	var ClientItemHandles []string
@@ -193,9 +193,9 @@ func TestSubscribe(t *testing.T) {
 
 	// Subscription Polled Refresh
 	var ClientRequestHandle1 string
-	optionsPolledRefresh := map[string]interface{}{
-		"ReturnErrorText": true,
-		"ReturnItemTime":  true,
+	optionsPolledRefresh := TRequestOptions{
+		ReturnErrorText: true,
+		ReturnItemTime:  true,
 	}
 	ServerTime := TServerTime{response.Response.Result.ReplyTime, false}
 	refreshResponse, err := s.SubscriptionPolledRefresh(
@@ -297,7 +297,7 @@ func TestGetProperties(t *testing.T) {
 	}
 	OpcUrl := os.Getenv("OPC_URL")
 	_url, err := url.Parse(OpcUrl)
-	s := Server{_url, "en-US", 10 * time.Second}
+	s := Server{Url: _url, LocaleID: "en-US", Timeout: 10 * time.Second}
 	var ClientRequestHandle string
 	items := []TItem{
 		{
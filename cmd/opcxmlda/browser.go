@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// browser navigates the address space of a server like a file system, used by shell and tui.
+// Browse results are cached until refresh is called.
+type browser struct {
+	s         *gopcxmlda.Server
+	namespace string
+	cwd       []gopcxmlda.TBrowseElement // branches from the root to the current branch
+	cache     map[string][]gopcxmlda.TBrowseElement
+}
+
+func newBrowser(s *gopcxmlda.Server, namespace string) *browser {
+	return &browser{s: s, namespace: namespace, cache: make(map[string][]gopcxmlda.TBrowseElement)}
+}
+
+// pwd returns the path of the current branch.
+func (b *browser) pwd() string {
+	names := make([]string, len(b.cwd))
+	for i, element := range b.cwd {
+		names[i] = element.Name
+	}
+	return "/" + strings.Join(names, "/")
+}
+
+// refresh forgets the cached browse results.
+func (b *browser) refresh() {
+	b.cache = make(map[string][]gopcxmlda.TBrowseElement)
+}
+
+// children returns the elements of a branch, browsed once and then cached.
+func (b *browser) children(ctx context.Context, branch []gopcxmlda.TBrowseElement) ([]gopcxmlda.TBrowseElement, error) {
+	var itemName, itemPath string
+	if len(branch) > 0 {
+		itemName, itemPath = branch[len(branch)-1].ItemName, branch[len(branch)-1].ItemPath
+	}
+	key := itemPath + "\x00" + itemName
+	if elements, ok := b.cache[key]; ok {
+		return elements, nil
+	}
+	elements, err := b.s.BrowseAll(ctx, itemPath, b.namespace, gopcxmlda.TBrowseOptions{ItemName: itemName})
+	if err != nil {
+		return nil, err
+	}
+	b.cache[key] = elements
+	return elements, nil
+}
+
+// resolve returns the elements from the root to the element of a path.
+func (b *browser) resolve(ctx context.Context, target string) ([]gopcxmlda.TBrowseElement, error) {
+	branch := append([]gopcxmlda.TBrowseElement(nil), b.cwd...)
+	if strings.HasPrefix(target, "/") {
+		branch = nil
+	}
+	for _, name := range strings.Split(target, "/") {
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if len(branch) > 0 {
+				branch = branch[:len(branch)-1]
+			}
+			continue
+		}
+		elements, err := b.children(ctx, branch)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, element := range elements {
+			if element.Name == name {
+				branch = append(branch, element)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s not found", target)
+		}
+	}
+	return branch, nil
+}
+
+// itemNames returns the item names of paths. Paths which are not found are used as item names.
+func (b *browser) itemNames(ctx context.Context, paths []string) []string {
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = path
+		if elements, err := b.resolve(ctx, path); err == nil && len(elements) > 0 {
+			names[i] = elements[len(elements)-1].ItemName
+		}
+	}
+	return names
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// itemOptions are the options of the read, write and watch requests.
+var itemOptions = gopcxmlda.TRequestOptions{
+	ReturnErrorText: true,
+	ReturnItemName:  true,
+	ReturnItemPath:  true,
+	ReturnItemTime:  true,
+}
+
+func runStatus(ctx context.Context, e *env, args []string) error {
+	s, err := e.parse(args)
+	if err != nil {
+		return err
+	}
+	if e.flags.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected arguments %q", errUsage, e.flags.Args())
+	}
+	var handle string
+	status, err := s.GetStatus(ctx, &handle, e.cfg.namespace)
+	if err != nil {
+		return err
+	}
+	result, st := status.Response.Result, status.Response.Status
+	fields := [][2]string{
+		{"serverState", result.ServerState},
+		{"productVersion", st.ProductVersion},
+		{"startTime", st.StartTime},
+		{"replyTime", result.ReplyTime.Format(time.RFC3339Nano)},
+		{"statusInfo", st.StatusInfo},
+		{"vendorInfo", st.VendorInfo},
+		{"supportedLocaleIDs", st.SupportedLocaleIDs},
+		{"supportedInterfaceVersions", st.SupportedInterfaceVersions},
+	}
+	if e.cfg.format == "json" {
+		values := make(map[string]string, len(fields))
+		for _, field := range fields {
+			values[field[0]] = field[1]
+		}
+		out := newOutput("json", e.stdout, true)
+		return out.row(values)
+	}
+	out := newOutput(e.cfg.format, e.stdout, false, "field", "value")
+	for _, field := range fields {
+		if err := out.row(nil, field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	return out.flush()
+}
+
+// browseRow is an element as written by browse.
+type browseRow struct {
+	Name        string `json:"name"`
+	ItemName    string `json:"itemName"`
+	ItemPath    string `json:"itemPath,omitempty"`
+	IsItem      bool   `json:"isItem"`
+	HasChildren bool   `json:"hasChildren"`
+	Depth       int    `json:"depth,omitempty"`
+}
+
+func runBrowse(ctx context.Context, e *env, args []string) error {
+	recursive := e.flags.Bool("r", false, "browse recursively")
+	depth := e.flags.Int("depth", 0, "maximum depth with -r, unlimited if zero")
+	filter := e.flags.String("filter", "", "ElementNameFilter of the elements")
+	browseFilter := e.flags.String("type", "", "BrowseFilter: branch or item")
+	path := e.flags.String("path", "", "item path of the item")
+	s, err := e.parse(args)
+	if err != nil {
+		return err
+	}
+	if e.flags.NArg() > 1 {
+		return fmt.Errorf("%w: more than one item", errUsage)
+	}
+	options := gopcxmlda.TBrowseOptions{
+		ItemName:          e.flags.Arg(0),
+		ElementNameFilter: *filter,
+		BrowseFilter:      *browseFilter,
+	}
+
+	out := newOutput(e.cfg.format, e.stdout, false, "name", "item", "isItem", "hasChildren")
+	add := func(element gopcxmlda.TBrowseElement, depth int) error {
+		name := element.Name
+		if depth > 1 {
+			name = strings.Repeat("  ", depth-1) + name
+		}
+		row := browseRow{element.Name, element.ItemName, element.ItemPath, element.IsItem, element.HasChildren, depth}
+		return out.row(row, name, element.ItemName, strconv.FormatBool(element.IsItem), strconv.FormatBool(element.HasChildren))
+	}
+	if *recursive {
+		err = s.Walk(ctx, *path, e.cfg.namespace, gopcxmlda.WalkOptions{BrowseOptions: options, MaxDepth: *depth}, add)
+	} else {
+		var elements []gopcxmlda.TBrowseElement
+		elements, err = s.BrowseAll(ctx, *path, e.cfg.namespace, options)
+		for _, element := range elements {
+			if err := add(element, 0); err != nil {
+				return err
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return out.flush()
+}
+
+// newItems returns the items of names and the ClientItemHandles used to map the results to names.
+func newItems(names []string, path string) ([]gopcxmlda.TItem, []string) {
+	items := make([]gopcxmlda.TItem, len(names))
+	handles := make([]string, len(names))
+	for i, name := range names {
+		items[i] = gopcxmlda.TItem{ItemName: name, ItemPath: path}
+		handles[i] = strconv.Itoa(i)
+	}
+	return items, handles
+}
+
+// nameOf returns the item name of a result by its ClientItemHandle.
+func nameOf(item gopcxmlda.TItem, names []string) string {
+	if i, err := strconv.Atoi(item.ClientItemHandle); err == nil && i >= 0 && i < len(names) {
+		return names[i]
+	}
+	return ""
+}
+
+// writeItems writes the items of a response. Without items, the error of the request is returned.
+// Otherwise the errors of the failed items are returned, or err if there is none.
+func (e *env) writeItems(items []gopcxmlda.TItem, names []string, err error, itemErrors error) error {
+	if len(items) == 0 {
+		return err
+	}
+	out := newOutput(e.cfg.format, e.stdout, false, itemHeader...)
+	for _, item := range items {
+		row := newItemRow(item, nameOf(item, names))
+		if err := out.row(row, row.fields()...); err != nil {
+			return err
+		}
+	}
+	if err := out.flush(); err != nil {
+		return err
+	}
+	if itemErrors != nil {
+		return itemErrors
+	}
+	return err
+}
+
+func runRead(ctx context.Context, e *env, args []string) error {
+	path := e.flags.String("path", "", "item path of the items")
+	s, err := e.parse(args)
+	if err != nil {
+		return err
+	}
+	names := e.flags.Args()
+	if len(names) == 0 {
+		return fmt.Errorf("%w: no items", errUsage)
+	}
+	return e.read(ctx, s, names, *path)
+}
+
+// read reads the items and writes their values.
+func (e *env) read(ctx context.Context, s *gopcxmlda.Server, names []string, path string) error {
+	items, handles := newItems(names, path)
+	var handle string
+	response, err := s.Read(ctx, items, &handle, &handles, e.cfg.namespace, itemOptions)
+	return e.writeItems(response.Response.ItemList.Items, names, err, response.ItemErrors())
+}
+
+func runWrite(ctx context.Context, e *env, args []string) error {
+	path := e.flags.String("path", "", "item path of the items")
+	xsdType := e.flags.String("type", "", "xsi:type of all values, e.g. double or ArrayOfInt; the dataType property of the items if empty")
+	s, err := e.parse(args)
+	if err != nil {
+		return err
+	}
+	if e.flags.NArg() == 0 {
+		return fmt.Errorf("%w: no items", errUsage)
+	}
+	names := make([]string, e.flags.NArg())
+	texts := make([]string, e.flags.NArg())
+	for i, arg := range e.flags.Args() {
+		var ok bool
+		names[i], texts[i], ok = strings.Cut(arg, "=")
+		if !ok || names[i] == "" {
+			return fmt.Errorf("%w: %q is not of the form item=value", errUsage, arg)
+		}
+	}
+	return e.write(ctx, s, names, texts, *path, *xsdType)
+}
+
+// write writes the values given as texts to the items and writes the results. Without xsdType,
+// the values are parsed as the dataType property of the items.
+func (e *env) write(ctx context.Context, s *gopcxmlda.Server, names []string, texts []string, path string, xsdType string) error {
+	items, handles := newItems(names, path)
+	types := make([]string, len(items))
+	for i := range types {
+		types[i] = xsdType
+	}
+	if xsdType == "" {
+		types = dataTypes(ctx, s, e.cfg.namespace, items)
+	}
+	for i := range items {
+		value, err := parseValue(texts[i], types[i])
+		if err != nil {
+			return fmt.Errorf("%w: item %q: %v", errUsage, names[i], err)
+		}
+		items[i].Value = gopcxmlda.TValue{Type: types[i], Value: value}
+	}
+
+	var handle string
+	response, err := s.Write(ctx, items, &handle, &handles, e.cfg.namespace, itemOptions)
+	return e.writeItems(response.Response.ItemList.Items, names, err, response.ItemErrors())
+}
+
+// dataTypes returns the dataType property of the items without prefix, or "" if it is unknown.
+func dataTypes(ctx context.Context, s *gopcxmlda.Server, namespace string, items []gopcxmlda.TItem) []string {
+	types := make([]string, len(items))
+	var handle string
+	response, _ := s.GetProperties(ctx, items, gopcxmlda.TPropertyOptions{
+		PropertyNames:        []string{"dataType"},
+		ReturnPropertyValues: true,
+	}, &handle, namespace)
+	for i, list := range response.Response.PropertyList {
+		if i >= len(types) || list.ResultId != "" {
+			continue
+		}
+		for _, p := range list.Properties {
+			if qname, ok := p.Value.Value.(string); ok && strings.HasSuffix(p.Name, "dataType") {
+				types[i] = qname[strings.LastIndex(qname, ":")+1:]
+			}
+		}
+	}
+	return types
+}
+
+// parseValue parses text as value of the xsi:type xsdType. The elements of arrays are separated
+// by commas. Without type, booleans, integers and floats are recognized, other text is a string.
+// Durations are given as by time.ParseDuration, e.g. "1h30m".
+func parseValue(text string, xsdType string) (interface{}, error) {
+	if elemType, ok := strings.CutPrefix(xsdType, "ArrayOf"); ok {
+		elemType = strings.ToLower(elemType[:1]) + elemType[1:]
+		var elements []string
+		if text != "" {
+			elements = strings.Split(text, ",")
+		}
+		values := make([]interface{}, len(elements))
+		for i, element := range elements {
+			value, err := parseValue(strings.TrimSpace(element), elemType)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+	switch xsdType {
+	case "":
+		if b, err := strconv.ParseBool(text); err == nil && (text == "true" || text == "false") {
+			return b, nil
+		}
+		if i, err := strconv.Atoi(text); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f, nil
+		}
+		return text, nil
+	case "string", "QName", "base64Binary", "anyType":
+		return text, nil
+	case "boolean":
+		return strconv.ParseBool(text)
+	case "double":
+		return strconv.ParseFloat(text, 64)
+	case "decimal":
+		return gopcxmlda.ParseDecimal(text)
+	case "duration":
+		return time.ParseDuration(text)
+	case "float":
+		f, err := strconv.ParseFloat(text, 32)
+		return float32(f), err
+	case "long":
+		return strconv.ParseInt(text, 10, 64)
+	case "int":
+		i, err := strconv.ParseInt(text, 10, 32)
+		return int32(i), err
+	case "short":
+		i, err := strconv.ParseInt(text, 10, 16)
+		return int16(i), err
+	case "byte":
+		i, err := strconv.ParseInt(text, 10, 8)
+		return int8(i), err
+	case "unsignedLong":
+		return strconv.ParseUint(text, 10, 64)
+	case "unsignedInt":
+		u, err := strconv.ParseUint(text, 10, 32)
+		return uint32(u), err
+	case "unsignedShort":
+		u, err := strconv.ParseUint(text, 10, 16)
+		return uint16(u), err
+	case "unsignedByte":
+		u, err := strconv.ParseUint(text, 10, 8)
+		return uint8(u), err
+	case "dateTime":
+		return time.Parse(time.RFC3339Nano, text)
+	default:
+		return nil, fmt.Errorf("unsupported type %q", xsdType)
+	}
+}
+
+func runWatch(ctx context.Context, e *env, args []string) error {
+	path := e.flags.String("path", "", "item path of the items")
+	rate := e.flags.Uint("rate", 1000, "SubscriptionPingRate in milliseconds")
+	count := e.flags.Int("n", 0, "stop after n changes, run until interrupted if zero")
+	s, err := e.parse(args)
+	if err != nil {
+		return err
+	}
+	names := e.flags.Args()
+	if len(names) == 0 {
+		return fmt.Errorf("%w: no items", errUsage)
+	}
+	return e.watch(ctx, s, names, *path, *rate, *count)
+}
+
+// watch subscribes the items and writes their changes until ctx is canceled or count changes
+// were received, if count is not zero.
+func (e *env) watch(ctx context.Context, s *gopcxmlda.Server, names []string, path string, rate uint, count int) error {
+	items, handles := newItems(names, path)
+	sub, err := s.StartSubscription(ctx, items, gopcxmlda.SubscriptionOptions{
+		Namespace:           e.cfg.namespace,
+		PingRate:            rate,
+		ReturnValuesOnReply: true,
+		Options:             itemOptions,
+		ClientItemHandles:   handles,
+		Resubscribe:         true,
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	out := newOutput(e.cfg.format, e.stdout, true, itemHeader...)
+	changes := 0
+	for event := range sub.Events() {
+		switch event.Type {
+		case gopcxmlda.EventItems:
+			for _, item := range event.Items {
+				row := newItemRow(item, nameOf(item, names))
+				if err := out.row(row, row.fields()...); err != nil {
+					return err
+				}
+				changes++
+			}
+			if err := out.flush(); err != nil {
+				return err
+			}
+			if count > 0 && changes >= count {
+				return nil
+			}
+		case gopcxmlda.EventError:
+			fmt.Fprintf(e.stderr, "opcxmlda watch: %v\n", event.Err)
+		case gopcxmlda.EventDataBufferOverflow:
+			fmt.Fprintln(e.stderr, "opcxmlda watch: data buffer overflow, values were dropped")
+		case gopcxmlda.EventResubscribed:
+			fmt.Fprintf(e.stderr, "opcxmlda watch: subscribed again after %v: %v\n", event.Gap.Round(time.Millisecond), event.Err)
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return sub.Err()
+}
+
+// propertyRow is a property as written by props.
+type propertyRow struct {
+	Item     string      `json:"item"`
+	Property string      `json:"property,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+func runProps(ctx context.Context, e *env, args []string) error {
+	path := e.flags.String("path", "", "item path of the items")
+	propertyNames := e.flags.String("names", "", "comma-separated property names, all properties if empty")
+	s, err := e.parse(args)
+	if err != nil {
+		return err
+	}
+	names := e.flags.Args()
+	if len(names) == 0 {
+		return fmt.Errorf("%w: no items", errUsage)
+	}
+	var properties []string
+	if *propertyNames != "" {
+		properties = strings.Split(*propertyNames, ",")
+	}
+	return e.properties(ctx, s, names, *path, properties)
+}
+
+// properties writes the properties of the items, all properties if propertyNames is empty.
+func (e *env) properties(ctx context.Context, s *gopcxmlda.Server, names []string, path string, propertyNames []string) error {
+	items, _ := newItems(names, path)
+	options := gopcxmlda.TPropertyOptions{ReturnAllProperties: true, ReturnPropertyValues: true, ReturnErrorText: true}
+	if len(propertyNames) > 0 {
+		options.ReturnAllProperties = false
+		options.PropertyNames = propertyNames
+	}
+	var handle string
+	response, err := s.GetProperties(ctx, items, options, &handle, e.cfg.namespace)
+	lists := response.Response.PropertyList
+	if len(lists) == 0 {
+		return err
+	}
+
+	out := newOutput(e.cfg.format, e.stdout, false, "item", "property", "value", "error")
+	for i, list := range lists {
+		name := list.ItemName
+		if name == "" && i < len(names) {
+			name = names[i]
+		}
+		if list.ResultId != "" {
+			row := propertyRow{Item: name, Error: string(gopcxmlda.ParseResultCode(list.ResultId))}
+			if err := out.row(row, name, "", "", row.Error); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, p := range list.Properties {
+			row := propertyRow{Item: name, Property: p.Name, Value: p.Value.Value}
+			if err := out.row(row, name, p.Name, formatValue(p.Value.Value), ""); err != nil {
+				return err
+			}
+		}
+	}
+	if err := out.flush(); err != nil {
+		return err
+	}
+	if itemErrors := response.ItemErrors(); itemErrors != nil {
+		return itemErrors
+	}
+	return err
+}
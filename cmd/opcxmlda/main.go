@@ -0,0 +1,228 @@
+// Command opcxmlda is a command-line client for OPC XML-DA servers.
+//
+// Usage:
+//
+//	opcxmlda [flags] <command> [command flags] [arguments]
+//
+// Commands:
+//
+//	status                    show the status of the server
+//	browse [-r] [item]        list the elements below an item, recursively with -r
+//	read item...              read the values of items
+//	write item=value...       write values, typed by the dataType property of the items or -type
+//	watch item...             subscribe to items and print every change until interrupted
+//	props item...             show the properties of items
+//	shell                     navigate the items interactively, with tab completion and history
+//	tui [item...]             dashboard of subscribed items with a pane to browse and add items
+//
+// Flags, also accepted after the command:
+//
+//	--url        URL of the server, $OPC_URL by default
+//	--locale     locale ID of the requests, "en-US" by default
+//	--timeout    timeout of every request, 10s by default
+//	--namespace  namespace prefix of the requests, "ns0" by default
+//	--format     output format: table, json or csv
+//	--verbose    log the errors of the client to stderr
+//
+// Exit codes:
+//
+//	0  success
+//	1  error, e.g. the server could not be reached
+//	2  invalid usage
+//	3  the server answered with a SOAP fault
+//	4  an item failed, e.g. E_UNKNOWNITEMNAME or E_READONLY
+//	5  a request timed out
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// Exit codes of the command.
+const (
+	exitOK        = 0
+	exitError     = 1
+	exitUsage     = 2
+	exitFault     = 3
+	exitItemError = 4
+	exitTimeout   = 5
+)
+
+// config holds the flags common to all commands.
+type config struct {
+	url       string
+	locale    string
+	timeout   time.Duration
+	namespace string
+	format    string
+	verbose   bool
+}
+
+// command is a subcommand. run returns an error wrapping errUsage for invalid arguments.
+type command struct {
+	usage string
+	run   func(ctx context.Context, env *env, args []string) error
+}
+
+// env is passed to the commands.
+type env struct {
+	cfg    *config
+	flags  *flag.FlagSet
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var errUsage = errors.New("invalid usage")
+
+var commands = map[string]command{
+	"status": {"status", runStatus},
+	"browse": {"browse [-r] [-depth n] [-filter pattern] [-type branch|item] [item]", runBrowse},
+	"read":   {"read [-path itemPath] item...", runRead},
+	"write":  {"write [-path itemPath] [-type xsdType] item=value...", runWrite},
+	"watch":  {"watch [-rate ms] [-n count] item...", runWatch},
+	"props":  {"props [-names name,...] item...", runProps},
+	"shell":  {"shell [-history file]", runShell},
+	"tui":    {"tui [-rate ms] [item...]", runTUI},
+}
+
+var commandOrder = []string{"status", "browse", "read", "write", "watch", "props", "shell", "tui"}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run executes the command line args and returns the exit code.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	cfg := &config{
+		url:     os.Getenv("OPC_URL"),
+		locale:  "en-US",
+		timeout: 10 * time.Second,
+		format:  "table",
+	}
+	fs := flag.NewFlagSet("opcxmlda", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(stderr, fs) }
+	cfg.register(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	name := fs.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "opcxmlda: unknown command %q\n", name)
+		fs.Usage()
+		return exitUsage
+	}
+
+	cmdFlags := flag.NewFlagSet(name, flag.ContinueOnError)
+	cmdFlags.SetOutput(stderr)
+	cmdFlags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: opcxmlda %s\n", cmd.usage)
+		cmdFlags.PrintDefaults()
+	}
+	cfg.register(cmdFlags)
+	e := &env{cfg: cfg, flags: cmdFlags, stdin: stdin, stdout: stdout, stderr: stderr}
+	err := cmd.run(ctx, e, fs.Args()[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil && err != errUsage {
+		fmt.Fprintf(stderr, "opcxmlda %s: %v\n", name, err)
+	}
+	if errors.Is(err, errUsage) {
+		cmdFlags.Usage()
+	}
+	return exitCode(err)
+}
+
+// register adds the common flags to fs, with the current values as defaults.
+func (c *config) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.url, "url", c.url, "URL of the server, $OPC_URL by default")
+	fs.StringVar(&c.locale, "locale", c.locale, "locale ID of the requests")
+	fs.DurationVar(&c.timeout, "timeout", c.timeout, "timeout of every request")
+	fs.StringVar(&c.namespace, "namespace", c.namespace, "namespace prefix of the requests, ns0 if empty")
+	fs.StringVar(&c.format, "format", c.format, "output format: table, json or csv")
+	fs.BoolVar(&c.verbose, "verbose", c.verbose, "log the errors of the client to stderr")
+}
+
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: opcxmlda [flags] <command> [command flags] [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+}
+
+// parse parses the arguments of a command and returns the client for the server.
+func (e *env) parse(args []string) (*gopcxmlda.Server, error) {
+	if err := e.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, errUsage
+	}
+	switch e.cfg.format {
+	case "table", "json", "csv":
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", errUsage, e.cfg.format)
+	}
+	if e.cfg.url == "" {
+		return nil, fmt.Errorf("%w: no server URL, set --url or $OPC_URL", errUsage)
+	}
+	u, err := url.Parse(e.cfg.url)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%w: invalid URL %q", errUsage, e.cfg.url)
+	}
+	log.SetOutput(e.stderr)
+	if !e.cfg.verbose {
+		log.SetOutput(io.Discard)
+	}
+	return gopcxmlda.NewServer(u, e.cfg.locale, gopcxmlda.WithTimeout(e.cfg.timeout)), nil
+}
+
+// exitCode returns the exit code for the error of a command.
+func exitCode(err error) int {
+	var fault *gopcxmlda.SoapFault
+	var opcErr *gopcxmlda.OpcError
+	var itemErr *gopcxmlda.ItemError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.As(err, &fault):
+		return exitFault
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return exitTimeout
+	case errors.As(err, &itemErr), errors.As(err, &opcErr):
+		return exitItemError
+	default:
+		return exitError
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+	"github.com/dernate/gopcxmlda/opctest"
+)
+
+func newTestServer(t *testing.T) *opctest.Server {
+	t.Helper()
+	srv := opctest.NewServer([]opctest.Tag{
+		{Name: "Plant1/P", Value: 1200.5, Properties: map[string]interface{}{"engineeringUnits": "kW"}},
+		{Name: "Plant1/Status", Value: 2, ReadOnly: true},
+		{Name: "Plant1/Ctrl/SetP", Value: 2000.0},
+		{Name: "Plant1/Ctrl/Modes", Value: []int{1, 2}},
+	})
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// runCommand runs the command line args against srv and returns the exit code and the output.
+func runCommand(t *testing.T, srv *opctest.Server, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), append([]string{"--url", srv.URL}, args...), strings.NewReader(""), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestStatus(t *testing.T) {
+	srv := newTestServer(t)
+	code, stdout, stderr := runCommand(t, srv, "status")
+	if code != exitOK || !strings.Contains(stdout, "running") || !strings.Contains(stdout, "opctest") {
+		t.Errorf("unexpected result %d: %s%s", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCommand(t, srv, "status", "--format", "json")
+	var status map[string]string
+	if err := json.Unmarshal([]byte(stdout), &status); code != exitOK || err != nil || status["serverState"] != "running" {
+		t.Errorf("unexpected JSON %d: %s", code, stdout)
+	}
+}
+
+func TestRead(t *testing.T) {
+	srv := newTestServer(t)
+	code, stdout, stderr := runCommand(t, srv, "--format", "json", "read", "Plant1/P", "Plant1/Ctrl/Modes")
+	var rows []itemRow
+	if err := json.Unmarshal([]byte(stdout), &rows); code != exitOK || err != nil {
+		t.Fatalf("unexpected result %d: %s%s", code, stdout, stderr)
+	}
+	if len(rows) != 2 || rows[0].Item != "Plant1/P" || rows[0].Value != 1200.5 || rows[0].Quality != "good" || rows[1].Type != "ArrayOfInt" {
+		t.Errorf("unexpected rows: %+v", rows)
+	}
+
+	code, stdout, stderr = runCommand(t, srv, "read", "Plant1/P", "Unknown")
+	if code != exitItemError || !strings.Contains(stdout, "E_UNKNOWNITEMNAME") || !strings.Contains(stderr, `"Unknown"`) {
+		t.Errorf("unexpected result %d: %s%s", code, stdout, stderr)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	srv := newTestServer(t)
+	code, stdout, stderr := runCommand(t, srv, "write", "Plant1/Ctrl/SetP=1500", "Plant1/Ctrl/Modes=3,4,5")
+	if code != exitOK {
+		t.Fatalf("unexpected result %d: %s%s", code, stdout, stderr)
+	}
+	if v, _ := srv.Value("Plant1/Ctrl/SetP"); v != 1500.0 {
+		t.Errorf("expected 1500, got %v", v)
+	}
+	if v, _ := srv.Value("Plant1/Ctrl/Modes"); len(v.([]interface{})) != 3 {
+		t.Errorf("unexpected array: %v", v)
+	}
+
+	code, stdout, stderr = runCommand(t, srv, "write", "Plant1/Status=1")
+	if code != exitItemError || !strings.Contains(stdout, "E_READONLY") {
+		t.Errorf("unexpected result %d: %s%s", code, stdout, stderr)
+	}
+	if code, _, _ = runCommand(t, srv, "write", "-type", "int", "Plant1/Ctrl/SetP=high"); code != exitUsage {
+		t.Errorf("expected usage error, got %d", code)
+	}
+}
+
+func TestBrowse(t *testing.T) {
+	srv := newTestServer(t)
+	code, stdout, stderr := runCommand(t, srv, "--format", "csv", "browse", "-r", "Plant1")
+	records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if code != exitOK || err != nil {
+		t.Fatalf("unexpected result %d: %s%s", code, stdout, stderr)
+	}
+	if len(records) != 6 || records[0][0] != "name" || records[3][0] != "Ctrl" || records[4][0] != "  SetP" {
+		t.Errorf("unexpected records: %q", records)
+	}
+
+	code, stdout, _ = runCommand(t, srv, "browse", "-type", "branch", "Plant1")
+	if code != exitOK || !strings.Contains(stdout, "Ctrl") || strings.Contains(stdout, "Status") {
+		t.Errorf("unexpected result %d: %s", code, stdout)
+	}
+}
+
+func TestProps(t *testing.T) {
+	srv := newTestServer(t)
+	code, stdout, stderr := runCommand(t, srv, "props", "-names", "dataType,engineeringUnits", "Plant1/P")
+	if code != exitOK || !strings.Contains(stdout, "xsd:double") || !strings.Contains(stdout, "kW") || strings.Contains(stdout, "accessRights") {
+		t.Errorf("unexpected result %d: %s%s", code, stdout, stderr)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	srv := newTestServer(t)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		srv.SetValue("Plant1/P", 1300.0)
+	}()
+	code, stdout, stderr := runCommand(t, srv, "--format", "json", "watch", "-rate", "200", "-n", "2", "Plant1/P")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if code != exitOK || len(lines) != 2 {
+		t.Fatalf("unexpected result %d: %s%s", code, stdout, stderr)
+	}
+	var row itemRow
+	if err := json.Unmarshal([]byte(lines[1]), &row); err != nil || row.Item != "Plant1/P" || row.Value != 1300.0 {
+		t.Errorf("unexpected change %q: %v", lines[1], err)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	srv := newTestServer(t)
+	tests := []struct {
+		name  string
+		fault *opctest.Fault
+		args  []string
+		code  int
+	}{
+		{"no command", nil, nil, exitUsage},
+		{"unknown command", nil, []string{"restart"}, exitUsage},
+		{"no items", nil, []string{"read"}, exitUsage},
+		{"unknown format", nil, []string{"--format", "xml", "status"}, exitUsage},
+		{"fault", &opctest.Fault{Err: gopcxmlda.ErrBusy}, []string{"read", "Plant1/P"}, exitFault},
+		{"timeout", &opctest.Fault{Delay: time.Second}, []string{"--timeout", "50ms", "status"}, exitTimeout},
+		{"status code", &opctest.Fault{StatusCode: 503}, []string{"status"}, exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.ClearFaults()
+			if tt.fault != nil {
+				srv.InjectFault(*tt.fault)
+			}
+			if code, stdout, stderr := runCommand(t, srv, tt.args...); code != tt.code {
+				t.Errorf("expected exit code %d, got %d: %s%s", tt.code, code, stdout, stderr)
+			}
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"--url", "", "status"}, nil, &stdout, &stderr); code != exitUsage {
+		t.Errorf("expected usage error without URL, got %d", code)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// output writes rows as aligned table, CSV or JSON. Tables and CSV are written with a header,
+// JSON as array of the row values, or with stream set as one value per line.
+type output struct {
+	format string
+	stream bool
+	w      io.Writer
+	tw     *tabwriter.Writer
+	cw     *csv.Writer
+	values []interface{}
+}
+
+func newOutput(format string, w io.Writer, stream bool, header ...string) *output {
+	o := &output{format: format, stream: stream, w: w}
+	switch format {
+	case "csv":
+		o.cw = csv.NewWriter(w)
+		o.cw.Write(header)
+	case "json":
+		o.values = []interface{}{}
+	default:
+		o.tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(o.tw, strings.ToUpper(strings.Join(header, "\t")))
+	}
+	return o
+}
+
+// row adds a row, value is written for JSON and fields for tables and CSV.
+func (o *output) row(value interface{}, fields ...string) error {
+	switch {
+	case o.cw != nil:
+		return o.cw.Write(fields)
+	case o.tw != nil:
+		_, err := fmt.Fprintln(o.tw, strings.Join(fields, "\t"))
+		return err
+	case o.stream:
+		return json.NewEncoder(o.w).Encode(value)
+	default:
+		o.values = append(o.values, value)
+		return nil
+	}
+}
+
+// flush writes the buffered rows.
+func (o *output) flush() error {
+	switch {
+	case o.cw != nil:
+		o.cw.Flush()
+		return o.cw.Error()
+	case o.tw != nil:
+		return o.tw.Flush()
+	case o.stream:
+		return nil
+	default:
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(o.values)
+	}
+}
+
+// itemRow is an item value as written by read, write and watch.
+type itemRow struct {
+	Item      string      `json:"item"`
+	Path      string      `json:"path,omitempty"`
+	Value     interface{} `json:"value,omitempty"`
+	Type      string      `json:"type,omitempty"`
+	Quality   string      `json:"quality,omitempty"`
+	Timestamp *time.Time  `json:"timestamp,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+var itemHeader = []string{"item", "value", "type", "quality", "timestamp", "error"}
+
+// newItemRow returns the row of an item, name is used if the server did not return the ItemName.
+func newItemRow(item gopcxmlda.TItem, name string) itemRow {
+	row := itemRow{
+		Item:    item.ItemName,
+		Path:    item.ItemPath,
+		Value:   item.Value.Value,
+		Type:    item.Value.Type,
+		Quality: formatQuality(item.Quality),
+		Error:   string(gopcxmlda.ParseResultCode(item.Error)),
+	}
+	if row.Item == "" {
+		row.Item = name
+	}
+	if !item.Timestamp.IsZero() {
+		row.Timestamp = &item.Timestamp
+	}
+	return row
+}
+
+func (r itemRow) fields() []string {
+	timestamp := ""
+	if r.Timestamp != nil {
+		timestamp = r.Timestamp.Format(time.RFC3339Nano)
+	}
+	return []string{r.Item, formatValue(r.Value), r.Type, r.Quality, timestamp, r.Error}
+}
+
+// formatValue formats a value for tables and CSV, the elements of arrays separated by commas.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case gopcxmlda.TQuality:
+		return formatQuality(v)
+	case []interface{}:
+		elements := make([]string, len(v))
+		for i, element := range v {
+			elements[i] = formatValue(element)
+		}
+		return strings.Join(elements, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatQuality returns the QualityField, followed by the LimitField if the value is limited.
+func formatQuality(quality gopcxmlda.TQuality) string {
+	if quality.LimitField != "" && quality.LimitField != "none" {
+		return quality.QualityField + "/" + quality.LimitField
+	}
+	return quality.QualityField
+}
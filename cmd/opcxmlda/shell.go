@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// historySize is the number of lines kept by the history of the terminal.
+const historySize = 100
+
+const shellHelp = `Commands:
+  cd [path]                             change the current branch, ".." is the parent, "/" the root
+  ls [-l] [path]                        list the elements of a branch, branches end with "/"
+  pwd                                   show the current branch
+  get path...                           read the values of items
+  set path value [type]                 write a value, typed by the dataType property of the item or type
+  props [-names a,b] path...            show the properties of items
+  watch [-rate ms] [-n count] path...   show the changes of items until Enter is pressed
+  refresh                               forget the cached browse results
+  help                                  show this help
+  exit                                  leave the shell
+Paths are relative to the current branch, unless they start with "/". Names which are not found
+by browsing are used as item names. Tab completes commands and paths.
+`
+
+var shellCommands = []string{"cd", "exit", "get", "help", "ls", "props", "pwd", "refresh", "set", "watch"}
+
+// lineReader reads the lines of the shell, a term.Terminal or a lineScanner.
+type lineReader interface {
+	ReadLine() (string, error)
+}
+
+// lineScanner reads lines from a reader which is no terminal.
+type lineScanner struct {
+	*bufio.Scanner
+}
+
+func (s lineScanner) ReadLine() (string, error) {
+	if !s.Scan() {
+		return "", cmp.Or(s.Err(), io.EOF)
+	}
+	return s.Text(), nil
+}
+
+type input struct {
+	line string
+	err  error
+}
+
+// shell is an interactive session on a server.
+type shell struct {
+	*browser
+	e           *env
+	terminal    *term.Terminal // nil if stdin is no terminal
+	interactive bool
+	history     io.Writer // nil if the history is not saved
+
+	lines   chan input
+	next    chan struct{}
+	pending bool // a line was requested but not received yet
+}
+
+func runShell(ctx context.Context, e *env, args []string) error {
+	historyFile := e.flags.String("history", defaultHistoryFile(), "file the command history is saved in, not saved if empty")
+	s, err := e.parse(args)
+	if err != nil {
+		return err
+	}
+	if e.flags.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected arguments %q", errUsage, e.flags.Args())
+	}
+	sh := &shell{
+		browser: newBrowser(s, e.cfg.namespace),
+		e:       e,
+		lines:   make(chan input, 1),
+		next:    make(chan struct{}),
+	}
+
+	var reader lineReader = lineScanner{bufio.NewScanner(e.stdin)}
+	if f, ok := e.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		state, err := term.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return err
+		}
+		defer term.Restore(int(f.Fd()), state)
+		conn := &terminalConn{Reader: f, Writer: e.stdout}
+		sh.terminal = term.NewTerminal(conn, "")
+		sh.terminal.AutoCompleteCallback = sh.complete
+		if width, height, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			sh.terminal.SetSize(width, height)
+		}
+		sh.interactive = true
+		if *historyFile != "" {
+			sh.loadHistory(conn, readHistory(*historyFile))
+			if f, err := os.OpenFile(*historyFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600); err == nil {
+				defer f.Close()
+				sh.history = f
+			}
+		}
+		reader = sh.terminal
+		// the output of the commands is written through the terminal, which redraws the prompt
+		e = &env{cfg: e.cfg, flags: e.flags, stdin: e.stdin, stdout: sh.terminal, stderr: sh.terminal}
+		sh.e = e
+	}
+	go sh.readLines(reader)
+	defer close(sh.next)
+
+	if sh.interactive {
+		fmt.Fprintf(e.stdout, "Connected to %s, type \"help\" for the commands.\n", e.cfg.url)
+	}
+	for {
+		if sh.terminal != nil {
+			sh.terminal.SetPrompt(sh.prompt())
+		}
+		var in input
+		select {
+		case in = <-sh.readLine():
+			sh.pending = false
+		case <-ctx.Done():
+			return nil
+		}
+		if in.err != nil {
+			if errors.Is(in.err, io.EOF) {
+				return nil
+			}
+			return in.err
+		}
+		line := strings.TrimSpace(in.line)
+		if line == "" {
+			continue
+		}
+		if sh.history != nil {
+			fmt.Fprintln(sh.history, line)
+		}
+		if err := sh.exec(ctx, line); err != nil {
+			if errors.Is(err, errExit) {
+				return nil
+			}
+			fmt.Fprintf(e.stderr, "error: %v\n", err)
+		}
+	}
+}
+
+// terminalConn is the connection of the terminal. Its Reader and Writer are replaced while the
+// saved history is loaded.
+type terminalConn struct {
+	io.Reader
+	io.Writer
+}
+
+// defaultHistoryFile returns the file the history is saved in by default.
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".opcxmlda_history")
+}
+
+// readHistory returns the last lines of the history file.
+func readHistory(file string) []string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" && strings.IndexFunc(line, func(r rune) bool { return !unicode.IsPrint(r) }) < 0 {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > historySize {
+		lines = lines[len(lines)-historySize:]
+	}
+	return lines
+}
+
+// loadHistory loads lines into the history of the terminal. The terminal has no way to set its
+// history, so the lines are entered with its output discarded.
+func (sh *shell) loadHistory(conn *terminalConn, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	reader, writer := conn.Reader, conn.Writer
+	conn.Reader = strings.NewReader(strings.Join(lines, "\r") + "\r")
+	conn.Writer = io.Discard
+	for range lines {
+		if _, err := sh.terminal.ReadLine(); err != nil {
+			break
+		}
+	}
+	conn.Reader, conn.Writer = reader, writer
+}
+
+// readLines reads a line whenever one is requested on sh.next.
+func (sh *shell) readLines(reader lineReader) {
+	for range sh.next {
+		line, err := reader.ReadLine()
+		sh.lines <- input{line, err}
+	}
+}
+
+// readLine requests the next line, unless it was already requested, and returns the channel it is
+// received on. The receiver clears sh.pending.
+func (sh *shell) readLine() <-chan input {
+	if !sh.pending {
+		sh.next <- struct{}{}
+		sh.pending = true
+	}
+	return sh.lines
+}
+
+func (sh *shell) prompt() string {
+	return "opcxmlda:" + sh.pwd() + "> "
+}
+
+var errExit = errors.New("exit")
+
+// exec executes a command line.
+func (sh *shell) exec(ctx context.Context, line string) error {
+	args, err := splitArgs(line)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	e := sh.e
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "exit", "quit":
+		return errExit
+	case "help":
+		fmt.Fprint(e.stdout, shellHelp)
+	case "pwd":
+		fmt.Fprintln(e.stdout, sh.pwd())
+	case "refresh":
+		sh.refresh()
+	case "cd":
+		target := "/"
+		if len(args) > 0 {
+			target = args[0]
+		}
+		cwd, err := sh.resolve(ctx, target)
+		if err != nil {
+			return err
+		}
+		if len(cwd) > 0 && !cwd[len(cwd)-1].HasChildren {
+			return fmt.Errorf("%s is no branch", target)
+		}
+		sh.cwd = cwd
+	case "ls":
+		fs := sh.flagSet("ls")
+		long := fs.Bool("l", false, "show the item names")
+		if err := fs.Parse(args); err != nil {
+			return nil
+		}
+		return sh.ls(ctx, fs.Arg(0), *long)
+	case "get":
+		if len(args) == 0 {
+			return errors.New("usage: get path...")
+		}
+		return e.read(ctx, sh.s, sh.itemNames(ctx, args), "")
+	case "set":
+		if len(args) < 2 || len(args) > 3 {
+			return errors.New("usage: set path value [type]")
+		}
+		xsdType := ""
+		if len(args) == 3 {
+			xsdType = args[2]
+		}
+		return e.write(ctx, sh.s, sh.itemNames(ctx, args[:1]), args[1:2], "", xsdType)
+	case "props":
+		fs := sh.flagSet("props")
+		names := fs.String("names", "", "comma-separated property names, all properties if empty")
+		if err := fs.Parse(args); err != nil {
+			return nil
+		}
+		if fs.NArg() == 0 {
+			return errors.New("usage: props [-names a,b] path...")
+		}
+		var properties []string
+		if *names != "" {
+			properties = strings.Split(*names, ",")
+		}
+		return e.properties(ctx, sh.s, sh.itemNames(ctx, fs.Args()), "", properties)
+	case "watch":
+		fs := sh.flagSet("watch")
+		rate := fs.Uint("rate", 1000, "SubscriptionPingRate in milliseconds")
+		count := fs.Int("n", 0, "stop after n changes")
+		if err := fs.Parse(args); err != nil {
+			return nil
+		}
+		if fs.NArg() == 0 {
+			return errors.New("usage: watch [-rate ms] [-n count] path...")
+		}
+		return sh.watch(ctx, sh.itemNames(ctx, fs.Args()), *rate, *count)
+	default:
+		return fmt.Errorf("unknown command %q, type \"help\" for the commands", cmd)
+	}
+	return nil
+}
+
+func (sh *shell) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(sh.e.stderr)
+	return fs
+}
+
+// watch shows the changes of the items. In an interactive shell, it stops when a line is entered.
+func (sh *shell) watch(ctx context.Context, names []string, rate uint, count int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- sh.e.watch(ctx, sh.s, names, "", rate, count)
+	}()
+	if !sh.interactive {
+		return <-done
+	}
+	sh.terminal.SetPrompt("")
+	fmt.Fprintln(sh.e.stdout, "Press Enter to stop.")
+	select {
+	case err := <-done:
+		return err
+	case <-sh.readLine():
+		sh.pending = false
+		cancel()
+		return <-done
+	}
+}
+
+// ls writes the elements of a branch.
+func (sh *shell) ls(ctx context.Context, target string, long bool) error {
+	branch, err := sh.resolve(ctx, target)
+	if err != nil {
+		return err
+	}
+	elements, err := sh.children(ctx, branch)
+	if err != nil {
+		return err
+	}
+	if !long {
+		for _, element := range elements {
+			name := element.Name
+			if element.HasChildren {
+				name += "/"
+			}
+			fmt.Fprintln(sh.e.stdout, name)
+		}
+		return nil
+	}
+	out := newOutput(sh.e.cfg.format, sh.e.stdout, false, "name", "item", "isItem", "hasChildren")
+	for _, element := range elements {
+		row := browseRow{element.Name, element.ItemName, element.ItemPath, element.IsItem, element.HasChildren, 0}
+		if err := out.row(row, element.Name, element.ItemName, strconv.FormatBool(element.IsItem), strconv.FormatBool(element.HasChildren)); err != nil {
+			return err
+		}
+	}
+	return out.flush()
+}
+
+// complete completes the command or path before the cursor when Tab is pressed.
+func (sh *shell) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	start := strings.LastIndexByte(line[:pos], ' ') + 1
+	word := line[start:pos]
+	var candidates []string
+	if strings.TrimSpace(line[:start]) == "" {
+		for _, cmd := range shellCommands {
+			if strings.HasPrefix(cmd, word) {
+				candidates = append(candidates, cmd+" ")
+			}
+		}
+	} else {
+		dir, prefix := "", word
+		if i := strings.LastIndexByte(word, '/'); i >= 0 {
+			dir, prefix = word[:i+1], word[i+1:]
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), sh.e.cfg.timeout)
+		defer cancel()
+		branch, err := sh.resolve(ctx, dir)
+		if err != nil {
+			return "", 0, false
+		}
+		elements, err := sh.children(ctx, branch)
+		if err != nil {
+			return "", 0, false
+		}
+		for _, element := range elements {
+			if !strings.HasPrefix(element.Name, prefix) {
+				continue
+			}
+			if element.HasChildren {
+				candidates = append(candidates, dir+element.Name+"/")
+			} else {
+				candidates = append(candidates, dir+element.Name+" ")
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+	completion := commonPrefix(candidates)
+	if len(candidates) > 1 {
+		completion = strings.TrimRight(completion, " ")
+	}
+	if len(completion) <= len(word) {
+		return "", 0, false
+	}
+	return line[:start] + completion + line[pos:], start + len(completion), true
+}
+
+// commonPrefix returns the longest common prefix of words.
+func commonPrefix(words []string) string {
+	sort.Strings(words)
+	first, last := words[0], words[len(words)-1]
+	i := 0
+	for i < len(first) && i < len(last) && first[i] == last[i] {
+		i++
+	}
+	return first[:i]
+}
+
+// splitArgs splits a command line at spaces. Double-quoted arguments may contain spaces and
+// the escape sequences of Go strings.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var arg bytes.Buffer
+	inArg := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, errors.New("unterminated quote")
+			}
+			s, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, err
+			}
+			arg.WriteString(s)
+			inArg = true
+			i = end
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
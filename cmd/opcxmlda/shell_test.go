@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runScript runs the shell with script as input and returns the exit code and the output.
+func runScript(t *testing.T, url string, script string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"--url", url, "shell"}, strings.NewReader(script), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestShell(t *testing.T) {
+	srv := newTestServer(t)
+	code, stdout, stderr := runScript(t, srv.URL, strings.Join([]string{
+		"ls",
+		"cd Plant1",
+		"pwd",
+		"ls -l Ctrl",
+		"cd Ctrl/..",
+		"get P Ctrl/SetP",
+		"set Ctrl/SetP 1500",
+		"get /Plant1/Ctrl/SetP",
+		"props -names engineeringUnits P",
+		"watch -n 1 P",
+		"cd Unknown",
+		"exit",
+		"pwd",
+	}, "\n"))
+	if code != exitOK {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	for _, want := range []string{"Plant1/\n", "/Plant1\n", "SetP", "Modes", "1200.5", "1500", "kW"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output misses %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Count(stdout, "/Plant1\n") != 1 {
+		t.Errorf("commands after exit executed:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "Unknown not found") {
+		t.Errorf("unexpected errors: %s", stderr)
+	}
+	if v, _ := srv.Value("Plant1/Ctrl/SetP"); v != 1500.0 {
+		t.Errorf("unexpected value %v", v)
+	}
+}
+
+func TestShellComplete(t *testing.T) {
+	srv := newTestServer(t)
+	sh := &shell{
+		browser: newBrowser(srv.Client(), ""),
+		e:       &env{cfg: &config{timeout: time.Second}, stdout: &bytes.Buffer{}, stderr: &bytes.Buffer{}},
+	}
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"w", "watch "},
+		{"ge", "get "},
+		{"p", "p"},
+		{"cd P", "cd Plant1/"},
+		{"get Plant1/C", "get Plant1/Ctrl/"},
+		{"get Plant1/Ctrl/S", "get Plant1/Ctrl/SetP "},
+		{"get /Plant1/S", "get /Plant1/Status "},
+		{"get Plant1/X", "get Plant1/X"},
+	}
+	for _, test := range tests {
+		line, pos, ok := sh.complete(test.line, len(test.line), '\t')
+		if !ok {
+			line, pos = test.line, len(test.line)
+		}
+		if line != test.want || pos != len(test.want) {
+			t.Errorf("%q: got %q at %d, want %q", test.line, line, pos, test.want)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(`set  "Plant 1/Name" "a \"b\"" string`)
+	if err != nil || !reflect.DeepEqual(args, []string{"set", "Plant 1/Name", `a "b"`, "string"}) {
+		t.Errorf("unexpected args %q: %v", args, err)
+	}
+	if _, err := splitArgs(`set "a`); err == nil {
+		t.Error("no error for an unterminated quote")
+	}
+}
+
+func TestReadHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history")
+	var lines []string
+	for i := 0; i < historySize+10; i++ {
+		lines = append(lines, "get P"+strings.Repeat("x", i%3))
+	}
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n\nls\x1b[A\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	history := readHistory(file)
+	if len(history) != historySize || history[historySize-1] != lines[len(lines)-1] {
+		t.Errorf("unexpected history %q", history)
+	}
+}
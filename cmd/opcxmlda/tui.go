@@ -0,0 +1,533 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dernate/gopcxmlda"
+	"golang.org/x/term"
+)
+
+const (
+	sparkWidth = 20 // number of values shown by the sparklines
+	treeWidth  = 30 // width of the browse pane
+)
+
+// ANSI escape sequences of the dashboard.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiReverse   = "\x1b[7m"
+	ansiRed       = "\x1b[31m"
+	ansiYellow    = "\x1b[33m"
+	ansiGreen     = "\x1b[32m"
+	ansiHome      = "\x1b[H"
+	ansiClearLine = "\x1b[K"
+	ansiClearDown = "\x1b[J"
+	ansiEnter     = "\x1b[?1049h\x1b[?25l" // alternate screen, hidden cursor
+	ansiLeave     = "\x1b[?25h\x1b[?1049l"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+const tuiHelp = "Tab pane  ↑↓ move  Enter open/add  ← parent  d remove  t tree  r refresh  q quit"
+
+// watchItem is an item of the watchlist.
+type watchItem struct {
+	name      string
+	value     interface{}
+	quality   gopcxmlda.TQuality
+	timestamp time.Time
+	err       string
+	history   []float64 // the last sparkWidth numeric values
+}
+
+// dashboard is the state of the tui command.
+type dashboard struct {
+	*browser
+	url      string
+	rate     uint
+	now      func() time.Time
+	items    []*watchItem
+	selected int                        // selected row of the watchlist
+	elements []gopcxmlda.TBrowseElement // elements of the current branch
+	cursor   int                        // selected element of the browse pane
+	browsing bool                       // the browse pane has the focus
+	showTree bool
+	status   string // last error, shown instead of the help
+	width    int
+	height   int
+}
+
+func runTUI(ctx context.Context, e *env, args []string) error {
+	rate := e.flags.Uint("rate", 1000, "SubscriptionPingRate in milliseconds")
+	s, err := e.parse(args)
+	if err != nil {
+		return err
+	}
+	in, ok := e.stdin.(*os.File)
+	out, ok2 := e.stdout.(*os.File)
+	if !ok || !ok2 || !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return errors.New("tui needs a terminal")
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+	fmt.Fprint(out, ansiEnter)
+	defer fmt.Fprint(out, ansiLeave)
+
+	d := newDashboard(s, e.cfg, *rate, e.flags.Args())
+	keys := make(chan string)
+	go readKeys(in, keys)
+	return d.run(ctx, keys, out, func() (int, int) {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil || width <= 0 || height <= 0 {
+			return 80, 24
+		}
+		return width, height
+	})
+}
+
+func newDashboard(s *gopcxmlda.Server, cfg *config, rate uint, names []string) *dashboard {
+	d := &dashboard{
+		browser:  newBrowser(s, cfg.namespace),
+		url:      cfg.url,
+		rate:     rate,
+		now:      time.Now,
+		showTree: true,
+		browsing: len(names) == 0,
+		width:    80,
+		height:   24,
+	}
+	for _, name := range names {
+		d.add(name)
+	}
+	return d
+}
+
+// run updates and draws the dashboard until q is pressed or ctx is canceled. The watchlist is
+// subscribed again whenever it is changed.
+func (d *dashboard) run(ctx context.Context, keys <-chan string, out io.Writer, size func() (int, int)) error {
+	d.width, d.height = size()
+	d.open(ctx)
+	var sub *gopcxmlda.Subscription
+	subscribe := func() {
+		if sub != nil {
+			sub.Close()
+			sub = nil
+		}
+		if len(d.items) == 0 {
+			return
+		}
+		names := make([]string, len(d.items))
+		for i, item := range d.items {
+			names[i] = item.name
+		}
+		items, handles := newItems(names, "")
+		var err error
+		sub, err = d.s.StartSubscription(ctx, items, gopcxmlda.SubscriptionOptions{
+			Namespace:           d.namespace,
+			PingRate:            d.rate,
+			ReturnValuesOnReply: true,
+			Options:             itemOptions,
+			ClientItemHandles:   handles,
+			Resubscribe:         true,
+		})
+		if err != nil {
+			d.status = err.Error()
+		}
+	}
+	subscribe()
+	defer func() {
+		if sub != nil {
+			sub.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		if _, err := io.WriteString(out, d.render()); err != nil {
+			return err
+		}
+		var events <-chan gopcxmlda.SubscriptionEvent
+		if sub != nil {
+			events = sub.Events()
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			d.width, d.height = size()
+		case event, ok := <-events:
+			if !ok {
+				d.status = fmt.Sprintf("subscription ended: %v", sub.Err())
+				sub = nil
+				continue
+			}
+			d.update(event)
+		case key := <-keys:
+			n := len(d.items)
+			if !d.key(ctx, key) {
+				return nil
+			}
+			if len(d.items) != n {
+				subscribe()
+			}
+		}
+	}
+}
+
+// update applies a subscription event.
+func (d *dashboard) update(event gopcxmlda.SubscriptionEvent) {
+	switch event.Type {
+	case gopcxmlda.EventItems:
+		names := make([]string, len(d.items))
+		for i, item := range d.items {
+			names[i] = item.name
+		}
+		for _, item := range event.Items {
+			name := nameOf(item, names)
+			for _, w := range d.items {
+				if w.name == name {
+					w.set(item)
+				}
+			}
+		}
+	case gopcxmlda.EventError:
+		d.status = event.Err.Error()
+	case gopcxmlda.EventDataBufferOverflow:
+		d.status = "data buffer overflow, values were dropped"
+	case gopcxmlda.EventResubscribed:
+		d.status = fmt.Sprintf("subscribed again after %v: %v", event.Gap.Round(time.Millisecond), event.Err)
+	}
+}
+
+// set stores the value of a subscribed item.
+func (w *watchItem) set(item gopcxmlda.TItem) {
+	w.err = string(gopcxmlda.ParseResultCode(item.Error))
+	if w.err != "" {
+		return
+	}
+	w.value = item.Value.Value
+	w.quality = item.Quality
+	w.timestamp = item.Timestamp
+	if f, ok := numeric(w.value); ok {
+		w.history = append(w.history, f)
+		if len(w.history) > sparkWidth {
+			w.history = w.history[len(w.history)-sparkWidth:]
+		}
+	}
+}
+
+// key handles a key and returns false to quit.
+func (d *dashboard) key(ctx context.Context, key string) bool {
+	d.status = ""
+	switch key {
+	case "q", "ctrl-c":
+		return false
+	case "tab":
+		d.browsing = d.showTree && !d.browsing
+	case "t":
+		d.showTree = !d.showTree
+		d.browsing = d.showTree
+	case "r":
+		d.refresh()
+		d.open(ctx)
+	case "up", "k":
+		if d.browsing {
+			d.cursor = max(d.cursor-1, 0)
+		} else {
+			d.selected = max(d.selected-1, 0)
+		}
+	case "down", "j":
+		if d.browsing {
+			d.cursor = max(min(d.cursor+1, len(d.elements)-1), 0)
+		} else {
+			d.selected = max(min(d.selected+1, len(d.items)-1), 0)
+		}
+	case "d", "delete":
+		if !d.browsing && d.selected < len(d.items) {
+			d.items = append(d.items[:d.selected], d.items[d.selected+1:]...)
+			d.selected = max(min(d.selected, len(d.items)-1), 0)
+		}
+	case "left", "backspace":
+		if d.browsing && len(d.cwd) > 0 {
+			name := d.cwd[len(d.cwd)-1].Name
+			d.cwd = d.cwd[:len(d.cwd)-1]
+			d.open(ctx)
+			for i, element := range d.elements {
+				if element.Name == name {
+					d.cursor = i
+				}
+			}
+		}
+	case "enter", "right", "a":
+		if !d.browsing || d.cursor >= len(d.elements) {
+			break
+		}
+		element := d.elements[d.cursor]
+		if element.HasChildren && key != "a" {
+			d.cwd = append(d.cwd, element)
+			d.open(ctx)
+		} else if element.IsItem && key != "right" {
+			d.add(element.ItemName)
+		}
+	}
+	return true
+}
+
+// open browses the current branch.
+func (d *dashboard) open(ctx context.Context) {
+	elements, err := d.children(ctx, d.cwd)
+	if err != nil {
+		d.status = err.Error()
+	}
+	d.elements = elements
+	d.cursor = 0
+}
+
+// add adds an item to the watchlist, unless it is already watched.
+func (d *dashboard) add(name string) {
+	for _, item := range d.items {
+		if item.name == name {
+			return
+		}
+	}
+	d.items = append(d.items, &watchItem{name: name})
+}
+
+// render returns the frame of the dashboard, drawn over the previous one.
+func (d *dashboard) render() string {
+	var b strings.Builder
+	b.WriteString(ansiHome)
+	line := func(text string) {
+		b.WriteString(text)
+		b.WriteString(ansiReset + ansiClearLine + "\r\n")
+	}
+
+	title := fmt.Sprintf(" opcxmlda %s  %d items  %s", d.url, len(d.items), d.now().Format("15:04:05"))
+	line(ansiReverse + pad(title, d.width))
+
+	tableWidth := d.width
+	if d.showTree {
+		tableWidth = max(d.width-treeWidth-3, 0)
+	}
+	columns := []int{0, 14, 18, 12, 6, sparkWidth}
+	if tableWidth < 80 {
+		columns = columns[:5]
+	}
+	fixed := 0
+	for _, width := range columns[1:] {
+		fixed += width + 1
+	}
+	columns[0] = max(tableWidth-fixed, 10)
+
+	// the title, the header and the status line take three lines, the watchlist is scrolled to
+	// the selected item
+	rows := max(d.height-3, 0)
+	first := max(d.selected-rows+1, 0)
+	for i := -1; i < rows; i++ {
+		var text string
+		if d.showTree {
+			text = d.treeLine(i, rows) + " │ "
+		}
+		if i < 0 {
+			header := []string{"ITEM", "VALUE", "QUALITY", "TIMESTAMP", "AGE", "TREND"}
+			text += ansiBold + d.cells(header, columns) + ansiReset
+		} else if first+i < len(d.items) {
+			text += d.itemLine(first+i, columns)
+		}
+		line(text)
+	}
+
+	if d.status != "" {
+		b.WriteString(ansiRed + pad(" "+d.status, d.width) + ansiReset + ansiClearDown)
+	} else {
+		b.WriteString(pad(" "+tuiHelp, d.width) + ansiClearDown)
+	}
+	return b.String()
+}
+
+// treeLine returns a line of the browse pane of rows lines, -1 is its header. The elements are
+// scrolled to the cursor.
+func (d *dashboard) treeLine(i int, rows int) string {
+	if i < 0 {
+		return ansiBold + pad(d.pwd(), treeWidth) + ansiReset
+	}
+	i += max(d.cursor-rows+1, 0)
+	if i >= len(d.elements) {
+		return pad("", treeWidth)
+	}
+	element := d.elements[i]
+	name := element.Name
+	if element.HasChildren {
+		name += "/"
+	}
+	text := pad(" "+name, treeWidth)
+	if i == d.cursor && d.browsing {
+		return ansiReverse + text + ansiReset
+	}
+	return text
+}
+
+// itemLine returns the line of an item of the watchlist, colored by its quality.
+func (d *dashboard) itemLine(i int, columns []int) string {
+	item := d.items[i]
+	quality, timestamp, age := item.err, "", ""
+	if quality == "" {
+		quality = formatQuality(item.quality)
+	}
+	if !item.timestamp.IsZero() {
+		timestamp = item.timestamp.Local().Format("15:04:05.000")
+		age = formatAge(d.now().Sub(item.timestamp))
+	}
+	text := d.cells([]string{item.name, formatValue(item.value), quality, timestamp, age, sparkline(item.history)}, columns)
+	color := ""
+	switch {
+	case item.quality.QualityField == "" && item.err == "":
+		// no value received yet
+	case item.err != "" || item.quality.IsBad():
+		color = ansiRed
+	case item.quality.IsUncertain():
+		color = ansiYellow
+	default:
+		color = ansiGreen
+	}
+	if i == d.selected && !d.browsing {
+		color += ansiReverse
+	}
+	return color + text + ansiReset
+}
+
+// cells pads the cells to the widths of the columns.
+func (d *dashboard) cells(cells []string, columns []int) string {
+	texts := make([]string, len(columns))
+	for i, width := range columns {
+		texts[i] = pad(cells[i], width)
+	}
+	return strings.Join(texts, " ")
+}
+
+// pad truncates or pads text to width runes.
+func pad(text string, width int) string {
+	n := utf8.RuneCountInString(text)
+	if n > width {
+		runes := []rune(text)
+		if width < 1 {
+			return ""
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-n)
+}
+
+// formatAge formats the age of a value in its largest unit.
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Second:
+		return "<1s"
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age/time.Second))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age/time.Minute))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age/time.Hour))
+	default:
+		return fmt.Sprintf("%dd", int(age/(24*time.Hour)))
+	}
+}
+
+// sparkline draws values as blocks, scaled between their minimum and maximum.
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		low, high = min(low, v), max(high, v)
+	}
+	blocks := make([]rune, len(values))
+	for i, v := range values {
+		level := len(sparkBlocks) / 2
+		if high > low {
+			level = int((v - low) / (high - low) * float64(len(sparkBlocks)-1))
+		}
+		blocks[i] = sparkBlocks[level]
+	}
+	return string(blocks)
+}
+
+// numeric returns a finite numeric value as float64.
+func numeric(value interface{}) (float64, bool) {
+	f, err := gopcxmlda.ValueAs[float64](gopcxmlda.TValue{Value: value})
+	return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// readKeys sends the keys read from r to keys.
+func readKeys(r io.Reader, keys chan<- string) {
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+var escapeKeys = map[string]string{
+	"\x1b[A": "up", "\x1b[B": "down", "\x1b[C": "right", "\x1b[D": "left",
+	"\x1bOA": "up", "\x1bOB": "down", "\x1bOC": "right", "\x1bOD": "left",
+	"\x1b[3~": "delete",
+}
+
+// parseKeys returns the names of the keys in the input of a terminal in raw mode: "up", "down",
+// "left", "right", "enter", "tab", "backspace", "delete", "esc", "ctrl-c" or the character.
+func parseKeys(input []byte) []string {
+	var keys []string
+	for len(input) > 0 {
+		if input[0] == 0x1b {
+			found := false
+			for seq, key := range escapeKeys {
+				if strings.HasPrefix(string(input), seq) {
+					keys = append(keys, key)
+					input = input[len(seq):]
+					found = true
+					break
+				}
+			}
+			if !found {
+				keys = append(keys, "esc")
+				input = input[1:]
+			}
+			continue
+		}
+		r, size := utf8.DecodeRune(input)
+		input = input[size:]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case '\t':
+			keys = append(keys, "tab")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		case 0x03:
+			keys = append(keys, "ctrl-c")
+		default:
+			keys = append(keys, string(r))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// waitFor waits until the last frame written to b contains text.
+func (b *syncBuffer) waitFor(t *testing.T, text string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		frames := strings.Split(b.buf.String(), ansiHome)
+		b.mu.Unlock()
+		if strings.Contains(frames[len(frames)-1], text) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%q not shown", text)
+}
+
+func TestDashboard(t *testing.T) {
+	srv := newTestServer(t)
+	d := newDashboard(srv.Client(), &config{url: srv.URL}, 100, []string{"Plant1/P"})
+	keys := make(chan string)
+	out := &syncBuffer{}
+	done := make(chan error)
+	go func() {
+		done <- d.run(context.Background(), keys, out, func() (int, int) { return 140, 12 })
+	}()
+	out.waitFor(t, "1200.5")
+
+	// browse to Plant1/Status and add it to the watchlist
+	for _, key := range []string{"tab", "enter", "down", "enter"} {
+		keys <- key
+	}
+	out.waitFor(t, "Plant1/Status")
+	srv.SetValue("Plant1/P", 1300.0)
+	out.waitFor(t, "1300")
+
+	// remove Plant1/P
+	for _, key := range []string{"tab", "d", "q"} {
+		keys <- key
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(d.items) != 1 || d.items[0].name != "Plant1/Status" || d.items[0].value != 2 {
+		t.Errorf("unexpected watchlist %+v", d.items)
+	}
+	if d.pwd() != "/Plant1" {
+		t.Errorf("unexpected branch %s", d.pwd())
+	}
+}
+
+func TestDashboardRender(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	d := &dashboard{
+		browser:  newBrowser(nil, ""),
+		url:      "http://opc",
+		now:      func() time.Time { return now },
+		showTree: true,
+		width:    120,
+		height:   10,
+		elements: []gopcxmlda.TBrowseElement{{Name: "Ctrl", HasChildren: true}, {Name: "P", IsItem: true}},
+	}
+	good := &watchItem{name: "P"}
+	for i, v := range []float64{1, 2, 3, 4} {
+		good.set(gopcxmlda.TItem{
+			Value:     gopcxmlda.TValue{Value: v},
+			Quality:   gopcxmlda.TQuality{QualityField: "good"},
+			Timestamp: now.Add(time.Duration(i-65) * time.Second),
+		})
+	}
+	bad := &watchItem{name: "T"}
+	bad.set(gopcxmlda.TItem{Value: gopcxmlda.TValue{Value: "x"}, Quality: gopcxmlda.TQuality{QualityField: "badCommFailure"}})
+	uncertain := &watchItem{name: "S"}
+	uncertain.set(gopcxmlda.TItem{Value: gopcxmlda.TValue{Value: 1}, Quality: gopcxmlda.TQuality{QualityField: "uncertain", LimitField: "high"}})
+	d.items = []*watchItem{good, bad, uncertain}
+
+	frame := d.render()
+	if lines := strings.Count(frame, "\r\n") + 1; lines != d.height {
+		t.Errorf("frame of %d lines, want %d", lines, d.height)
+	}
+	for _, want := range []string{"Ctrl/", "1m", "▁▃▅█", ansiRed + "T ", ansiYellow + "S ", "uncertain/high", tuiHelp} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("frame misses %q:\n%s", want, frame)
+		}
+	}
+
+	d.status = "E_FAIL"
+	d.showTree = false
+	if frame := d.render(); !strings.Contains(frame, ansiRed+" E_FAIL") || strings.Contains(frame, "Ctrl/") {
+		t.Errorf("unexpected frame:\n%s", frame)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("a\x1b[A\x1b[B\r\t\x7f\x1b[3~\x1b\x03ä"))
+	want := []string{"a", "up", "down", "enter", "tab", "backspace", "delete", "esc", "ctrl-c", "ä"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %q, want %q", keys, want)
+	}
+}
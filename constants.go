@@ -25,7 +25,12 @@ const EnvelopeOpen1 = "<SOAP-ENV:Envelope " +
 
 const EnvelopeOpen2 = "=\"http://opcfoundation.org/webservices/XMLDA/1.0/\">"
 
-const EnvelopeHeader = "<SOAP-ENV:Header></SOAP-ENV:Header>"
+const EnvelopeHeaderOpen = "<SOAP-ENV:Header>"
+
+// HEADER ELEMENTS OF THE HEADER PROVIDERS GO HERE
+
+const EnvelopeHeaderClose = "</SOAP-ENV:Header>"
+const EnvelopeHeader = EnvelopeHeaderOpen + EnvelopeHeaderClose
 const EnvelopeBodyOpenNs1 = "<SOAP-ENV:Body xmlns:"
 
 const EnvelopeHeaderToBody = EnvelopeOpen2 + EnvelopeHeader + EnvelopeBodyOpenNs1
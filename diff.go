@@ -0,0 +1,193 @@
+package gopcxmlda
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AddressSpaceDiff is the difference between two address spaces, as returned by DiffAddressSpaces.
+type AddressSpaceDiff struct {
+	Added   []AddressSpaceElement `json:"added" yaml:"added"`
+	Removed []AddressSpaceElement `json:"removed" yaml:"removed"`
+	Changed []ElementChange       `json:"changed" yaml:"changed"`
+}
+
+// ElementChange lists the changed attributes and properties of an element found in both address spaces.
+type ElementChange struct {
+	Name     string           `json:"name" yaml:"name"`
+	ItemName string           `json:"itemName" yaml:"itemName"`
+	ItemPath string           `json:"itemPath,omitempty" yaml:"itemPath,omitempty"`
+	Changes  []PropertyChange `json:"changes" yaml:"changes"`
+}
+
+// PropertyChange is a single changed attribute or property of an element.
+type PropertyChange struct {
+	Property string `json:"property" yaml:"property"`
+	Old      string `json:"old" yaml:"old"`
+	New      string `json:"new" yaml:"new"`
+}
+
+// HasChanges reports whether elements were added, removed or changed.
+func (d *AddressSpaceDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// DiffAddressSpaces compares two address spaces. Elements are matched by ItemPath and ItemName.
+// Properties are only compared if both elements have them, so a snapshot exported without
+// properties can be compared by structure.
+//
+// Example:
+//
+//	before, _ := ReadAddressSpace(beforeFile, FormatJSON)
+//	after, _ := ReadAddressSpace(afterFile, FormatJSON)
+//	diff := DiffAddressSpaces(before, after)
+//	if diff.HasChanges() {
+//		_ = diff.Write(os.Stdout, FormatJSON)
+//		os.Exit(1)
+//	}
+func DiffAddressSpaces(before *AddressSpace, after *AddressSpace) *AddressSpaceDiff {
+	d := &AddressSpaceDiff{}
+	oldElements := make(map[string]AddressSpaceElement, len(before.Elements))
+	for _, element := range before.Elements {
+		oldElements[diffKey(element)] = element
+	}
+	newKeys := make(map[string]bool, len(after.Elements))
+	for _, element := range after.Elements {
+		key := diffKey(element)
+		newKeys[key] = true
+		oldElement, ok := oldElements[key]
+		if !ok {
+			d.Added = append(d.Added, element)
+			continue
+		}
+		if changes := elementChanges(oldElement, element); len(changes) > 0 {
+			d.Changed = append(d.Changed, ElementChange{
+				Name:     element.Name,
+				ItemName: element.ItemName,
+				ItemPath: element.ItemPath,
+				Changes:  changes,
+			})
+		}
+	}
+	for _, element := range before.Elements {
+		if !newKeys[diffKey(element)] {
+			d.Removed = append(d.Removed, element)
+		}
+	}
+	return d
+}
+
+// DiffServers exports the address space below itemPath from both servers in parallel and compares them.
+func DiffServers(ctx context.Context, before *Server, after *Server, itemPath string, namespace string, options ExportOptions) (*AddressSpaceDiff, error) {
+	var wg sync.WaitGroup
+	var oldAs, newAs *AddressSpace
+	var oldErr, newErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		oldAs, oldErr = before.ExportAddressSpace(ctx, itemPath, namespace, options)
+	}()
+	go func() {
+		defer wg.Done()
+		newAs, newErr = after.ExportAddressSpace(ctx, itemPath, namespace, options)
+	}()
+	wg.Wait()
+	if oldErr != nil {
+		return nil, fmt.Errorf("export of %s: %w", before.Url, oldErr)
+	}
+	if newErr != nil {
+		return nil, fmt.Errorf("export of %s: %w", after.Url, newErr)
+	}
+	return DiffAddressSpaces(oldAs, newAs), nil
+}
+
+// Write writes the diff in the given format. As CSV, every change is a row with the columns
+// change (added, removed or changed), itemName, itemPath, property, old and new.
+func (d *AddressSpaceDiff) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(d)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(d); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatCSV:
+		return d.writeCSV(w)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+func (d *AddressSpaceDiff) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"change", "itemName", "itemPath", "property", "old", "new"}); err != nil {
+		return err
+	}
+	for _, element := range d.Added {
+		if err := writer.Write([]string{"added", element.ItemName, element.ItemPath, "", "", ""}); err != nil {
+			return err
+		}
+	}
+	for _, element := range d.Removed {
+		if err := writer.Write([]string{"removed", element.ItemName, element.ItemPath, "", "", ""}); err != nil {
+			return err
+		}
+	}
+	for _, element := range d.Changed {
+		for _, change := range element.Changes {
+			if err := writer.Write([]string{"changed", element.ItemName, element.ItemPath, change.Property, change.Old, change.New}); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// diffKey identifies an element in both address spaces.
+func diffKey(element AddressSpaceElement) string {
+	if element.ItemName == "" {
+		return element.ItemPath + "\x00\x00" + element.Name
+	}
+	return element.ItemPath + "\x00" + element.ItemName
+}
+
+// elementChanges returns the changed attributes and properties of an element.
+func elementChanges(before AddressSpaceElement, after AddressSpaceElement) []PropertyChange {
+	var changes []PropertyChange
+	compare := func(property string, oldValue string, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, PropertyChange{Property: property, Old: oldValue, New: newValue})
+		}
+	}
+	compare("isItem", strconv.FormatBool(before.IsItem), strconv.FormatBool(after.IsItem))
+	compare("hasChildren", strconv.FormatBool(before.HasChildren), strconv.FormatBool(after.HasChildren))
+	if before.Properties == nil || after.Properties == nil {
+		return changes
+	}
+	o, n := before.Properties, after.Properties
+	compare("dataType", o.DataType, n.DataType)
+	compare("accessRights", o.AccessRights, n.AccessRights)
+	compare("euType", o.EUType, n.EUType)
+	compare("euInfo", strings.Join(o.EUInfo, "|"), strings.Join(n.EUInfo, "|"))
+	compare("engineeringUnits", o.EngineeringUnits, n.EngineeringUnits)
+	compare("description", o.Description, n.Description)
+	compare("highEU", formatFloat(o.HighEU), formatFloat(n.HighEU))
+	compare("lowEU", formatFloat(o.LowEU), formatFloat(n.LowEU))
+	compare("highIR", formatFloat(o.HighIR), formatFloat(n.HighIR))
+	compare("lowIR", formatFloat(o.LowIR), formatFloat(n.LowIR))
+	return changes
+}
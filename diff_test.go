@@ -0,0 +1,56 @@
+package gopcxmlda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestDiffAddressSpaces(t *testing.T) {
+	high := 100.0
+	before := &AddressSpace{Elements: []AddressSpaceElement{
+		{Name: "P", ItemName: "Plant/P", IsItem: true, Properties: &ElementProperties{DataType: "xsd:float", AccessRights: "readable", HighEU: &high}},
+		{Name: "Old", ItemName: "Plant/Old", IsItem: true},
+		{Name: "Same", ItemName: "Plant/Same", IsItem: true, Properties: &ElementProperties{DataType: "xsd:int"}},
+	}}
+	after := &AddressSpace{Elements: []AddressSpaceElement{
+		{Name: "P", ItemName: "Plant/P", IsItem: true, Properties: &ElementProperties{DataType: "xsd:double", AccessRights: "readWritable"}},
+		{Name: "Same", ItemName: "Plant/Same", IsItem: true},
+		{Name: "New", ItemName: "Plant/New", IsItem: true},
+	}}
+	d := DiffAddressSpaces(before, after)
+	if !d.HasChanges() || len(d.Added) != 1 || d.Added[0].Name != "New" || len(d.Removed) != 1 || d.Removed[0].Name != "Old" {
+		t.Fatalf("unexpected diff %+v", d)
+	}
+	if len(d.Changed) != 1 || len(d.Changed[0].Changes) != 3 {
+		t.Fatalf("unexpected changes %+v", d.Changed)
+	}
+	if c := d.Changed[0].Changes[0]; c.Property != "dataType" || c.Old != "xsd:float" || c.New != "xsd:double" {
+		t.Fatalf("unexpected change %+v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := d.Write(&buf, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded AddressSpaceDiff
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Changed[0].Changes) != 3 {
+		t.Fatalf("unexpected JSON %s: %v", buf.String(), err)
+	}
+	if DiffAddressSpaces(after, after).HasChanges() {
+		t.Fatal("expected no changes")
+	}
+}
+
+func TestDiffServers(t *testing.T) {
+	a, _ := newTreeServer(t)
+	b, _ := newTreeServer(t)
+	d, err := DiffServers(context.Background(), a, b, "", "", ExportOptions{Properties: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.HasChanges() {
+		t.Fatalf("expected no changes, got %+v", d)
+	}
+}
@@ -0,0 +1,204 @@
+package gopcxmlda
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ResultCode is a result code of the OPC XML-DA specification, as used in ResultID
+// attributes, OPCError IDs and SOAP fault codes. Codes starting with "E_" are errors,
+// codes starting with "S_" indicate success with additional information.
+// All error types of this package unwrap to their ResultCode, so they can be tested with errors.Is:
+//
+//	if errors.Is(err, ErrUnknownItemName) {
+//		// handle unknown item
+//	}
+type ResultCode string
+
+// Result codes defined by the OPC XML-DA specification.
+const (
+	ResultClamp                 ResultCode = "S_CLAMP"
+	ResultDataQueueOverflow     ResultCode = "S_DATAQUEUEOVERFLOW"
+	ResultUnsupportedRate       ResultCode = "S_UNSUPPORTEDRATE"
+	ErrAccessDenied             ResultCode = "E_ACCESS_DENIED"
+	ErrBusy                     ResultCode = "E_BUSY"
+	ErrFail                     ResultCode = "E_FAIL"
+	ErrInvalidContinuationPoint ResultCode = "E_INVALIDCONTINUATIONPOINT"
+	ErrInvalidFilter            ResultCode = "E_INVALIDFILTER"
+	ErrInvalidHoldTime          ResultCode = "E_INVALIDHOLDTIME"
+	ErrInvalidItemName          ResultCode = "E_INVALIDITEMNAME"
+	ErrInvalidItemPath          ResultCode = "E_INVALIDITEMPATH"
+	ErrInvalidPID               ResultCode = "E_INVALIDPID"
+	ErrNoSubscription           ResultCode = "E_NOSUBSCRIPTION"
+	ErrNotSupported             ResultCode = "E_NOTSUPPORTED"
+	ErrOutOfMemory              ResultCode = "E_OUTOFMEMORY"
+	ErrRange                    ResultCode = "E_RANGE"
+	ErrReadOnly                 ResultCode = "E_READONLY"
+	ErrServerState              ResultCode = "E_SERVERSTATE"
+	ErrTimedOut                 ResultCode = "E_TIMEDOUT"
+	ErrUnknownItemName          ResultCode = "E_UNKNOWNITEMNAME"
+	ErrUnknownItemPath          ResultCode = "E_UNKNOWNITEMPATH"
+	ErrWriteOnly                ResultCode = "E_WRITEONLY"
+	ErrBadType                  ResultCode = "E_BADTYPE"
+)
+
+// ParseResultCode returns the ResultCode of a qualified name like "opc:E_READONLY".
+func ParseResultCode(qname string) ResultCode {
+	return ResultCode(qname[strings.LastIndex(qname, ":")+1:])
+}
+
+func (c ResultCode) Error() string {
+	return string(c)
+}
+
+// IsSuccess reports whether the code indicates success, which is the case for "S_" codes.
+func (c ResultCode) IsSuccess() bool {
+	return strings.HasPrefix(string(c), "S_")
+}
+
+// SoapFault is returned if the server answers with a SOAP fault.
+type SoapFault struct {
+	Code   string
+	String string
+	Detail string
+}
+
+func (f *SoapFault) Error() string {
+	return fmt.Sprintf("Faultcode: %s, Faultstring: %s, Detail: %s", f.Code, f.String, f.Detail)
+}
+
+// Unwrap returns the ResultCode of the fault code, servers use them e.g. for E_SERVERSTATE.
+func (f *SoapFault) Unwrap() error {
+	return ParseResultCode(f.Code)
+}
+
+// OpcError is returned for an OPCError element of a response.
+type OpcError struct {
+	ID   string
+	Type string
+	Text []string
+}
+
+func (e *OpcError) Error() string {
+	return fmt.Sprintf("Id: %s, Text: %s, Type: %s", e.ID, e.Text, e.Type)
+}
+
+// Unwrap returns the ResultCode of the error ID.
+func (e *OpcError) Unwrap() error {
+	return ParseResultCode(e.ID)
+}
+
+// ItemError is the error of a single item, given by the ResultID attribute of the item.
+type ItemError struct {
+	ItemName         string
+	ItemPath         string
+	ClientItemHandle string
+	ResultID         ResultCode
+	Text             string // error text of the server, if requested with ReturnErrorText
+}
+
+func (e *ItemError) Error() string {
+	msg := fmt.Sprintf("item %q", e.ItemName)
+	if e.ItemPath != "" {
+		msg += fmt.Sprintf(" (path %q)", e.ItemPath)
+	}
+	msg += ": " + string(e.ResultID)
+	if e.Text != "" {
+		msg += ": " + e.Text
+	}
+	return msg
+}
+
+// Unwrap returns the ResultID of the item.
+func (e *ItemError) Unwrap() error {
+	return e.ResultID
+}
+
+// Err returns an *ItemError if the item has a ResultID, nil otherwise.
+// Note that success codes like S_CLAMP are returned as well, see ResultCode.IsSuccess.
+func (i TItem) Err() error {
+	if i.Error == "" {
+		return nil
+	}
+	return &ItemError{
+		ItemName:         i.ItemName,
+		ItemPath:         i.ItemPath,
+		ClientItemHandle: i.ClientItemHandle,
+		ResultID:         ParseResultCode(i.Error),
+	}
+}
+
+// ItemErrors returns the errors of all items which could not be read as joined error.
+func (r TRead) ItemErrors() error {
+	return itemErrors(r.Response.ItemList.Items, r.Response.Errors)
+}
+
+// ItemErrors returns the errors of all items which could not be written as joined error.
+func (w TWrite) ItemErrors() error {
+	return itemErrors(w.Response.ItemList.Items, w.Response.Errors)
+}
+
+// ItemErrors returns the errors of all items which could not be subscribed as joined error.
+func (s TSubscribe) ItemErrors() error {
+	items := make([]TItem, len(s.Response.ItemList.Items))
+	for i, item := range s.Response.ItemList.Items {
+		items[i] = item.ItemValue
+	}
+	return itemErrors(items, s.Response.Errors)
+}
+
+// ItemErrors returns the errors of all items of the refresh with a failure ResultID as joined error.
+func (s TSubscriptionPolledRefresh) ItemErrors() error {
+	return itemErrors(s.Response.ItemList.Items, s.Response.Errors)
+}
+
+// ItemErrors returns the errors of all items whose properties could not be retrieved as joined error.
+func (p TGetProperties) ItemErrors() error {
+	items := make([]TItem, len(p.Response.PropertyList))
+	for i, list := range p.Response.PropertyList {
+		items[i] = TItem{ItemName: list.ItemName, ItemPath: list.ItemPath, Error: list.ResultId}
+	}
+	return itemErrors(items, p.Response.Errors)
+}
+
+// itemErrors joins the errors of all items with a failure ResultID.
+// The error text is taken from opcErrors, if it belongs to the ResultID.
+func itemErrors(items []TItem, opcErrors OpcErrors) error {
+	var errReturn error
+	for _, item := range items {
+		err := item.Err()
+		if err == nil {
+			continue
+		}
+		itemErr := err.(*ItemError)
+		if itemErr.ResultID.IsSuccess() {
+			continue
+		}
+		if len(opcErrors.Text) > 0 && ParseResultCode(opcErrors.Id) == itemErr.ResultID {
+			itemErr.Text = opcErrors.Text[0]
+		}
+		errReturn = errors.Join(errReturn, itemErr)
+	}
+	return errReturn
+}
+
+// responseError returns the SOAP fault and the OPC errors of a response as joined error.
+func responseError(fault TSoapError, opcErrors OpcErrors) error {
+	var errReturn error
+	if fault.FaultCode != "" {
+		errReturn = errors.Join(errReturn, &SoapFault{
+			Code:   fault.FaultCode,
+			String: fault.FaultString,
+			Detail: fault.Detail,
+		})
+	}
+	if opcErrors.Id != "" {
+		errReturn = errors.Join(errReturn, &OpcError{
+			ID:   opcErrors.Id,
+			Type: opcErrors.Type,
+			Text: opcErrors.Text,
+		})
+	}
+	return errReturn
+}
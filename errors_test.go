@@ -0,0 +1,66 @@
+package gopcxmlda
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestItemErrors(t *testing.T) {
+	s, _ := newTestServer(t, func(action string, body string) string {
+		return soapEnvelope(`<ReadResponse xmlns="http://opcfoundation.org/webservices/XMLDA/1.0/">` +
+			`<ReadResult RcvTime="2024-01-01T00:00:00Z" ReplyTime="2024-01-01T00:00:00Z" ServerState="running"/>` +
+			`<RItemList>` +
+			`<Items ItemName="Good" ClientItemHandle="h0"><Value xsi:type="xsd:int">1</Value></Items>` +
+			`<Items ItemName="Clamped" ClientItemHandle="h1" ResultID="S_CLAMP"><Value xsi:type="xsd:int">2</Value></Items>` +
+			`<Items ItemName="Missing" ClientItemHandle="h2" ResultID="E_UNKNOWNITEMNAME"/>` +
+			`</RItemList>` +
+			`<Errors ID="E_UNKNOWNITEMNAME"><Text>The item name is not known</Text></Errors>` +
+			`</ReadResponse>`)
+	})
+	items := []TItem{{ItemName: "Good"}, {ItemName: "Clamped"}, {ItemName: "Missing"}}
+	var ClientRequestHandle string
+	var ClientItemHandles []string
+	R, err := s.Read(context.Background(), items, &ClientRequestHandle, &ClientItemHandles, "", TRequestOptions{})
+	var opcErr *OpcError
+	if !errors.As(err, &opcErr) || !errors.Is(err, ErrUnknownItemName) {
+		t.Fatalf("expected OpcError for E_UNKNOWNITEMNAME, got %v", err)
+	}
+
+	err = R.ItemErrors()
+	var itemErr *ItemError
+	if !errors.As(err, &itemErr) {
+		t.Fatalf("expected ItemError, got %v", err)
+	}
+	if itemErr.ItemName != "Missing" || itemErr.ClientItemHandle != "h2" || itemErr.Text != "The item name is not known" {
+		t.Fatalf("unexpected ItemError: %+v", itemErr)
+	}
+	if errors.Is(err, ResultClamp) {
+		t.Fatal("success codes must not be reported by ItemErrors")
+	}
+	if !errors.Is(R.Response.ItemList.Items[1].Err(), ResultClamp) {
+		t.Fatal("expected S_CLAMP from TItem.Err")
+	}
+	if R.Response.ItemList.Items[0].Err() != nil {
+		t.Fatal("expected no error for good item")
+	}
+}
+
+func TestSoapFault(t *testing.T) {
+	s, _ := newTestServer(t, func(action string, body string) string {
+		return soapEnvelope(`<soap:Fault><faultcode>opc:E_NOSUBSCRIPTION</faultcode>` +
+			`<faultstring>no subscription</faultstring></soap:Fault>`)
+	})
+	var ClientRequestHandle string
+	canceled, err := s.SubscriptionCancel(context.Background(), "sub", "", &ClientRequestHandle)
+	var fault *SoapFault
+	if canceled || !errors.As(err, &fault) {
+		t.Fatalf("expected SoapFault, got %v, %v", canceled, err)
+	}
+	if fault.Code != "opc:E_NOSUBSCRIPTION" || fault.String != "no subscription" {
+		t.Fatalf("unexpected fault: %+v", fault)
+	}
+	if !errors.Is(err, ErrNoSubscription) {
+		t.Fatal("expected fault to match ErrNoSubscription")
+	}
+}
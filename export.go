@@ -0,0 +1,314 @@
+package gopcxmlda
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a file format of an exported AddressSpace.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatYAML Format = "yaml"
+)
+
+// exportPropertyNames are the properties requested for every item of an export.
+var exportPropertyNames = []string{
+	PropertyDataType, PropertyAccessRights, PropertyEUType, PropertyEUInfo, PropertyEngineeringUnits,
+	PropertyDescription, PropertyHighEU, PropertyLowEU, PropertyHighIR, PropertyLowIR,
+}
+
+// csvHeader are the columns of an AddressSpace exported as CSV.
+var csvHeader = []string{
+	"name", "itemName", "itemPath", "isItem", "hasChildren",
+	"dataType", "accessRights", "euType", "euInfo", "engineeringUnits", "description",
+	"highEU", "lowEU", "highIR", "lowIR",
+}
+
+// AddressSpace is a snapshot of the address space of a server, as created by ExportAddressSpace.
+type AddressSpace struct {
+	Server   string                `json:"server,omitempty" yaml:"server,omitempty"`
+	Root     string                `json:"root" yaml:"root"`
+	Created  time.Time             `json:"created" yaml:"created"`
+	Elements []AddressSpaceElement `json:"elements" yaml:"elements"`
+}
+
+// AddressSpaceElement is a browse element of an AddressSpace with the properties of its item.
+type AddressSpaceElement struct {
+	Name        string             `json:"name" yaml:"name"`
+	ItemName    string             `json:"itemName" yaml:"itemName"`
+	ItemPath    string             `json:"itemPath,omitempty" yaml:"itemPath,omitempty"`
+	IsItem      bool               `json:"isItem" yaml:"isItem"`
+	HasChildren bool               `json:"hasChildren" yaml:"hasChildren"`
+	Properties  *ElementProperties `json:"properties,omitempty" yaml:"properties,omitempty"`
+}
+
+// ElementProperties are the properties of an item exported with an AddressSpace.
+type ElementProperties struct {
+	DataType         string   `json:"dataType,omitempty" yaml:"dataType,omitempty"`
+	AccessRights     string   `json:"accessRights,omitempty" yaml:"accessRights,omitempty"`
+	EUType           string   `json:"euType,omitempty" yaml:"euType,omitempty"`
+	EUInfo           []string `json:"euInfo,omitempty" yaml:"euInfo,omitempty"`
+	EngineeringUnits string   `json:"engineeringUnits,omitempty" yaml:"engineeringUnits,omitempty"`
+	Description      string   `json:"description,omitempty" yaml:"description,omitempty"`
+	HighEU           *float64 `json:"highEU,omitempty" yaml:"highEU,omitempty"`
+	LowEU            *float64 `json:"lowEU,omitempty" yaml:"lowEU,omitempty"`
+	HighIR           *float64 `json:"highIR,omitempty" yaml:"highIR,omitempty"`
+	LowIR            *float64 `json:"lowIR,omitempty" yaml:"lowIR,omitempty"`
+}
+
+// ExportOptions configures ExportAddressSpace.
+type ExportOptions struct {
+	Walk       WalkOptions // options of the recursive browse
+	Properties bool        // request the properties of all items with GetProperties
+	BatchSize  int         // number of items per GetProperties request, 100 if zero
+}
+
+// ExportAddressSpace browses the address space below itemPath recursively and returns it as snapshot,
+// which can be written with AddressSpace.Write. The elements are in depth-first order.
+//
+// Parameters:
+// - ctx (context.Context): The context of the requests.
+// - itemPath (string): The path of the root item.
+// - namespace (string): The namespace to use for the requests.
+// - options (ExportOptions): The options of the export.
+//
+// Returns:
+// - (*AddressSpace): The snapshot of the address space.
+// - (error): An error if any issues occur during the requests.
+//
+// Example:
+//
+//	as, err := s.ExportAddressSpace(ctx, "Loc/Wec/Plant1", "", ExportOptions{Properties: true})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	err = as.Write(os.Stdout, FormatYAML)
+func (s *Server) ExportAddressSpace(ctx context.Context, itemPath string, namespace string, options ExportOptions) (*AddressSpace, error) {
+	root, err := s.BrowseTree(ctx, itemPath, namespace, options.Walk)
+	if err != nil {
+		return nil, err
+	}
+	as := &AddressSpace{
+		Root:    itemPath,
+		Created: time.Now().UTC().Truncate(time.Second),
+	}
+	if s.Url != nil {
+		as.Server = s.Url.String()
+	}
+	var flatten func(node *BrowseNode)
+	flatten = func(node *BrowseNode) {
+		for _, child := range node.Children {
+			as.Elements = append(as.Elements, AddressSpaceElement{
+				Name:        child.Element.Name,
+				ItemName:    child.Element.ItemName,
+				ItemPath:    child.Element.ItemPath,
+				IsItem:      child.Element.IsItem,
+				HasChildren: child.Element.HasChildren,
+			})
+			flatten(child)
+		}
+	}
+	flatten(root)
+
+	if options.Properties {
+		if err := s.exportProperties(ctx, namespace, as.Elements, options.BatchSize); err != nil {
+			return as, err
+		}
+	}
+	return as, nil
+}
+
+// exportProperties requests the properties of all items of elements in batches.
+func (s *Server) exportProperties(ctx context.Context, namespace string, elements []AddressSpaceElement, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	var indices []int
+	for i := range elements {
+		if elements[i].IsItem {
+			indices = append(indices, i)
+		}
+	}
+	for start := 0; start < len(indices); start += batchSize {
+		batch := indices[start:min(start+batchSize, len(indices))]
+		items := make([]TItem, len(batch))
+		for i, index := range batch {
+			items[i] = TItem{ItemName: elements[index].ItemName, ItemPath: elements[index].ItemPath}
+		}
+		var ClientRequestHandle string
+		response, err := s.GetProperties(ctx, items, TPropertyOptions{
+			PropertyNames:        exportPropertyNames,
+			ReturnPropertyValues: true,
+		}, &ClientRequestHandle, namespace)
+		if err != nil && len(response.Response.PropertyList) == 0 {
+			return err
+		}
+		for i, list := range response.Response.PropertyList {
+			if i < len(batch) && list.ResultId == "" {
+				elements[batch[i]].Properties = elementProperties(list)
+			}
+		}
+	}
+	return nil
+}
+
+// elementProperties converts the properties of a GetProperties response with NewItemProperties.
+// Properties of the wrong type are left out. The defaults unknown of accessRights and noEnum of
+// euType are left out like missing properties.
+func elementProperties(list TPropertyList) *ElementProperties {
+	item, _ := NewItemProperties(list)
+	p := &ElementProperties{
+		DataType:         item.DataType,
+		EUInfo:           item.EUInfo,
+		EngineeringUnits: item.EngineeringUnits,
+		Description:      item.Description,
+		HighEU:           item.HighEU,
+		LowEU:            item.LowEU,
+		HighIR:           item.HighIR,
+		LowIR:            item.LowIR,
+	}
+	if item.AccessRights != AccessUnknown {
+		p.AccessRights = item.AccessRights.String()
+	}
+	if item.EUType != EUTypeNoEnum {
+		p.EUType = item.EUType.String()
+	}
+	return p
+}
+
+// Write writes the address space in the given format. CSV contains the elements only,
+// without Server, Root and Created.
+func (as *AddressSpace) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(as)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(as); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatCSV:
+		return as.writeCSV(w)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// ReadAddressSpace reads an address space written by AddressSpace.Write.
+func ReadAddressSpace(r io.Reader, format Format) (*AddressSpace, error) {
+	as := &AddressSpace{}
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(as); err != nil {
+			return nil, err
+		}
+	case FormatYAML:
+		if err := yaml.NewDecoder(r).Decode(as); err != nil {
+			return nil, err
+		}
+	case FormatCSV:
+		if err := as.readCSV(r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+	return as, nil
+}
+
+func (as *AddressSpace) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, element := range as.Elements {
+		record := []string{
+			element.Name, element.ItemName, element.ItemPath,
+			strconv.FormatBool(element.IsItem), strconv.FormatBool(element.HasChildren),
+		}
+		p := element.Properties
+		if p == nil {
+			record = append(record, make([]string, len(csvHeader)-len(record))...)
+		} else {
+			record = append(record,
+				p.DataType, p.AccessRights, p.EUType, strings.Join(p.EUInfo, "|"), p.EngineeringUnits, p.Description,
+				formatFloat(p.HighEU), formatFloat(p.LowEU), formatFloat(p.HighIR), formatFloat(p.LowIR),
+			)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (as *AddressSpace) readCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		return errors.New("missing or unexpected CSV header")
+	}
+	for line, record := range records[1:] {
+		element := AddressSpaceElement{Name: record[0], ItemName: record[1], ItemPath: record[2]}
+		if element.IsItem, err = strconv.ParseBool(record[3]); err != nil {
+			return fmt.Errorf("line %d: isItem: %w", line+2, err)
+		}
+		if element.HasChildren, err = strconv.ParseBool(record[4]); err != nil {
+			return fmt.Errorf("line %d: hasChildren: %w", line+2, err)
+		}
+		if strings.Join(record[5:], "") != "" {
+			p := &ElementProperties{
+				DataType:         record[5],
+				AccessRights:     record[6],
+				EUType:           record[7],
+				EngineeringUnits: record[9],
+				Description:      record[10],
+			}
+			if record[8] != "" {
+				p.EUInfo = strings.Split(record[8], "|")
+			}
+			limits := []**float64{&p.HighEU, &p.LowEU, &p.HighIR, &p.LowIR}
+			for i, limit := range limits {
+				if record[11+i] == "" {
+					continue
+				}
+				f, err := strconv.ParseFloat(record[11+i], 64)
+				if err != nil {
+					return fmt.Errorf("line %d: %s: %w", line+2, csvHeader[11+i], err)
+				}
+				*limit = &f
+			}
+			element.Properties = p
+		}
+		as.Elements = append(as.Elements, element)
+	}
+	return nil
+}
+
+func formatFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'g', -1, 64)
+}
@@ -0,0 +1,45 @@
+package gopcxmlda
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestExportAddressSpace(t *testing.T) {
+	s, _ := newTreeServer(t)
+	as, err := s.ExportAddressSpace(context.Background(), "", "", ExportOptions{Properties: true, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(as.Elements) != 6 {
+		t.Fatalf("unexpected elements %+v", as.Elements)
+	}
+	p := as.Elements[1].Properties
+	if as.Elements[1].ItemName != "Plant1/P" || p == nil || p.DataType != "xsd:double" || p.AccessRights != "readWritable" ||
+		p.EUType != "analog" || p.EngineeringUnits != "kW" || *p.HighEU != 3000 || *p.LowIR != -10 || p.Description != `Power, "active"` {
+		t.Fatalf("unexpected properties %+v", as.Elements[1])
+	}
+	if as.Elements[0].Properties != nil {
+		t.Fatal("branches must not have properties")
+	}
+
+	for _, format := range []Format{FormatJSON, FormatYAML, FormatCSV} {
+		var buf bytes.Buffer
+		if err := as.Write(&buf, format); err != nil {
+			t.Fatal(format, err)
+		}
+		read, err := ReadAddressSpace(&buf, format)
+		if err != nil {
+			t.Fatal(format, err)
+		}
+		if format == FormatCSV {
+			// CSV contains the elements only
+			read.Server, read.Root, read.Created = as.Server, as.Root, as.Created
+		}
+		if !reflect.DeepEqual(as, read) {
+			t.Fatalf("%s round trip failed:\n%+v\n%+v", format, as, read)
+		}
+	}
+}
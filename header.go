@@ -0,0 +1,24 @@
+package gopcxmlda
+
+// HeaderProvider adds an element to the SOAP header of the requests sent to a server, e.g. UsernameToken.
+type HeaderProvider interface {
+	// SOAPHeader returns the element added to the header of a request of operation, like "Read".
+	// The element is encoded with encoding/xml, nil adds no element. SOAPHeader is called for every request.
+	SOAPHeader(operation string) (interface{}, error)
+}
+
+// HeaderProviderFunc is a function used as HeaderProvider.
+type HeaderProviderFunc func(operation string) (interface{}, error)
+
+// SOAPHeader returns f(operation).
+func (f HeaderProviderFunc) SOAPHeader(operation string) (interface{}, error) {
+	return f(operation)
+}
+
+// WithHeaderProviders adds providers of elements of the SOAP header of all requests sent to the server.
+// The elements are added in the order of the providers.
+func WithHeaderProviders(providers ...HeaderProvider) ServerOption {
+	return func(c *serverConfig) {
+		c.headerProviders = append(c.headerProviders, providers...)
+	}
+}
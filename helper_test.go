@@ -0,0 +1,101 @@
+package gopcxmlda
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testResponder answers a SOAP request. action is the last segment of the SOAPAction header.
+type testResponder func(action string, body string) string
+
+// newTestServer starts a local HTTP server answering with the given responder and
+// returns a Server pointing to it.
+func newTestServer(t *testing.T, responder testResponder, options ...ServerOption) (*Server, *httptest.Server) {
+	t.Helper()
+	ts := newUnstartedTestServer(responder)
+	ts.Start()
+	return serverFor(t, ts, options...), ts
+}
+
+// newUnstartedTestServer returns a local HTTP server answering with the given responder
+// which can be configured before it is started.
+func newUnstartedTestServer(responder testResponder) *httptest.Server {
+	return httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		action := r.Header.Get("SOAPAction")
+		action = action[strings.LastIndex(action, "/")+1:]
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		_, _ = io.WriteString(w, responder(action, string(body)))
+	}))
+}
+
+// serverFor returns a Server pointing to the started test server ts and closes ts when the test ends.
+func serverFor(t *testing.T, ts *httptest.Server, options ...ServerOption) *Server {
+	t.Helper()
+	t.Cleanup(ts.Close)
+	_url, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(_url, "en-US", options...)
+}
+
+// soapEnvelope wraps body into a SOAP envelope as sent by OPC XML-DA servers.
+func soapEnvelope(body string) string {
+	return `<?xml version="1.0" encoding="utf-8"?>` +
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" ` +
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema">` +
+		`<soap:Body>` + body + `</soap:Body></soap:Envelope>`
+}
+
+const testGetStatusResponse = `<GetStatusResponse xmlns="http://opcfoundation.org/webservices/XMLDA/1.0/">` +
+	`<GetStatusResult RcvTime="2024-01-01T00:00:00Z" ReplyTime="2024-01-01T00:00:00Z" ServerState="running"/>` +
+	`<Status StartTime="2024-01-01T00:00:00Z" ProductVersion="1.0"><VendorInfo>test</VendorInfo></Status>` +
+	`</GetStatusResponse>`
+
+const testSubscribeResponse = `<SubscribeResponse xmlns="http://opcfoundation.org/webservices/XMLDA/1.0/" ServerSubHandle="%s">` +
+	`<SubscribeResult RcvTime="2024-01-01T00:00:00Z" ReplyTime="2024-01-01T00:00:00Z" ServerState="running"/>` +
+	`<RItemList><Items><ItemValue ItemName="My/Item" ClientItemHandle="h0"><Value xsi:type="xsd:int">0</Value></ItemValue></Items></RItemList>` +
+	`</SubscribeResponse>`
+
+const testRefreshResponse = `<SubscriptionPolledRefreshResponse xmlns="http://opcfoundation.org/webservices/XMLDA/1.0/" DataBufferOverflow="%t">` +
+	`<SubscriptionPolledRefreshResult RcvTime="2024-01-01T00:00:00Z" ReplyTime="2024-01-01T00:00:01Z" ServerState="running"/>` +
+	`<RItemList SubscriptionHandle="%s"><Items ItemName="My/Item" ClientItemHandle="h0"><Value xsi:type="xsd:int">%d</Value></Items></RItemList>` +
+	`</SubscriptionPolledRefreshResponse>`
+
+const testInvalidHandleResponse = `<SubscriptionPolledRefreshResponse xmlns="http://opcfoundation.org/webservices/XMLDA/1.0/">` +
+	`<SubscriptionPolledRefreshResult RcvTime="2024-01-01T00:00:00Z" ReplyTime="2024-01-01T00:00:01Z" ServerState="running"/>` +
+	`<InvalidServerSubHandles>%s</InvalidServerSubHandles>` +
+	`</SubscriptionPolledRefreshResponse>`
+
+const testSubscriptionCancelResponse = `<SubscriptionCancelResponse xmlns="http://opcfoundation.org/webservices/XMLDA/1.0/"/>`
+
+// testPropertiesResponse answers a GetProperties request with the same properties for every item.
+func testPropertiesResponse(body string) string {
+	var lists strings.Builder
+	for _, part := range strings.Split(body, "ItemIDs ")[1:] {
+		name := strings.SplitN(part[strings.Index(part, `ItemName="`)+len(`ItemName="`):], `"`, 2)[0]
+		lists.WriteString(`<PropertyLists ItemName="` + name + `" ItemPath="">` +
+			`<Properties Name="dataType"><Value xsi:type="xsd:QName">xsd:double</Value></Properties>` +
+			`<Properties Name="accessRights"><Value xsi:type="xsd:string">readWritable</Value></Properties>` +
+			`<Properties Name="euType"><Value xsi:type="xsd:string">analog</Value></Properties>` +
+			`<Properties Name="engineeringUnits"><Value xsi:type="xsd:string">kW</Value></Properties>` +
+			`<Properties Name="description"><Value xsi:type="xsd:string">Power, "active"</Value></Properties>` +
+			`<Properties Name="highEU"><Value xsi:type="xsd:double">3000</Value></Properties>` +
+			`<Properties Name="lowEU"><Value xsi:type="xsd:double">0</Value></Properties>` +
+			`<Properties Name="highIR"><Value xsi:type="xsd:double">3500.5</Value></Properties>` +
+			`<Properties Name="lowIR"><Value xsi:type="xsd:double">-10</Value></Properties>` +
+			`</PropertyLists>`)
+	}
+	return `<GetPropertiesResponse xmlns="http://opcfoundation.org/webservices/XMLDA/1.0/">` +
+		`<GetPropertiesResult RcvTime="2024-01-01T00:00:00Z" ReplyTime="2024-01-01T00:00:00Z" ServerState="running"/>` +
+		lists.String() + `</GetPropertiesResponse>`
+}
@@ -0,0 +1,205 @@
+package opctest
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/dernate/gopcxmlda"
+	"github.com/dernate/gopcxmlda/server"
+)
+
+// backend serves the tags of a Server, the subscriptions are implemented by a server.SubscriptionEngine.
+type backend struct {
+	s *Server
+}
+
+// subscriber applies the injected faults to the subscription requests of the engine.
+type subscriber struct {
+	*server.SubscriptionEngine
+}
+
+func (s subscriber) Subscribe(ctx context.Context, request gopcxmlda.SubscribeRequest) (server.SubscribeResult, error) {
+	if err := injectedFault(ctx); err != nil {
+		return server.SubscribeResult{}, err
+	}
+	return s.SubscriptionEngine.Subscribe(ctx, request)
+}
+
+func (s subscriber) SubscriptionPolledRefresh(ctx context.Context, request gopcxmlda.SubscriptionPolledRefreshRequest) (server.RefreshResult, error) {
+	if err := injectedFault(ctx); err != nil {
+		return server.RefreshResult{}, err
+	}
+	return s.SubscriptionEngine.SubscriptionPolledRefresh(ctx, request)
+}
+
+func (s subscriber) SubscriptionCancel(ctx context.Context, serverSubHandle string) error {
+	if err := injectedFault(ctx); err != nil {
+		return err
+	}
+	return s.SubscriptionEngine.SubscriptionCancel(ctx, serverSubHandle)
+}
+
+func (b *backend) Status(ctx context.Context) (gopcxmlda.TStatus, string, error) {
+	if err := injectedFault(ctx); err != nil {
+		return gopcxmlda.TStatus{}, "", err
+	}
+	b.s.mu.Lock()
+	defer b.s.mu.Unlock()
+	return b.s.status, b.s.state, nil
+}
+
+// item returns the current value of an item or its ResultID. b.s.mu must be held.
+func (b *backend) item(item gopcxmlda.TItem) gopcxmlda.TItem {
+	if code, ok := b.s.itemErrors[item.ItemName]; ok {
+		item.Error = string(code)
+		return item
+	}
+	if item.ItemPath != "" {
+		item.Error = string(gopcxmlda.ErrUnknownItemPath)
+		return item
+	}
+	tag, ok := b.s.tags[item.ItemName]
+	if !ok {
+		item.Error = string(gopcxmlda.ErrUnknownItemName)
+		return item
+	}
+	value := b.s.itemOf(tag)
+	item.Value, item.Quality, item.Timestamp = value.Value, value.Quality, value.Timestamp
+	return item
+}
+
+func (b *backend) Read(ctx context.Context, _ gopcxmlda.TRequestOptions, items []gopcxmlda.TItem) ([]gopcxmlda.TItem, error) {
+	if err := injectedFault(ctx); err != nil {
+		return nil, err
+	}
+	b.s.mu.Lock()
+	defer b.s.mu.Unlock()
+	for i := range items {
+		items[i] = b.item(items[i])
+	}
+	return items, nil
+}
+
+func (b *backend) Write(ctx context.Context, _ gopcxmlda.TRequestOptions, items []gopcxmlda.TItem) ([]gopcxmlda.TItem, error) {
+	if err := injectedFault(ctx); err != nil {
+		return nil, err
+	}
+	b.s.mu.Lock()
+	defer b.s.mu.Unlock()
+	for i := range items {
+		items[i].Error = string(b.write(items[i]))
+	}
+	return items, nil
+}
+
+// write writes the value of an item to its tag. b.s.mu must be held.
+func (b *backend) write(item gopcxmlda.TItem) gopcxmlda.ResultCode {
+	if code, ok := b.s.itemErrors[item.ItemName]; ok {
+		return code
+	}
+	if item.ItemPath != "" {
+		return gopcxmlda.ErrUnknownItemPath
+	}
+	tag, ok := b.s.tags[item.ItemName]
+	if !ok {
+		return gopcxmlda.ErrUnknownItemName
+	}
+	if tag.ReadOnly || tag.Generator != nil {
+		return gopcxmlda.ErrReadOnly
+	}
+	if !sameType(tag, item.Value) {
+		return gopcxmlda.ErrBadType
+	}
+	tag.Value = item.Value.Value
+	tag.Timestamp = item.Timestamp
+	if tag.Timestamp.IsZero() {
+		tag.Timestamp = b.s.now()
+	}
+	if item.Quality != (gopcxmlda.TQuality{}) {
+		tag.Quality = item.Quality
+	}
+	return ""
+}
+
+// sameType reports whether value has the type of tag. Tags without value accept any type.
+func sameType(tag *Tag, value gopcxmlda.TValue) bool {
+	tagType := tag.Type
+	if tagType == "" && tag.Value != nil {
+		tagType, _ = gopcxmlda.OpcXmlDaType(tag.Value)
+	}
+	valueType := value.Type
+	if valueType == "" {
+		valueType, _ = gopcxmlda.OpcXmlDaType(value.Value)
+	}
+	return tagType == "" || tagType == valueType
+}
+
+func (b *backend) Browse(ctx context.Context, request gopcxmlda.BrowseRequest) (gopcxmlda.TBrowseResponse, error) {
+	if err := injectedFault(ctx); err != nil {
+		return gopcxmlda.TBrowseResponse{}, err
+	}
+	b.s.mu.Lock()
+	names := append([]string(nil), b.s.names...)
+	b.s.mu.Unlock()
+	return server.BrowseNames(names, request)
+}
+
+func (b *backend) GetProperties(ctx context.Context, request gopcxmlda.GetPropertiesRequest) ([]gopcxmlda.TPropertyList, error) {
+	if err := injectedFault(ctx); err != nil {
+		return nil, err
+	}
+	if request.ItemPath != "" {
+		return nil, gopcxmlda.ErrUnknownItemPath
+	}
+	names := make(map[string]bool)
+	for _, name := range request.PropertyNames {
+		names[name[strings.LastIndex(name, ":")+1:]] = true
+	}
+	b.s.mu.Lock()
+	defer b.s.mu.Unlock()
+	lists := make([]gopcxmlda.TPropertyList, len(request.ItemIDs))
+	for i, id := range request.ItemIDs {
+		lists[i] = gopcxmlda.TPropertyList{ItemName: id.ItemName, ItemPath: id.ItemPath}
+		tag, ok := b.s.tags[id.ItemName]
+		if !ok || id.ItemPath != "" {
+			lists[i].ResultId = string(gopcxmlda.ErrUnknownItemName)
+			continue
+		}
+		for _, p := range b.properties(tag) {
+			if request.ReturnAllProperties || len(names) == 0 || names[p.Name] {
+				lists[i].Properties = append(lists[i].Properties, p)
+			}
+		}
+	}
+	return lists, nil
+}
+
+// properties returns the properties of a tag, the properties of the specification first. b.s.mu must be held.
+func (b *backend) properties(tag *Tag) []gopcxmlda.TProperties {
+	item := b.s.itemOf(tag)
+	dataType := tag.Type
+	if dataType == "" && item.Value.Value != nil {
+		dataType, _ = gopcxmlda.OpcXmlDaType(item.Value.Value)
+	}
+	accessRights := gopcxmlda.AccessReadWritable
+	if tag.ReadOnly || tag.Generator != nil {
+		accessRights = gopcxmlda.AccessReadable
+	}
+	properties := []gopcxmlda.TProperties{
+		{Name: gopcxmlda.PropertyDataType, Value: gopcxmlda.TValue{Type: "QName", Value: gopcxmlda.QualifiedType(dataType, "")}},
+		{Name: gopcxmlda.PropertyValue, Value: item.Value},
+		{Name: gopcxmlda.PropertyQuality, Value: gopcxmlda.TValue{Type: "OPCQuality", Value: item.Quality}},
+		{Name: gopcxmlda.PropertyTimestamp, Value: gopcxmlda.TValue{Value: item.Timestamp}},
+		{Name: gopcxmlda.PropertyAccessRights, Value: gopcxmlda.TValue{Value: accessRights.String()}},
+	}
+	names := make([]string, 0, len(tag.Properties))
+	for name := range tag.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		properties = append(properties, gopcxmlda.TProperties{Name: name, Value: gopcxmlda.TValue{Value: tag.Properties[name]}})
+	}
+	return properties
+}
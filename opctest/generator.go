@@ -0,0 +1,71 @@
+package opctest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Generator computes the value of a simulated tag. elapsed is the time since the start of the
+// Server, measured with its clock, so values are reproducible with WithClock.
+type Generator func(elapsed time.Duration) interface{}
+
+// Ramp returns a Generator rising linearly from min to max within period and starting over.
+// It panics if period is not positive.
+func Ramp(min float64, max float64, period time.Duration) Generator {
+	checkPositive("Ramp", "period", period)
+	return func(elapsed time.Duration) interface{} {
+		fraction := float64(elapsed%period) / float64(period)
+		return min + (max-min)*fraction
+	}
+}
+
+// Sine returns a Generator oscillating around offset with amplitude and period.
+// It panics if period is not positive.
+func Sine(offset float64, amplitude float64, period time.Duration) Generator {
+	checkPositive("Sine", "period", period)
+	return func(elapsed time.Duration) interface{} {
+		return offset + amplitude*math.Sin(2*math.Pi*float64(elapsed)/float64(period))
+	}
+}
+
+// RandomWalk returns a Generator starting at start, which changes by a random value within
+// [-step, step] every interval. The walk is deterministic for a seed and kept within min and max.
+// It panics if interval is not positive.
+func RandomWalk(start float64, step float64, min float64, max float64, interval time.Duration, seed int64) Generator {
+	checkPositive("RandomWalk", "interval", interval)
+	var mu sync.Mutex
+	random := rand.New(rand.NewSource(seed))
+	value := start
+	steps := int64(0)
+	return func(elapsed time.Duration) interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		for target := int64(elapsed / interval); steps < target; steps++ {
+			value = math.Max(min, math.Min(max, value+(random.Float64()*2-1)*step))
+		}
+		return value
+	}
+}
+
+// Step returns a Generator cycling through values, switching to the next value every interval.
+// It panics if interval is not positive or no values are given.
+func Step(interval time.Duration, values ...interface{}) Generator {
+	checkPositive("Step", "interval", interval)
+	if len(values) == 0 {
+		panic("opctest: Step without values")
+	}
+	return func(elapsed time.Duration) interface{} {
+		return values[int(elapsed/interval)%len(values)]
+	}
+}
+
+// checkPositive panics with a message naming the Generator if the duration d is not positive,
+// so a wrong argument fails when the Generator is created instead of when the tag is first read.
+func checkPositive(generator string, name string, d time.Duration) {
+	if d <= 0 {
+		panic(fmt.Sprintf("opctest: %s with non-positive %s %v", generator, name, d))
+	}
+}
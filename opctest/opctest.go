@@ -0,0 +1,320 @@
+// Package opctest provides an in-memory OPC XML-DA server for tests, similar to net/http/httptest.
+// The server holds a table of tags, which can be read, written, browsed and subscribed with the
+// gopcxmlda client. Tag values can be simulated by Generators, faults can be injected per operation.
+//
+// Example:
+//
+//	srv := opctest.NewServer([]opctest.Tag{
+//		{Name: "Plant1/Temperature", Value: 21.5, Properties: map[string]interface{}{"engineeringUnits": "°C"}},
+//		{Name: "Plant1/Power", Generator: opctest.Sine(500, 100, time.Minute)},
+//	})
+//	defer srv.Close()
+//	s := srv.Client()
+//	response, err := s.Read(ctx, []gopcxmlda.TItem{{ItemName: "Plant1/Power"}}, &handle, &itemHandles, "", gopcxmlda.TRequestOptions{})
+package opctest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+	"github.com/dernate/gopcxmlda/server"
+)
+
+// Tag is an item of the simulated address space.
+type Tag struct {
+	Name       string                 // ItemName of the tag, "/" separates the branches shown by Browse
+	Value      interface{}            // initial value
+	Type       string                 // type of the value, derived from Value if empty
+	Quality    gopcxmlda.TQuality     // quality of the value, good if empty
+	Timestamp  time.Time              // timestamp of the value, the start of the server if zero
+	ReadOnly   bool                   // writes fail with E_READONLY
+	Generator  Generator              // computes the value on every read, implies ReadOnly
+	Properties map[string]interface{} // further properties, e.g. "engineeringUnits", "description" or "highEU"
+}
+
+// Fault is a failure injected into the requests of an operation with Server.InjectFault.
+type Fault struct {
+	Operation  string        // operation the fault applies to, e.g. "Read", all operations if empty
+	Err        error         // answered as SOAP fault, e.g. gopcxmlda.ErrBusy
+	Delay      time.Duration // delay before the request is processed
+	StatusCode int           // answered with this HTTP status and an empty body
+	Times      int           // number of requests the fault applies to, unlimited if zero
+}
+
+// Option configures a Server created by NewServer.
+type Option func(*Server)
+
+// WithClock sets the clock used for timestamps and Generators, time.Now by default.
+// Subscriptions still wait in real time.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithStatus sets the status returned by GetStatus. StartTime is set to the start of the server if empty.
+func WithStatus(status gopcxmlda.TStatus) Option {
+	return func(s *Server) {
+		s.status = status
+	}
+}
+
+// WithEngineOptions sets the options of the server.SubscriptionEngine serving the subscriptions.
+func WithEngineOptions(options server.EngineOptions) Option {
+	return func(s *Server) {
+		s.engineOptions = options
+	}
+}
+
+// Server is an OPC XML-DA server listening on a system-chosen port on the local loopback interface.
+// It is safe for concurrent use, the tags can be changed while requests are served.
+type Server struct {
+	*httptest.Server
+
+	now           func() time.Time
+	start         time.Time
+	status        gopcxmlda.TStatus
+	engineOptions server.EngineOptions
+	engine        *server.SubscriptionEngine
+	handler       http.Handler
+
+	mu         sync.Mutex
+	tags       map[string]*Tag
+	names      []string // tag names in the order of creation, the order of Browse
+	state      string
+	faults     []*Fault
+	itemErrors map[string]gopcxmlda.ResultCode
+	requests   map[string]int
+}
+
+// NewServer starts and returns a new Server with tags. The caller should call Close when finished.
+func NewServer(tags []Tag, options ...Option) *Server {
+	s := &Server{
+		now:        time.Now,
+		tags:       make(map[string]*Tag),
+		state:      server.StateRunning,
+		itemErrors: make(map[string]gopcxmlda.ResultCode),
+		requests:   make(map[string]int),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	s.start = s.now()
+	if s.status.StartTime == "" {
+		s.status.StartTime = s.start.UTC().Format(time.RFC3339)
+	}
+	if s.status.ProductVersion == "" {
+		s.status.ProductVersion = "opctest"
+	}
+	for _, tag := range tags {
+		s.AddTag(tag)
+	}
+	s.engine = server.NewSubscriptionEngine(&backend{s}, s.engineOptions)
+	s.handler = server.NewHandler(subscriber{s.engine})
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close stops the subscriptions and shuts down the server.
+func (s *Server) Close() {
+	s.engine.Close()
+	s.Server.Close()
+}
+
+// Subscriptions returns the number of active subscriptions.
+func (s *Server) Subscriptions() int {
+	return s.engine.Subscriptions()
+}
+
+// Client returns a gopcxmlda.Server for the server with the locale ID "en-US".
+func (s *Server) Client(options ...gopcxmlda.ServerOption) *gopcxmlda.Server {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		panic(fmt.Sprintf("opctest: invalid server URL %q: %v", s.URL, err))
+	}
+	return gopcxmlda.NewServer(u, "en-US", options...)
+}
+
+// AddTag adds a tag or replaces the tag with the same name.
+func (s *Server) AddTag(tag Tag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tag.Timestamp.IsZero() {
+		tag.Timestamp = s.start
+	}
+	if tag.Quality == (gopcxmlda.TQuality{}) {
+		tag.Quality = gopcxmlda.TQuality{QualityField: "good"}
+	}
+	if _, ok := s.tags[tag.Name]; !ok {
+		s.names = append(s.names, tag.Name)
+	}
+	s.tags[tag.Name] = &tag
+}
+
+// Value returns the current value of a tag.
+func (s *Server) Value(name string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tag, ok := s.tags[name]
+	if !ok {
+		return nil, false
+	}
+	return s.valueOf(tag), true
+}
+
+// SetValue sets the value of a tag with the current time as timestamp, like a write of the process.
+// It returns false if the tag does not exist.
+func (s *Server) SetValue(name string, value interface{}) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tag, ok := s.tags[name]
+	if !ok {
+		return false
+	}
+	tag.Value = value
+	tag.Timestamp = s.now()
+	return true
+}
+
+// SetQuality sets the quality of a tag. It returns false if the tag does not exist.
+func (s *Server) SetQuality(name string, quality gopcxmlda.TQuality) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tag, ok := s.tags[name]
+	if !ok {
+		return false
+	}
+	tag.Quality = quality
+	return true
+}
+
+// SetState sets the ServerState returned with every response, e.g. server.StateSuspended.
+func (s *Server) SetState(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+}
+
+// SetItemError makes every Read, Write and Subscribe of the item fail with code.
+// An empty code removes the error.
+func (s *Server) SetItemError(name string, code gopcxmlda.ResultCode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if code == "" {
+		delete(s.itemErrors, name)
+	} else {
+		s.itemErrors[name] = code
+	}
+}
+
+// InjectFault adds a fault. Faults are applied in the order they were injected, only the
+// first fault matching a request is applied.
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// RequestCount returns the number of requests received for an operation, e.g. "Read".
+func (s *Server) RequestCount(operation string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[operation]
+}
+
+type faultKey struct{}
+
+// serveHTTP counts the request and applies the injected faults before the request is handled.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	action := strings.Trim(r.Header.Get("SOAPAction"), "\"")
+	operation := action[strings.LastIndex(action, "/")+1:]
+
+	s.mu.Lock()
+	s.requests[operation]++
+	fault := s.takeFault(operation)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if fault.Delay > 0 {
+			// with the body read, the request context is canceled when the client disconnects
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.StatusCode != 0 {
+			w.WriteHeader(fault.StatusCode)
+			return
+		}
+		if fault.Err != nil {
+			r = r.WithContext(context.WithValue(r.Context(), faultKey{}, fault.Err))
+		}
+	}
+	s.handler.ServeHTTP(w, r)
+}
+
+// takeFault returns the first fault for operation and counts its use. s.mu must be held.
+func (s *Server) takeFault(operation string) *Fault {
+	for i, fault := range s.faults {
+		if fault.Operation != "" && fault.Operation != operation {
+			continue
+		}
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return fault
+	}
+	return nil
+}
+
+// injectedFault returns the error of a fault applied to the request of ctx.
+func injectedFault(ctx context.Context) error {
+	err, _ := ctx.Value(faultKey{}).(error)
+	return err
+}
+
+// valueOf returns the current value of a tag. s.mu must be held.
+func (s *Server) valueOf(tag *Tag) interface{} {
+	if tag.Generator != nil {
+		return tag.Generator(s.now().Sub(s.start))
+	}
+	return tag.Value
+}
+
+// itemOf returns the current value, quality and timestamp of a tag as item. s.mu must be held.
+func (s *Server) itemOf(tag *Tag) gopcxmlda.TItem {
+	item := gopcxmlda.TItem{
+		Value:     gopcxmlda.TValue{Type: tag.Type, Value: s.valueOf(tag)},
+		Quality:   tag.Quality,
+		Timestamp: tag.Timestamp,
+	}
+	if tag.Generator != nil {
+		item.Timestamp = s.now()
+	}
+	return item
+}
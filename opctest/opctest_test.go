@@ -0,0 +1,195 @@
+package opctest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+	"github.com/dernate/gopcxmlda/server"
+)
+
+func TestGenerators(t *testing.T) {
+	ramp := Ramp(0, 100, 10*time.Second)
+	if v := ramp(2500 * time.Millisecond); v != 25.0 {
+		t.Errorf("Ramp: expected 25, got %v", v)
+	}
+	if v := ramp(12 * time.Second); v != 20.0 {
+		t.Errorf("Ramp: expected 20 after the period, got %v", v)
+	}
+
+	sine := Sine(10, 5, 4*time.Second)
+	if v := sine(time.Second).(float64); math.Abs(v-15) > 1e-9 {
+		t.Errorf("Sine: expected 15, got %v", v)
+	}
+
+	step := Step(time.Second, "off", "on")
+	if step(500*time.Millisecond) != "off" || step(1500*time.Millisecond) != "on" || step(2*time.Second) != "off" {
+		t.Error("Step: unexpected values")
+	}
+
+	walk1 := RandomWalk(50, 1, 0, 100, time.Second, 42)
+	walk2 := RandomWalk(50, 1, 0, 100, time.Second, 42)
+	if walk1(0) != 50.0 {
+		t.Errorf("RandomWalk: expected start value, got %v", walk1(0))
+	}
+	v1, v2 := walk1(10*time.Second).(float64), walk2(10*time.Second).(float64)
+	if v1 != v2 || v1 == 50 || math.Abs(v1-50) > 10 {
+		t.Errorf("RandomWalk: unexpected values %v and %v", v1, v2)
+	}
+
+	invalid := map[string]func(){
+		"Step without values": func() { Step(time.Second) },
+		"Step zero interval":  func() { Step(0, 1) },
+		"Ramp zero period":    func() { Ramp(0, 1, 0) },
+		"Sine zero period":    func() { Sine(0, 1, 0) },
+		"RandomWalk negative": func() { RandomWalk(0, 1, 0, 1, -time.Second, 1) },
+	}
+	for name, create := range invalid {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.HasPrefix(fmt.Sprint(r), "opctest: ") {
+					t.Errorf("%s: unexpected panic %v", name, r)
+				}
+			}()
+			create()
+		}()
+	}
+}
+
+func TestServerClock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv := NewServer([]Tag{{Name: "Ramp", Generator: Ramp(0, 10, 10*time.Second)}}, WithClock(func() time.Time { return now }))
+	defer srv.Close()
+
+	now = now.Add(3 * time.Second)
+	if v, _ := srv.Value("Ramp"); v != 3.0 {
+		t.Errorf("expected 3, got %v", v)
+	}
+
+	var handle string
+	var itemHandles []string
+	read, err := srv.Client().Read(context.Background(), []gopcxmlda.TItem{{ItemName: "Ramp"}}, &handle, &itemHandles, "",
+		gopcxmlda.TRequestOptions{ReturnItemTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := read.Response.ItemList.Items[0]; item.Value.Value != 3.0 || !item.Timestamp.Equal(now) {
+		t.Errorf("unexpected item: %+v", item)
+	}
+}
+
+func TestServerBrowsePaging(t *testing.T) {
+	srv := NewServer([]Tag{
+		{Name: "Plant1/A", Value: 1}, {Name: "Plant1/B", Value: 2}, {Name: "Plant1/C", Value: 3},
+		{Name: "Plant1/Sub/D", Value: 4}, {Name: "Plant2/E", Value: 5},
+	})
+	defer srv.Close()
+	s := srv.Client()
+	ctx := context.Background()
+
+	elements, err := s.BrowseAll(ctx, "", "", gopcxmlda.TBrowseOptions{ItemName: "Plant1", MaxElementsReturned: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 4 || elements[3].Name != "Sub" || !elements[3].HasChildren || elements[3].ItemName != "Plant1/Sub" {
+		t.Errorf("unexpected elements: %+v", elements)
+	}
+	if srv.RequestCount("Browse") != 2 {
+		t.Errorf("expected 2 Browse requests, got %d", srv.RequestCount("Browse"))
+	}
+
+	elements, err = s.BrowseAll(ctx, "", "", gopcxmlda.TBrowseOptions{ItemName: "Plant1", BrowseFilter: "item", ElementNameFilter: "[AB]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 2 {
+		t.Errorf("expected 2 filtered elements, got %+v", elements)
+	}
+
+	if _, err := s.BrowseAll(ctx, "", "", gopcxmlda.TBrowseOptions{ItemName: "Unknown"}); !errors.Is(err, gopcxmlda.ErrUnknownItemName) {
+		t.Errorf("expected ErrUnknownItemName, got %v", err)
+	}
+}
+
+func TestServerWrite(t *testing.T) {
+	srv := NewServer([]Tag{
+		{Name: "Setpoint", Value: 10.0},
+		{Name: "Limit", Value: 5.0, ReadOnly: true},
+	})
+	defer srv.Close()
+	s := srv.Client()
+
+	var handle string
+	var itemHandles []string
+	write, _ := s.Write(context.Background(), []gopcxmlda.TItem{
+		{ItemName: "Setpoint", Value: gopcxmlda.TValue{Value: 12.5}},
+		{ItemName: "Limit", Value: gopcxmlda.TValue{Value: 6.0}},
+		{ItemName: "Setpoint", Value: gopcxmlda.TValue{Value: "high"}},
+	}, &handle, &itemHandles, "", gopcxmlda.TRequestOptions{})
+	items := write.Response.ItemList.Items
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %+v", items)
+	}
+	if items[0].Error != "" || !errors.Is(items[1].Err(), gopcxmlda.ErrReadOnly) || !errors.Is(items[2].Err(), gopcxmlda.ErrBadType) {
+		t.Errorf("unexpected results: %+v", items)
+	}
+	if v, _ := srv.Value("Setpoint"); v != 12.5 {
+		t.Errorf("expected 12.5, got %v", v)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	srv := NewServer([]Tag{{Name: "Value", Value: 1}})
+	defer srv.Close()
+	s := srv.Client()
+	ctx := context.Background()
+	read := func() error {
+		var handle string
+		var itemHandles []string
+		_, err := s.Read(ctx, []gopcxmlda.TItem{{ItemName: "Value"}}, &handle, &itemHandles, "", gopcxmlda.TRequestOptions{})
+		return err
+	}
+
+	srv.InjectFault(Fault{Operation: "Read", Err: gopcxmlda.ErrBusy, Times: 1})
+	if err := read(); !errors.Is(err, gopcxmlda.ErrBusy) {
+		t.Errorf("expected ErrBusy, got %v", err)
+	}
+	if err := read(); err != nil {
+		t.Errorf("fault applied more than once: %v", err)
+	}
+
+	srv.InjectFault(Fault{StatusCode: 503})
+	if err := read(); err == nil {
+		t.Error("expected error for status 503")
+	}
+	srv.ClearFaults()
+
+	srv.InjectFault(Fault{Operation: "Read", Delay: time.Second})
+	s = srv.Client(gopcxmlda.WithTimeout(50 * time.Millisecond))
+	if err := read(); err == nil {
+		t.Error("expected timeout")
+	}
+	srv.ClearFaults()
+	s = srv.Client()
+
+	srv.SetItemError("Value", gopcxmlda.ErrAccessDenied)
+	if err := read(); !errors.Is(err, gopcxmlda.ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied, got %v", err)
+	}
+	srv.SetItemError("Value", "")
+
+	srv.SetState(server.StateSuspended)
+	var handle string
+	status, err := s.GetStatus(ctx, &handle, "")
+	if err != nil || status.Response.Result.ServerState != server.StateSuspended {
+		t.Errorf("unexpected status %+v: %v", status.Response.Result, err)
+	}
+	if srv.RequestCount("Read") != 5 {
+		t.Errorf("expected 5 Read requests, got %d", srv.RequestCount("Read"))
+	}
+}
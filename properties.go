@@ -0,0 +1,239 @@
+package gopcxmlda
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Names of the properties of OPC DA defined by the specification, for TPropertyOptions.PropertyNames.
+const (
+	PropertyDataType         = "dataType"
+	PropertyValue            = "value"
+	PropertyQuality          = "quality"
+	PropertyTimestamp        = "timestamp"
+	PropertyAccessRights     = "accessRights"
+	PropertyScanRate         = "scanRate"
+	PropertyEUType           = "euType"
+	PropertyEUInfo           = "euInfo"
+	PropertyEngineeringUnits = "engineeringUnits"
+	PropertyDescription      = "description"
+	PropertyHighEU           = "highEU"
+	PropertyLowEU            = "lowEU"
+	PropertyHighIR           = "highIR"
+	PropertyLowIR            = "lowIR"
+	PropertyCloseLabel       = "closeLabel"
+	PropertyOpenLabel        = "openLabel"
+	PropertyTimeZone         = "timeZone"
+	PropertyMinimumValue     = "minimumValue"
+	PropertyMaximumValue     = "maximumValue"
+	PropertyValuePrecision   = "valuePrecision"
+)
+
+// AccessRights is the value of the accessRights property. The values are the bits of classic OPC DA.
+type AccessRights uint8
+
+// Access rights defined by the OPC XML-DA specification.
+const (
+	AccessUnknown      AccessRights = 0
+	AccessReadable     AccessRights = 1
+	AccessWritable     AccessRights = 2
+	AccessReadWritable AccessRights = 3
+)
+
+var accessRightsNames = [...]string{"unknown", "readable", "writable", "readWritable"}
+
+// ParseAccessRights returns the access rights of an accessRights property value like "readWritable".
+func ParseAccessRights(s string) (AccessRights, error) {
+	for rights, name := range accessRightsNames {
+		if name == s {
+			return AccessRights(rights), nil
+		}
+	}
+	return AccessUnknown, fmt.Errorf("unknown accessRights %q", s)
+}
+
+// String returns the value of the accessRights property.
+func (a AccessRights) String() string {
+	if int(a) < len(accessRightsNames) {
+		return accessRightsNames[a]
+	}
+	return fmt.Sprintf("AccessRights(%d)", uint8(a))
+}
+
+// CanRead reports whether the item is readable.
+func (a AccessRights) CanRead() bool {
+	return a&AccessReadable != 0
+}
+
+// CanWrite reports whether the item is writable.
+func (a AccessRights) CanWrite() bool {
+	return a&AccessWritable != 0
+}
+
+// EUType is the value of the euType property. The values are those of classic OPC DA.
+type EUType uint8
+
+// EU types defined by the OPC XML-DA specification.
+const (
+	EUTypeNoEnum     EUType = 0 // no engineering units information
+	EUTypeAnalog     EUType = 1 // euInfo contains the lowEU and highEU
+	EUTypeEnumerated EUType = 2 // euInfo contains the texts of the values 0, 1, ...
+)
+
+var euTypeNames = [...]string{"noEnum", "analog", "enumerated"}
+
+// ParseEUType returns the EU type of an euType property value like "analog".
+func ParseEUType(s string) (EUType, error) {
+	for euType, name := range euTypeNames {
+		if name == s {
+			return EUType(euType), nil
+		}
+	}
+	return EUTypeNoEnum, fmt.Errorf("unknown euType %q", s)
+}
+
+// String returns the value of the euType property.
+func (t EUType) String() string {
+	if int(t) < len(euTypeNames) {
+		return euTypeNames[t]
+	}
+	return fmt.Sprintf("EUType(%d)", uint8(t))
+}
+
+// ItemProperties are the properties of an item returned by GetProperties. Properties not returned
+// by the server are left zero, the numeric properties are nil then. Further properties, like
+// vendor specific ones, are kept in Other by their name.
+type ItemProperties struct {
+	ItemName         string
+	ItemPath         string
+	Error            string    // ResultID of the item if its properties could not be returned
+	DataType         string    // QName of the type, e.g. "xsd:double"
+	Value            TValue    // current value
+	Quality          TQuality  // quality of the current value
+	Timestamp        time.Time // timestamp of the current value
+	AccessRights     AccessRights
+	ScanRate         *float64 // fastest rate in milliseconds at which the server can obtain the value
+	EUType           EUType
+	EUInfo           []string // texts of an enumerated item
+	EngineeringUnits string
+	Description      string
+	HighEU           *float64
+	LowEU            *float64
+	HighIR           *float64 // highest value of the instrument range
+	LowIR            *float64 // lowest value of the instrument range
+	CloseLabel       string   // text of true of a boolean item
+	OpenLabel        string   // text of false of a boolean item
+	TimeZone         *int     // offset to UTC in minutes of the timestamp of the device
+	MinimumValue     TValue
+	MaximumValue     TValue
+	ValuePrecision   *float64 // number of decimal places or the precision of floats
+	Other            map[string]TValue
+}
+
+// NewItemProperties converts the properties of a GetProperties response. The names of the properties
+// may have a prefix, properties without value are skipped. Values of the wrong type are reported as
+// joined error, the other properties are converted nevertheless.
+func NewItemProperties(list TPropertyList) (ItemProperties, error) {
+	p := ItemProperties{ItemName: list.ItemName, ItemPath: list.ItemPath, Error: list.ResultId}
+	var errs []error
+	setString := func(target *string, value TValue) error {
+		s, err := value.AsString()
+		*target = s
+		return err
+	}
+	setFloat := func(target **float64, value TValue) error {
+		f, err := value.AsFloat64()
+		if err == nil {
+			*target = &f
+		}
+		return err
+	}
+	for _, property := range list.Properties {
+		value := property.Value
+		if value.Value == nil {
+			// no values requested
+			continue
+		}
+		name := property.Name[strings.LastIndex(property.Name, ":")+1:]
+		var err error
+		switch name {
+		case PropertyDataType:
+			err = setString(&p.DataType, value)
+		case PropertyValue:
+			p.Value = value
+		case PropertyQuality:
+			var ok bool
+			if p.Quality, ok = value.Value.(TQuality); !ok {
+				err = fmt.Errorf("%w: %T", ErrBadType, value.Value)
+			}
+		case PropertyTimestamp:
+			p.Timestamp, err = value.AsTime()
+		case PropertyAccessRights:
+			var s string
+			if s, err = value.AsString(); err == nil {
+				p.AccessRights, err = ParseAccessRights(s)
+			}
+		case PropertyScanRate:
+			err = setFloat(&p.ScanRate, value)
+		case PropertyEUType:
+			var s string
+			if s, err = value.AsString(); err == nil {
+				p.EUType, err = ParseEUType(s)
+			}
+		case PropertyEUInfo:
+			p.EUInfo, err = AsSlice[string](value)
+		case PropertyEngineeringUnits:
+			err = setString(&p.EngineeringUnits, value)
+		case PropertyDescription:
+			err = setString(&p.Description, value)
+		case PropertyHighEU:
+			err = setFloat(&p.HighEU, value)
+		case PropertyLowEU:
+			err = setFloat(&p.LowEU, value)
+		case PropertyHighIR:
+			err = setFloat(&p.HighIR, value)
+		case PropertyLowIR:
+			err = setFloat(&p.LowIR, value)
+		case PropertyCloseLabel:
+			err = setString(&p.CloseLabel, value)
+		case PropertyOpenLabel:
+			err = setString(&p.OpenLabel, value)
+		case PropertyTimeZone:
+			var minutes int
+			if minutes, err = ValueAs[int](value); err == nil {
+				p.TimeZone = &minutes
+			}
+		case PropertyMinimumValue:
+			p.MinimumValue = value
+		case PropertyMaximumValue:
+			p.MaximumValue = value
+		case PropertyValuePrecision:
+			err = setFloat(&p.ValuePrecision, value)
+		default:
+			if p.Other == nil {
+				p.Other = make(map[string]TValue)
+			}
+			p.Other[property.Name] = value
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", list.ItemName, property.Name, err))
+		}
+	}
+	return p, errors.Join(errs...)
+}
+
+// ItemProperties returns the properties of all items of the response, see NewItemProperties.
+// Items whose properties could not be returned have their Error set, see ItemErrors.
+func (p TGetProperties) ItemProperties() ([]ItemProperties, error) {
+	properties := make([]ItemProperties, len(p.Response.PropertyList))
+	var errs []error
+	for i, list := range p.Response.PropertyList {
+		var err error
+		if properties[i], err = NewItemProperties(list); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return properties, errors.Join(errs...)
+}
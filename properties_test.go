@@ -0,0 +1,85 @@
+package gopcxmlda
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewItemProperties(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	list := TPropertyList{ItemName: "Plant1/Valve", Properties: []TProperties{
+		{Name: "dataType", Value: TValue{Type: "QName", Value: "xsd:boolean"}},
+		{Name: "ns1:value", Value: TValue{Value: true}},
+		{Name: "quality", Value: TValue{Value: TQuality{QualityField: "good"}}},
+		{Name: "timestamp", Value: TValue{Value: timestamp}},
+		{Name: "accessRights", Value: TValue{Value: "readWritable"}},
+		{Name: "scanRate", Value: TValue{Value: float32(250)}},
+		{Name: "euType", Value: TValue{Value: "enumerated"}},
+		{Name: "euInfo", Value: TValue{Value: []interface{}{"closed", "open"}}},
+		{Name: "description", Value: TValue{Value: "inlet valve"}},
+		{Name: "highIR", Value: TValue{Value: 1}},
+		{Name: "closeLabel", Value: TValue{Value: "open"}},
+		{Name: "openLabel", Value: TValue{Value: "closed"}},
+		{Name: "timeZone", Value: TValue{Value: -60}},
+		{Name: "valuePrecision", Value: TValue{Value: 0.0}},
+		{Name: "vendor:serial", Value: TValue{Value: "4711"}},
+		{Name: "lowIR"},
+	}}
+	p, err := NewItemProperties(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanRate, highIR, timeZone, precision := 250.0, 1.0, -60, 0.0
+	want := ItemProperties{
+		ItemName:       "Plant1/Valve",
+		DataType:       "xsd:boolean",
+		Value:          TValue{Value: true},
+		Quality:        TQuality{QualityField: "good"},
+		Timestamp:      timestamp,
+		AccessRights:   AccessReadWritable,
+		ScanRate:       &scanRate,
+		EUType:         EUTypeEnumerated,
+		EUInfo:         []string{"closed", "open"},
+		Description:    "inlet valve",
+		HighIR:         &highIR,
+		CloseLabel:     "open",
+		OpenLabel:      "closed",
+		TimeZone:       &timeZone,
+		ValuePrecision: &precision,
+		Other:          map[string]TValue{"vendor:serial": {Value: "4711"}},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v\nwant %+v", p, want)
+	}
+	if !p.AccessRights.CanRead() || !p.AccessRights.CanWrite() || AccessReadable.CanWrite() {
+		t.Errorf("unexpected access rights")
+	}
+
+	list.Properties = []TProperties{
+		{Name: "accessRights", Value: TValue{Value: "all"}},
+		{Name: "highEU", Value: TValue{Value: "high"}},
+		{Name: "lowEU", Value: TValue{Value: 0}},
+	}
+	p, err = NewItemProperties(list)
+	if !errors.Is(err, ErrBadType) || p.LowEU == nil || *p.LowEU != 0 || p.HighEU != nil {
+		t.Errorf("got %+v, %v", p, err)
+	}
+}
+
+func TestPropertyEnums(t *testing.T) {
+	for _, name := range accessRightsNames {
+		if rights, err := ParseAccessRights(name); err != nil || rights.String() != name {
+			t.Errorf("%s: got %v, %v", name, rights, err)
+		}
+	}
+	for _, name := range euTypeNames {
+		if euType, err := ParseEUType(name); err != nil || euType.String() != name {
+			t.Errorf("%s: got %v, %v", name, euType, err)
+		}
+	}
+	if _, err := ParseEUType("Analog"); err == nil {
+		t.Error("no error for Analog")
+	}
+}
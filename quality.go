@@ -0,0 +1,229 @@
+package gopcxmlda
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// QualityStatus is the QualityField of a quality, the qualityBits enumeration of the specification.
+// The values are the quality and substatus bits of the classic 16-bit OPC DA quality word.
+type QualityStatus uint16
+
+// Quality statuses defined by the OPC XML-DA specification.
+const (
+	QualityBad                        QualityStatus = 0x00
+	QualityBadConfigurationError      QualityStatus = 0x04
+	QualityBadNotConnected            QualityStatus = 0x08
+	QualityBadDeviceFailure           QualityStatus = 0x0C
+	QualityBadSensorFailure           QualityStatus = 0x10
+	QualityBadLastKnownValue          QualityStatus = 0x14
+	QualityBadCommFailure             QualityStatus = 0x18
+	QualityBadOutOfService            QualityStatus = 0x1C
+	QualityBadWaitingForInitialData   QualityStatus = 0x20
+	QualityUncertain                  QualityStatus = 0x40
+	QualityUncertainLastUsableValue   QualityStatus = 0x44
+	QualityUncertainSensorNotAccurate QualityStatus = 0x50
+	QualityUncertainEUExceeded        QualityStatus = 0x54
+	QualityUncertainSubNormal         QualityStatus = 0x58
+	QualityGood                       QualityStatus = 0xC0
+	QualityGoodLocalOverride          QualityStatus = 0xD8
+)
+
+var qualityStatusNames = map[QualityStatus]string{
+	QualityBad:                        "bad",
+	QualityBadConfigurationError:      "badConfigurationError",
+	QualityBadNotConnected:            "badNotConnected",
+	QualityBadDeviceFailure:           "badDeviceFailure",
+	QualityBadSensorFailure:           "badSensorFailure",
+	QualityBadLastKnownValue:          "badLastKnownValue",
+	QualityBadCommFailure:             "badCommFailure",
+	QualityBadOutOfService:            "badOutOfService",
+	QualityBadWaitingForInitialData:   "badWaitingForInitialData",
+	QualityUncertain:                  "uncertain",
+	QualityUncertainLastUsableValue:   "uncertainLastUsableValue",
+	QualityUncertainSensorNotAccurate: "uncertainSensorNotAccurate",
+	QualityUncertainEUExceeded:        "uncertainEUExceeded",
+	QualityUncertainSubNormal:         "uncertainSubNormal",
+	QualityGood:                       "good",
+	QualityGoodLocalOverride:          "goodLocalOverride",
+}
+
+// ParseQualityStatus returns the status of a QualityField, e.g. QualityBadCommFailure for "badCommFailure".
+// An empty QualityField is good, the default of the specification.
+func ParseQualityStatus(s string) (QualityStatus, error) {
+	if s == "" {
+		return QualityGood, nil
+	}
+	for status, name := range qualityStatusNames {
+		if name == s {
+			return status, nil
+		}
+	}
+	return QualityBad, fmt.Errorf("unknown QualityField %q", s)
+}
+
+// String returns the QualityField of the status.
+func (s QualityStatus) String() string {
+	if name, ok := qualityStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("QualityStatus(%#02x)", uint16(s))
+}
+
+// class returns the quality bits of the status, QualityGood, QualityUncertain or QualityBad.
+// The quality bits 10 are not used by OPC DA and are treated as bad.
+func (s QualityStatus) class() QualityStatus {
+	switch s & 0xC0 {
+	case QualityGood, QualityUncertain:
+		return s & 0xC0
+	default:
+		return QualityBad
+	}
+}
+
+// IsGood reports whether the status is one of the good statuses.
+func (s QualityStatus) IsGood() bool {
+	return s.class() == QualityGood
+}
+
+// IsUncertain reports whether the status is one of the uncertain statuses.
+func (s QualityStatus) IsUncertain() bool {
+	return s.class() == QualityUncertain
+}
+
+// IsBad reports whether the status is one of the bad statuses.
+func (s QualityStatus) IsBad() bool {
+	return s.class() == QualityBad
+}
+
+// QualityLimit is the LimitField of a quality, the limitBits enumeration of the specification.
+type QualityLimit uint16
+
+// Limits defined by the OPC XML-DA specification.
+const (
+	LimitNone     QualityLimit = 0
+	LimitLow      QualityLimit = 1
+	LimitHigh     QualityLimit = 2
+	LimitConstant QualityLimit = 3
+)
+
+var qualityLimitNames = [...]string{"none", "low", "high", "constant"}
+
+// ParseQualityLimit returns the limit of a LimitField, an empty LimitField is LimitNone.
+func ParseQualityLimit(s string) (QualityLimit, error) {
+	if s == "" {
+		return LimitNone, nil
+	}
+	for limit, name := range qualityLimitNames {
+		if name == s {
+			return QualityLimit(limit), nil
+		}
+	}
+	return LimitNone, fmt.Errorf("unknown LimitField %q", s)
+}
+
+// String returns the LimitField of the limit.
+func (l QualityLimit) String() string {
+	if int(l) < len(qualityLimitNames) {
+		return qualityLimitNames[l]
+	}
+	return fmt.Sprintf("QualityLimit(%d)", uint16(l))
+}
+
+// NewQuality returns the quality with the given status and limit.
+func NewQuality(status QualityStatus, limit QualityLimit) TQuality {
+	return TQuality{QualityField: status.String(), LimitField: limit.String()}
+}
+
+// QualityFromWord returns the quality of a classic 16-bit OPC DA quality word: the status in
+// bits 2 to 7, the limit in bits 0 and 1 and the vendor specific bits in the high byte.
+// Unknown substatus bits are reduced to the status bad, uncertain or good.
+func QualityFromWord(word uint16) TQuality {
+	status := QualityStatus(word & 0xFC)
+	if _, ok := qualityStatusNames[status]; !ok {
+		status = status.class()
+	}
+	q := NewQuality(status, QualityLimit(word&0x03))
+	if vendor := word >> 8; vendor != 0 {
+		q.VendorField = strconv.Itoa(int(vendor))
+	}
+	return q
+}
+
+// Status returns the QualityField of the quality. Unknown values are reduced to QualityGood,
+// QualityUncertain or QualityBad by their prefix, other values are bad.
+func (q TQuality) Status() QualityStatus {
+	status, err := ParseQualityStatus(q.QualityField)
+	if err != nil {
+		switch {
+		case strings.HasPrefix(q.QualityField, "good"):
+			return QualityGood
+		case strings.HasPrefix(q.QualityField, "uncertain"):
+			return QualityUncertain
+		}
+	}
+	return status
+}
+
+// Limit returns the LimitField of the quality, LimitNone if it is unknown.
+func (q TQuality) Limit() QualityLimit {
+	limit, _ := ParseQualityLimit(q.LimitField)
+	return limit
+}
+
+// Word returns the quality as classic 16-bit OPC DA quality word, see QualityFromWord.
+// A VendorField which is no number from 0 to 255 is ignored.
+func (q TQuality) Word() uint16 {
+	vendor, _ := strconv.ParseUint(q.VendorField, 10, 8)
+	return uint16(vendor)<<8 | uint16(q.Status()) | uint16(q.Limit())
+}
+
+// IsGood reports whether the quality is good.
+func (q TQuality) IsGood() bool {
+	return q.Status().IsGood()
+}
+
+// IsUncertain reports whether the quality is uncertain.
+func (q TQuality) IsUncertain() bool {
+	return q.Status().IsUncertain()
+}
+
+// IsBad reports whether the quality is bad.
+func (q TQuality) IsBad() bool {
+	return q.Status().IsBad()
+}
+
+// IsGood reports whether the item has a value of good quality. Items with a failure ResultID are
+// not good, success codes like S_CLAMP are ignored.
+func (i TItem) IsGood() bool {
+	return !i.failed() && i.Quality.IsGood()
+}
+
+// IsUncertain reports whether the item has a value of uncertain quality.
+func (i TItem) IsUncertain() bool {
+	return !i.failed() && i.Quality.IsUncertain()
+}
+
+// IsBad reports whether the item has a failure ResultID or a value of bad quality.
+func (i TItem) IsBad() bool {
+	return i.failed() || i.Quality.IsBad()
+}
+
+// failed reports whether the ResultID of the item is a failure code, see ResultCode.IsSuccess.
+func (i TItem) failed() bool {
+	return i.Error != "" && !ParseResultCode(i.Error).IsSuccess()
+}
+
+// FilterItems returns the items for which keep returns true, e.g. the items with good quality:
+//
+//	good := FilterItems(R.Response.ItemList.Items, TItem.IsGood)
+func FilterItems(items []TItem, keep func(TItem) bool) []TItem {
+	var kept []TItem
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
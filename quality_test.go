@@ -0,0 +1,89 @@
+package gopcxmlda
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQualityStatus(t *testing.T) {
+	for status, name := range qualityStatusNames {
+		if parsed, err := ParseQualityStatus(name); err != nil || parsed != status || status.String() != name {
+			t.Errorf("%s: parsed as %v, %v", name, parsed, err)
+		}
+		q := NewQuality(status, LimitHigh)
+		if got := QualityFromWord(q.Word()); got != q {
+			t.Errorf("%s: word %#04x decoded as %+v", name, q.Word(), got)
+		}
+	}
+	tests := []struct {
+		quality              TQuality
+		status               QualityStatus
+		good, uncertain, bad bool
+	}{
+		{TQuality{}, QualityGood, true, false, false},
+		{TQuality{QualityField: "goodLocalOverride"}, QualityGoodLocalOverride, true, false, false},
+		{TQuality{QualityField: "uncertainEUExceeded"}, QualityUncertainEUExceeded, false, true, false},
+		{TQuality{QualityField: "badCommFailure"}, QualityBadCommFailure, false, false, true},
+		{TQuality{QualityField: "uncertainVendorSpecific"}, QualityUncertain, false, true, false},
+		{TQuality{QualityField: "unknown"}, QualityBad, false, false, true},
+	}
+	for _, test := range tests {
+		q := test.quality
+		if q.Status() != test.status || q.IsGood() != test.good || q.IsUncertain() != test.uncertain || q.IsBad() != test.bad {
+			t.Errorf("%+v: status %v, good %v, uncertain %v, bad %v", q, q.Status(), q.IsGood(), q.IsUncertain(), q.IsBad())
+		}
+	}
+	if _, err := ParseQualityStatus("Good"); err == nil {
+		t.Error("no error for Good")
+	}
+}
+
+func TestQualityWord(t *testing.T) {
+	tests := []struct {
+		word    uint16
+		quality TQuality
+	}{
+		{0x00C0, TQuality{QualityField: "good", LimitField: "none"}},
+		{0x0056, TQuality{QualityField: "uncertainEUExceeded", LimitField: "high"}},
+		{0x2A19, TQuality{QualityField: "badCommFailure", LimitField: "low", VendorField: "42"}},
+		{0x0048, TQuality{QualityField: "uncertain", LimitField: "none"}},
+		{0x0083, TQuality{QualityField: "bad", LimitField: "constant"}},
+	}
+	for _, test := range tests {
+		if got := QualityFromWord(test.word); got != test.quality {
+			t.Errorf("%#04x: got %+v, want %+v", test.word, got, test.quality)
+		}
+	}
+	if word := (TQuality{QualityField: "goodLocalOverride", LimitField: "low", VendorField: "x"}).Word(); word != 0xD9 {
+		t.Errorf("got %#04x", word)
+	}
+	if limit, err := ParseQualityLimit("constant"); err != nil || limit != LimitConstant || limit.String() != "constant" {
+		t.Errorf("got %v, %v", limit, err)
+	}
+}
+
+func TestFilterItems(t *testing.T) {
+	items := []TItem{
+		{ItemName: "a", Quality: TQuality{QualityField: "good"}},
+		{ItemName: "b", Quality: TQuality{QualityField: "uncertain"}},
+		{ItemName: "c", Quality: TQuality{QualityField: "badSensorFailure"}},
+		{ItemName: "d", Error: string(ErrUnknownItemName)},
+		{ItemName: "e", Error: "opc:" + string(ResultClamp), Quality: TQuality{QualityField: "good", LimitField: "high"}},
+		{ItemName: "f", Error: string(ResultUnsupportedRate), Quality: TQuality{QualityField: "uncertainSubNormal"}},
+	}
+	names := func(items []TItem) (names []string) {
+		for _, item := range items {
+			names = append(names, item.ItemName)
+		}
+		return names
+	}
+	if got := names(FilterItems(items, TItem.IsGood)); !reflect.DeepEqual(got, []string{"a", "e"}) {
+		t.Errorf("good: %v", got)
+	}
+	if got := names(FilterItems(items, TItem.IsUncertain)); !reflect.DeepEqual(got, []string{"b", "f"}) {
+		t.Errorf("uncertain: %v", got)
+	}
+	if got := names(FilterItems(items, TItem.IsBad)); !reflect.DeepEqual(got, []string{"c", "d"}) {
+		t.Errorf("bad: %v", got)
+	}
+}
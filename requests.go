@@ -0,0 +1,145 @@
+package gopcxmlda
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// OpcNamespace is the XML namespace of the OPC XML-DA messages.
+const OpcNamespace = "http://opcfoundation.org/webservices/XMLDA/1.0/"
+
+// The request types below are the bodies of the OPC XML-DA requests, as sent by the methods of Server.
+// They are used to decode the requests on the server side, see the server package.
+
+// GetStatusRequest is the body of a GetStatus request.
+type GetStatusRequest struct {
+	XMLName             xml.Name `xml:"GetStatus"`
+	LocaleID            string   `xml:"LocaleID,attr,omitempty"`
+	ClientRequestHandle string   `xml:"ClientRequestHandle,attr,omitempty"`
+}
+
+// ReadRequest is the body of a Read request.
+type ReadRequest struct {
+	XMLName  xml.Name            `xml:"Read"`
+	Options  TRequestOptions     `xml:"Options"`
+	ItemList ReadRequestItemList `xml:"ItemList"`
+}
+
+type ReadRequestItemList struct {
+	ItemPath string            `xml:"ItemPath,attr,omitempty"`
+	ReqType  string            `xml:"ReqType,attr,omitempty"`
+	MaxAge   int               `xml:"MaxAge,attr,omitempty"`
+	Items    []ReadRequestItem `xml:"Items"`
+}
+
+type ReadRequestItem struct {
+	ItemPath         string `xml:"ItemPath,attr,omitempty"`
+	ReqType          string `xml:"ReqType,attr,omitempty"`
+	ItemName         string `xml:"ItemName,attr,omitempty"`
+	ClientItemHandle string `xml:"ClientItemHandle,attr,omitempty"`
+	MaxAge           int    `xml:"MaxAge,attr,omitempty"`
+}
+
+// WriteRequest is the body of a Write request.
+type WriteRequest struct {
+	XMLName             xml.Name             `xml:"Write"`
+	ReturnValuesOnReply bool                 `xml:"ReturnValuesOnReply,attr"`
+	Options             TRequestOptions      `xml:"Options"`
+	ItemList            WriteRequestItemList `xml:"ItemList"`
+}
+
+type WriteRequestItemList struct {
+	ItemPath string             `xml:"ItemPath,attr,omitempty"`
+	Items    []WriteRequestItem `xml:"Items"`
+}
+
+type WriteRequestItem struct {
+	ItemPath         string     `xml:"ItemPath,attr,omitempty"`
+	ItemName         string     `xml:"ItemName,attr,omitempty"`
+	ClientItemHandle string     `xml:"ClientItemHandle,attr,omitempty"`
+	Timestamp        *time.Time `xml:"Timestamp,attr,omitempty"`
+	Value            TValue     `xml:"Value"`
+	Quality          *TQuality  `xml:"Quality,omitempty"`
+}
+
+// SubscribeRequest is the body of a Subscribe request.
+type SubscribeRequest struct {
+	XMLName              xml.Name                 `xml:"Subscribe"`
+	ReturnValuesOnReply  bool                     `xml:"ReturnValuesOnReply,attr"`
+	SubscriptionPingRate uint                     `xml:"SubscriptionPingRate,attr,omitempty"`
+	Options              TRequestOptions          `xml:"Options"`
+	ItemList             SubscribeRequestItemList `xml:"ItemList"`
+}
+
+type SubscribeRequestItemList struct {
+	ItemPath              string                 `xml:"ItemPath,attr,omitempty"`
+	ReqType               string                 `xml:"ReqType,attr,omitempty"`
+	Deadband              float64                `xml:"Deadband,attr,omitempty"`
+	RequestedSamplingRate uint                   `xml:"RequestedSamplingRate,attr,omitempty"`
+	EnableBuffering       bool                   `xml:"EnableBuffering,attr,omitempty"`
+	Items                 []SubscribeRequestItem `xml:"Items"`
+}
+
+type SubscribeRequestItem struct {
+	ItemPath              string  `xml:"ItemPath,attr,omitempty"`
+	ReqType               string  `xml:"ReqType,attr,omitempty"`
+	ItemName              string  `xml:"ItemName,attr,omitempty"`
+	ClientItemHandle      string  `xml:"ClientItemHandle,attr,omitempty"`
+	Deadband              float64 `xml:"Deadband,attr"`
+	RequestedSamplingRate uint    `xml:"RequestedSamplingRate,attr"`
+	EnableBuffering       bool    `xml:"EnableBuffering,attr"`
+}
+
+// SubscriptionPolledRefreshRequest is the body of a SubscriptionPolledRefresh request.
+type SubscriptionPolledRefreshRequest struct {
+	XMLName          xml.Name        `xml:"SubscriptionPolledRefresh"`
+	HoldTime         *time.Time      `xml:"HoldTime,attr,omitempty"` // optional, the server does not hold the response if nil
+	WaitTime         uint            `xml:"WaitTime,attr"`
+	ReturnAllItems   bool            `xml:"ReturnAllItems,attr"`
+	Options          TRequestOptions `xml:"Options"`
+	ServerSubHandles []string        `xml:"ServerSubHandles"`
+}
+
+// SubscriptionCancelRequest is the body of a SubscriptionCancel request.
+type SubscriptionCancelRequest struct {
+	XMLName             xml.Name `xml:"SubscriptionCancel"`
+	ServerSubHandle     string   `xml:"ServerSubHandle,attr,omitempty"`
+	ClientRequestHandle string   `xml:"ClientRequestHandle,attr,omitempty"`
+}
+
+// BrowseRequest is the body of a Browse request.
+type BrowseRequest struct {
+	XMLName              xml.Name `xml:"Browse"`
+	LocaleID             string   `xml:"LocaleID,attr,omitempty"`
+	ClientRequestHandle  string   `xml:"ClientRequestHandle,attr,omitempty"`
+	ItemPath             string   `xml:"ItemPath,attr,omitempty"`
+	ItemName             string   `xml:"ItemName,attr,omitempty"`
+	ContinuationPoint    string   `xml:"ContinuationPoint,attr,omitempty"`
+	MaxElementsReturned  int      `xml:"MaxElementsReturned,attr"`
+	BrowseFilter         string   `xml:"BrowseFilter,attr,omitempty"`
+	ElementNameFilter    string   `xml:"ElementNameFilter,attr,omitempty"`
+	VendorFilter         string   `xml:"VendorFilter,attr,omitempty"`
+	ReturnAllProperties  bool     `xml:"ReturnAllProperties,attr"`
+	ReturnPropertyValues bool     `xml:"ReturnPropertyValues,attr"`
+	ReturnErrorText      bool     `xml:"ReturnErrorText,attr"`
+	PropertyNames        []string `xml:"PropertyNames"`
+}
+
+// GetPropertiesRequest is the body of a GetProperties request.
+type GetPropertiesRequest struct {
+	XMLName              xml.Name         `xml:"GetProperties"`
+	LocaleID             string           `xml:"LocaleID,attr,omitempty"`
+	ClientRequestHandle  string           `xml:"ClientRequestHandle,attr,omitempty"`
+	ItemPath             string           `xml:"ItemPath,attr,omitempty"`
+	ReturnAllProperties  bool             `xml:"ReturnAllProperties,attr"`
+	ReturnPropertyValues bool             `xml:"ReturnPropertyValues,attr"`
+	ReturnErrorText      bool             `xml:"ReturnErrorText,attr"`
+	ItemIDs              []ItemIdentifier `xml:"ItemIDs"`
+	PropertyNames        []string         `xml:"PropertyNames"`
+}
+
+// ItemIdentifier identifies an item of a GetProperties request.
+type ItemIdentifier struct {
+	ItemPath string `xml:"ItemPath,attr,omitempty"`
+	ItemName string `xml:"ItemName,attr,omitempty"`
+}
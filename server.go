@@ -0,0 +1,112 @@
+package gopcxmlda
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultTimeout is used for requests of a Server without a Timeout.
+const DefaultTimeout = 10 * time.Second
+
+// defaultTransport is shared by all Servers without their own HttpClient,
+// so keep-alive connections are reused across requests and Servers.
+var defaultTransport http.RoundTripper = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   32,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// ServerOption configures a Server created by NewServer.
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	timeout         time.Duration
+	httpClient      *http.Client
+	transport       http.RoundTripper
+	validator       *writeValidator
+	authenticator   Authenticator
+	headerProviders []HeaderProvider
+}
+
+// WithTimeout sets the timeout of every request sent to the server.
+func WithTimeout(timeout time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithHttpClient sets the HTTP client used for all requests. The client is used as is,
+// its own Timeout and Transport apply.
+func WithHttpClient(client *http.Client) ServerOption {
+	return func(c *serverConfig) {
+		c.httpClient = client
+	}
+}
+
+// WithTransport sets the RoundTripper used for all requests. It is ignored if
+// WithHttpClient is given as well.
+func WithTransport(transport http.RoundTripper) ServerOption {
+	return func(c *serverConfig) {
+		c.transport = transport
+	}
+}
+
+// NewServer creates a Server for the given URL and locale ID.
+// Without options the Server uses DefaultTimeout and a shared, pooled HTTP transport.
+//
+// Example:
+//
+//	_url, _ := url.Parse("http://opc-addr-or-IP.local:8080")
+//	s := NewServer(_url, "en-US", WithTimeout(5*time.Second))
+//	// s can now be used from multiple goroutines
+func NewServer(u *url.URL, localeID string, options ...ServerOption) *Server {
+	c := serverConfig{timeout: DefaultTimeout}
+	for _, option := range options {
+		option(&c)
+	}
+	s := &Server{
+		Url:             u,
+		LocaleID:        localeID,
+		Timeout:         c.timeout,
+		HttpClient:      c.httpClient,
+		validator:       c.validator,
+		Authenticator:   c.authenticator,
+		HeaderProviders: c.headerProviders,
+	}
+	if s.HttpClient == nil && c.transport != nil {
+		s.HttpClient = &http.Client{
+			Transport: c.transport,
+			Timeout:   c.timeout,
+		}
+	}
+	return s
+}
+
+// httpClient returns the HTTP client to use for a request. It never modifies the Server.
+func (s *Server) httpClient() *http.Client {
+	if s.HttpClient != nil {
+		return s.HttpClient
+	}
+	return &http.Client{
+		Transport: defaultTransport,
+		Timeout:   s.timeout(),
+	}
+}
+
+// timeout returns the Timeout of the Server or DefaultTimeout.
+func (s *Server) timeout() time.Duration {
+	if s.Timeout == 0 {
+		return DefaultTimeout
+	}
+	return s.Timeout
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// ServerState values of the OPC XML-DA specification.
+const (
+	StateRunning   = "running"
+	StateFailed    = "failed"
+	StateNoConfig  = "noConfig"
+	StateSuspended = "suspended"
+	StateTest      = "test"
+	StateCommFault = "commFault"
+)
+
+// Backend provides the data served by a Handler. Its methods are called concurrently.
+//
+// Item results are returned as gopcxmlda.TItem: Value, Quality and Timestamp for values,
+// Error for the ResultID of an item which failed, e.g. string(gopcxmlda.ErrUnknownItemName).
+// An error returned by a method fails the whole request and is sent as SOAP fault; if it wraps
+// a gopcxmlda.ResultCode, the code is used as fault code.
+type Backend interface {
+	// Status returns the status of the server and its ServerState, e.g. StateRunning.
+	// The ServerState is sent with the result of every request.
+	Status(ctx context.Context) (gopcxmlda.TStatus, string, error)
+
+	// Read returns the values of items. ItemName, ItemPath and ClientItemHandle of the
+	// items are set from the request, the item path of the item list is already applied.
+	Read(ctx context.Context, options gopcxmlda.TRequestOptions, items []gopcxmlda.TItem) ([]gopcxmlda.TItem, error)
+
+	// Write writes the values of items and returns their results. With ReturnValuesOnReply,
+	// the Handler reads the successfully written items afterwards.
+	Write(ctx context.Context, options gopcxmlda.TRequestOptions, items []gopcxmlda.TItem) ([]gopcxmlda.TItem, error)
+
+	// Browse returns the elements below request.ItemPath and request.ItemName.
+	Browse(ctx context.Context, request gopcxmlda.BrowseRequest) (gopcxmlda.TBrowseResponse, error)
+
+	// GetProperties returns a property list for every item of request.ItemIDs.
+	GetProperties(ctx context.Context, request gopcxmlda.GetPropertiesRequest) ([]gopcxmlda.TPropertyList, error)
+}
+
+// Subscriber is implemented by a Backend which supports subscriptions. The Handler answers
+// Subscribe, SubscriptionPolledRefresh and SubscriptionCancel with an E_NOTSUPPORTED fault
+// if its Backend is no Subscriber.
+type Subscriber interface {
+	// Subscribe creates a subscription. With request.ReturnValuesOnReply, the current values
+	// are returned in the ItemValue of the items.
+	Subscribe(ctx context.Context, request gopcxmlda.SubscribeRequest) (SubscribeResult, error)
+
+	// SubscriptionPolledRefresh returns the changed values of the subscriptions of request.ServerSubHandles.
+	SubscriptionPolledRefresh(ctx context.Context, request gopcxmlda.SubscriptionPolledRefreshRequest) (RefreshResult, error)
+
+	// SubscriptionCancel cancels a subscription. Unknown handles should return gopcxmlda.ErrNoSubscription.
+	SubscriptionCancel(ctx context.Context, serverSubHandle string) error
+}
+
+// SubscribeResult is the result of Subscriber.Subscribe.
+type SubscribeResult struct {
+	ServerSubHandle     string
+	RevisedSamplingRate int
+	Items               []gopcxmlda.TSubscribeItemValue
+}
+
+// RefreshResult is the result of Subscriber.SubscriptionPolledRefresh.
+type RefreshResult struct {
+	InvalidServerSubHandles []string
+	ItemLists               []gopcxmlda.TItemListSPR // changed items per valid ServerSubHandle
+	DataBufferOverflow      bool
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// BrowseNames answers a Browse request for an address space given as list of item names, in which
+// "/" separates the branches. The elements are returned in the order of names, a branch at the
+// position of its first item. BrowseFilter, ElementNameFilter (a path.Match pattern),
+// MaxElementsReturned and ContinuationPoint are applied. Item paths are not supported.
+func BrowseNames(names []string, request gopcxmlda.BrowseRequest) (gopcxmlda.TBrowseResponse, error) {
+	if request.ItemPath != "" {
+		return gopcxmlda.TBrowseResponse{}, gopcxmlda.ErrUnknownItemPath
+	}
+	elements, known := children(names, request.ItemName)
+	if !known {
+		return gopcxmlda.TBrowseResponse{}, gopcxmlda.ErrUnknownItemName
+	}
+
+	var filtered []gopcxmlda.TBrowseElement
+	for _, element := range elements {
+		switch request.BrowseFilter {
+		case "branch":
+			if !element.HasChildren {
+				continue
+			}
+		case "item":
+			if !element.IsItem {
+				continue
+			}
+		}
+		if request.ElementNameFilter != "" {
+			match, err := path.Match(request.ElementNameFilter, element.Name)
+			if err != nil {
+				return gopcxmlda.TBrowseResponse{}, gopcxmlda.ErrInvalidFilter
+			}
+			if !match {
+				continue
+			}
+		}
+		filtered = append(filtered, element)
+	}
+
+	// the ContinuationPoint is the index of the next element
+	start := 0
+	if request.ContinuationPoint != "" {
+		var err error
+		start, err = strconv.Atoi(request.ContinuationPoint)
+		if err != nil || start < 0 || start > len(filtered) {
+			return gopcxmlda.TBrowseResponse{}, gopcxmlda.ErrInvalidContinuationPoint
+		}
+	}
+	response := gopcxmlda.TBrowseResponse{MoreElements: "false", Elements: filtered[start:]}
+	if request.MaxElementsReturned > 0 && len(response.Elements) > request.MaxElementsReturned {
+		response.Elements = response.Elements[:request.MaxElementsReturned]
+		response.MoreElements = "true"
+		response.ContinuationPoint = strconv.Itoa(start + request.MaxElementsReturned)
+	}
+	return response, nil
+}
+
+// children returns the elements below the branch or item name. known is false if name is
+// neither an item nor a branch.
+func children(names []string, name string) (elements []gopcxmlda.TBrowseElement, known bool) {
+	prefix := ""
+	if name != "" {
+		prefix = name + "/"
+	}
+	items := make(map[string]bool, len(names))
+	for _, itemName := range names {
+		items[itemName] = true
+	}
+	known = name == "" || items[name]
+	branches := make(map[string]bool)
+	for _, itemName := range names {
+		if !strings.HasPrefix(itemName, prefix) {
+			continue
+		}
+		known = true
+		rest := itemName[len(prefix):]
+		if i := strings.Index(rest, "/"); i >= 0 {
+			branch := prefix + rest[:i]
+			if !branches[branch] {
+				branches[branch] = true
+				elements = append(elements, gopcxmlda.TBrowseElement{
+					Name: rest[:i], ItemName: branch, IsItem: items[branch], HasChildren: true,
+				})
+			}
+			continue
+		}
+		if !branches[itemName] {
+			elements = append(elements, gopcxmlda.TBrowseElement{Name: rest, ItemName: itemName, IsItem: true})
+		}
+	}
+	return elements, known
+}
@@ -0,0 +1,477 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// EngineOptions configures a SubscriptionEngine.
+type EngineOptions struct {
+	MinSamplingRate time.Duration // fastest sampling rate, faster requested rates are revised, 100 ms if zero
+	BufferSize      int           // maximum number of buffered values per item with EnableBuffering, 100 if zero
+}
+
+// SubscriptionEngine implements the subscriptions for a Backend, which only needs to support Read.
+// It embeds the Backend, so it can be passed to NewHandler in its place:
+//
+//	engine := server.NewSubscriptionEngine(myBackend, server.EngineOptions{})
+//	defer engine.Close()
+//	handler := server.NewHandler(engine)
+//
+// Every subscribed item is sampled with Read at its RequestedSamplingRate, revised to
+// EngineOptions.MinSamplingRate. A sampled value is queued if it differs from the last queued value,
+// for items with a percent Deadband only if the change exceeds the Deadband of the EU range given by
+// the highEU and lowEU properties. Without EnableBuffering only the latest value is kept, with
+// EnableBuffering up to BufferSize values, dropping the oldest and reporting DataBufferOverflow.
+//
+// SubscriptionPolledRefresh does not return before HoldTime, then waits up to WaitTime for changes.
+// A subscription expires if no SubscriptionPolledRefresh is received within its SubscriptionPingRate.
+type SubscriptionEngine struct {
+	Backend
+	options EngineOptions
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu            sync.Mutex
+	subscriptions map[string]*engineSubscription
+	changed       chan struct{} // closed and replaced when values are queued
+	nextHandle    uint64
+}
+
+type engineSubscription struct {
+	handle   string
+	options  gopcxmlda.TRequestOptions
+	pingRate time.Duration
+	tick     time.Duration
+	items    []*engineItem
+	cancel   context.CancelFunc
+	lastPoll time.Time
+	polls    int // number of running refreshes
+}
+
+type engineItem struct {
+	item      gopcxmlda.TItem // ItemName, ItemPath and ClientItemHandle
+	rate      time.Duration
+	next      time.Time
+	deadband  float64 // absolute deadband in engineering units, 0 for every change
+	buffering bool
+	last      gopcxmlda.TItem // last queued value
+	queue     []gopcxmlda.TItem
+	overflow  bool
+}
+
+// NewSubscriptionEngine returns a SubscriptionEngine for backend. Close stops all subscriptions.
+func NewSubscriptionEngine(backend Backend, options EngineOptions) *SubscriptionEngine {
+	if options.MinSamplingRate <= 0 {
+		options.MinSamplingRate = 100 * time.Millisecond
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = 100
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SubscriptionEngine{
+		Backend:       backend,
+		options:       options,
+		ctx:           ctx,
+		cancel:        cancel,
+		subscriptions: make(map[string]*engineSubscription),
+		changed:       make(chan struct{}),
+	}
+}
+
+// Close stops the sampling of all subscriptions and waits for it to finish.
+func (e *SubscriptionEngine) Close() {
+	e.mu.Lock()
+	e.cancel()
+	e.mu.Unlock()
+	e.wg.Wait()
+}
+
+// Subscriptions returns the number of active subscriptions.
+func (e *SubscriptionEngine) Subscriptions() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.subscriptions)
+}
+
+// Subscribe reads the items once and starts sampling them. With ReturnValuesOnReply the values are
+// returned, otherwise they are returned by the first SubscriptionPolledRefresh. Items which do not
+// exist are returned with their ResultID and not subscribed.
+func (e *SubscriptionEngine) Subscribe(ctx context.Context, request gopcxmlda.SubscribeRequest) (SubscribeResult, error) {
+	if e.ctx.Err() != nil {
+		return SubscribeResult{}, fmt.Errorf("subscription engine closed: %w", gopcxmlda.ErrServerState)
+	}
+	requested := request.ItemList.Items
+	items := make([]gopcxmlda.TItem, len(requested))
+	for i, item := range requested {
+		items[i] = gopcxmlda.TItem{ItemName: item.ItemName, ItemPath: item.ItemPath, ClientItemHandle: item.ClientItemHandle}
+	}
+	values, err := e.Backend.Read(ctx, request.Options, append([]gopcxmlda.TItem(nil), items...))
+	if err != nil {
+		return SubscribeResult{}, err
+	}
+	if len(values) != len(items) {
+		return SubscribeResult{}, fmt.Errorf("backend returned %d values for %d items", len(values), len(items))
+	}
+	ranges, err := e.euRanges(ctx, requested)
+	if err != nil {
+		return SubscribeResult{}, err
+	}
+
+	now := time.Now()
+	sub := &engineSubscription{
+		options:  request.Options,
+		pingRate: time.Duration(request.SubscriptionPingRate) * time.Millisecond,
+		lastPoll: now,
+	}
+	var result SubscribeResult
+	for i, r := range requested {
+		value := values[i]
+		value.ClientItemHandle = r.ClientItemHandle
+		requestedRate := time.Duration(r.RequestedSamplingRate) * time.Millisecond
+		rate := max(requestedRate, e.options.MinSamplingRate)
+		itemValue := gopcxmlda.TSubscribeItemValue{}
+		if requestedRate != 0 && rate != requestedRate {
+			itemValue.RevisedSamplingRate = int(rate / time.Millisecond)
+		}
+		switch gopcxmlda.ParseResultCode(value.Error) {
+		case gopcxmlda.ErrUnknownItemName, gopcxmlda.ErrInvalidItemName, gopcxmlda.ErrUnknownItemPath, gopcxmlda.ErrInvalidItemPath:
+			itemValue.ItemValue = value
+			result.Items = append(result.Items, itemValue)
+			continue
+		}
+
+		item := &engineItem{
+			item:      items[i],
+			rate:      rate,
+			next:      now.Add(rate),
+			buffering: r.EnableBuffering,
+			last:      value,
+		}
+		if euRange, ok := ranges[i]; ok && r.Deadband > 0 {
+			item.deadband = r.Deadband / 100 * euRange
+		}
+		if !request.ReturnValuesOnReply {
+			item.queue = []gopcxmlda.TItem{value}
+			value = gopcxmlda.TItem{ClientItemHandle: value.ClientItemHandle, Error: value.Error}
+		}
+		if itemValue.RevisedSamplingRate != 0 && value.Error == "" {
+			value.Error = string(gopcxmlda.ResultUnsupportedRate)
+		}
+		itemValue.ItemValue = value
+		result.Items = append(result.Items, itemValue)
+		sub.items = append(sub.items, item)
+	}
+	sub.tick = e.options.MinSamplingRate
+	for i, item := range sub.items {
+		if i == 0 || item.rate < sub.tick {
+			sub.tick = item.rate
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ctx.Err() != nil {
+		return SubscribeResult{}, fmt.Errorf("subscription engine closed: %w", gopcxmlda.ErrServerState)
+	}
+	e.nextHandle++
+	sub.handle = "sub-" + strconv.FormatUint(e.nextHandle, 10)
+	subCtx, cancel := context.WithCancel(e.ctx)
+	sub.cancel = cancel
+	e.subscriptions[sub.handle] = sub
+	e.wg.Add(1)
+	go e.run(subCtx, sub)
+	result.ServerSubHandle = sub.handle
+	return result, nil
+}
+
+// euRanges returns highEU - lowEU for the items with a Deadband, by index.
+func (e *SubscriptionEngine) euRanges(ctx context.Context, items []gopcxmlda.SubscribeRequestItem) (map[int]float64, error) {
+	request := gopcxmlda.GetPropertiesRequest{
+		PropertyNames:        []string{gopcxmlda.PropertyHighEU, gopcxmlda.PropertyLowEU},
+		ReturnPropertyValues: true,
+	}
+	var indices []int
+	for i, item := range items {
+		if item.Deadband > 0 {
+			indices = append(indices, i)
+			request.ItemIDs = append(request.ItemIDs, gopcxmlda.ItemIdentifier{ItemName: item.ItemName, ItemPath: item.ItemPath})
+		}
+	}
+	ranges := make(map[int]float64)
+	if len(indices) == 0 {
+		return ranges, nil
+	}
+	lists, err := e.Backend.GetProperties(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	for i, list := range lists {
+		if i >= len(indices) || list.ResultId != "" {
+			continue
+		}
+		var high, low float64
+		var hasHigh, hasLow bool
+		for _, p := range list.Properties {
+			switch p.Name[strings.LastIndex(p.Name, ":")+1:] {
+			case gopcxmlda.PropertyHighEU:
+				high, hasHigh = toFloat(p.Value.Value)
+			case gopcxmlda.PropertyLowEU:
+				low, hasLow = toFloat(p.Value.Value)
+			}
+		}
+		if hasHigh && hasLow && high > low {
+			ranges[indices[i]] = high - low
+		}
+	}
+	return ranges, nil
+}
+
+// run samples the items of a subscription until it is canceled or expires.
+func (e *SubscriptionEngine) run(ctx context.Context, sub *engineSubscription) {
+	defer e.wg.Done()
+	ticker := time.NewTicker(sub.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if e.expire(sub, now) {
+				return
+			}
+			e.sample(ctx, sub, now)
+		}
+	}
+}
+
+// expire removes the subscription if it was not refreshed within its ping rate.
+func (e *SubscriptionEngine) expire(sub *engineSubscription, now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if sub.pingRate <= 0 || sub.polls > 0 || now.Sub(sub.lastPoll) <= sub.pingRate {
+		return false
+	}
+	if e.subscriptions[sub.handle] == sub {
+		delete(e.subscriptions, sub.handle)
+	}
+	sub.cancel()
+	return true
+}
+
+// sample reads the items which are due and queues the changed values.
+func (e *SubscriptionEngine) sample(ctx context.Context, sub *engineSubscription, now time.Time) {
+	e.mu.Lock()
+	var due []*engineItem
+	var items []gopcxmlda.TItem
+	for _, item := range sub.items {
+		if item.next.After(now) {
+			continue
+		}
+		item.next = item.next.Add(item.rate)
+		if item.next.Before(now) {
+			item.next = now.Add(item.rate)
+		}
+		due = append(due, item)
+		items = append(items, item.item)
+	}
+	e.mu.Unlock()
+	if len(items) == 0 {
+		return
+	}
+
+	values, err := e.Backend.Read(ctx, sub.options, items)
+	if err != nil || len(values) != len(due) {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	changed := false
+	for i, item := range due {
+		values[i].ClientItemHandle = item.item.ClientItemHandle
+		if e.queue(item, values[i]) {
+			changed = true
+		}
+	}
+	if changed {
+		close(e.changed)
+		e.changed = make(chan struct{})
+	}
+}
+
+// queue queues value if it differs from the last queued value. e.mu must be held.
+func (e *SubscriptionEngine) queue(item *engineItem, value gopcxmlda.TItem) bool {
+	if !item.changed(value) {
+		return false
+	}
+	item.last = value
+	if !item.buffering {
+		item.queue = append(item.queue[:0], value)
+		return true
+	}
+	item.queue = append(item.queue, value)
+	if len(item.queue) > e.options.BufferSize {
+		item.queue = item.queue[len(item.queue)-e.options.BufferSize:]
+		item.overflow = true
+	}
+	return true
+}
+
+// changed reports whether value differs from the last queued value, considering the deadband.
+func (item *engineItem) changed(value gopcxmlda.TItem) bool {
+	if value.Error != item.last.Error || value.Quality != item.last.Quality {
+		return true
+	}
+	if item.deadband > 0 {
+		v, ok1 := toFloat(value.Value.Value)
+		last, ok2 := toFloat(item.last.Value.Value)
+		if ok1 && ok2 {
+			return math.Abs(v-last) > item.deadband
+		}
+	}
+	return !reflect.DeepEqual(value.Value.Value, item.last.Value.Value)
+}
+
+// SubscriptionPolledRefresh returns the queued values of the subscriptions. It waits until HoldTime
+// and then up to WaitTime milliseconds for changes, unless ReturnAllItems is set, which returns the
+// latest value of every item at HoldTime.
+func (e *SubscriptionEngine) SubscriptionPolledRefresh(ctx context.Context, request gopcxmlda.SubscriptionPolledRefreshRequest) (RefreshResult, error) {
+	e.mu.Lock()
+	var subs []*engineSubscription
+	for _, handle := range request.ServerSubHandles {
+		if sub, ok := e.subscriptions[handle]; ok {
+			sub.polls++
+			sub.lastPoll = time.Now()
+			subs = append(subs, sub)
+		}
+	}
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		for _, sub := range subs {
+			sub.polls--
+			sub.lastPoll = time.Now()
+		}
+		e.mu.Unlock()
+	}()
+
+	if len(subs) > 0 {
+		if err := e.wait(ctx, request, subs); err != nil {
+			return RefreshResult{}, err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var result RefreshResult
+	for _, handle := range request.ServerSubHandles {
+		sub, ok := e.subscriptions[handle]
+		if !ok {
+			result.InvalidServerSubHandles = append(result.InvalidServerSubHandles, handle)
+			continue
+		}
+		list := gopcxmlda.TItemListSPR{SubscriptionHandle: handle}
+		for _, item := range sub.items {
+			values := item.queue
+			if request.ReturnAllItems && len(values) == 0 {
+				values = []gopcxmlda.TItem{item.last}
+			}
+			if item.overflow && len(values) > 0 {
+				values[0].Error = string(gopcxmlda.ResultDataQueueOverflow)
+				result.DataBufferOverflow = true
+				item.overflow = false
+			}
+			list.Items = append(list.Items, values...)
+			item.queue = nil
+		}
+		result.ItemLists = append(result.ItemLists, list)
+	}
+	return result, nil
+}
+
+// wait waits until HoldTime and then until a value of subs is queued or WaitTime elapsed.
+func (e *SubscriptionEngine) wait(ctx context.Context, request gopcxmlda.SubscriptionPolledRefreshRequest, subs []*engineSubscription) error {
+	var hold time.Duration
+	if request.HoldTime != nil {
+		hold = time.Until(*request.HoldTime)
+	}
+	if hold > 0 {
+		timer := time.NewTimer(hold)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.ctx.Done():
+			return nil
+		}
+	}
+	if request.ReturnAllItems {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(request.WaitTime) * time.Millisecond)
+	defer timer.Stop()
+	for {
+		e.mu.Lock()
+		queued := false
+		for _, sub := range subs {
+			for _, item := range sub.items {
+				queued = queued || len(item.queue) > 0
+			}
+		}
+		changed := e.changed
+		e.mu.Unlock()
+		if queued {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// SubscriptionCancel stops the sampling of a subscription.
+func (e *SubscriptionEngine) SubscriptionCancel(_ context.Context, serverSubHandle string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	sub, ok := e.subscriptions[serverSubHandle]
+	if !ok {
+		return gopcxmlda.ErrNoSubscription
+	}
+	delete(e.subscriptions, serverSubHandle)
+	sub.cancel()
+	return nil
+}
+
+// toFloat converts a numeric value to float64.
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
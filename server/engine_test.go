@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// euBackend adds an EU range of 0 to 100 to the items of testBackend.
+type euBackend struct {
+	*testBackend
+}
+
+func (b euBackend) GetProperties(_ context.Context, request gopcxmlda.GetPropertiesRequest) ([]gopcxmlda.TPropertyList, error) {
+	var lists []gopcxmlda.TPropertyList
+	for _, id := range request.ItemIDs {
+		lists = append(lists, gopcxmlda.TPropertyList{ItemName: id.ItemName, Properties: []gopcxmlda.TProperties{
+			{Name: "highEU", Value: gopcxmlda.TValue{Value: 100.0}},
+			{Name: "lowEU", Value: gopcxmlda.TValue{Value: 0.0}},
+		}})
+	}
+	return lists, nil
+}
+
+func (b *testBackend) set(name string, value interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[name] = value
+}
+
+func newTestEngine(t *testing.T, backend Backend) *SubscriptionEngine {
+	t.Helper()
+	engine := NewSubscriptionEngine(backend, EngineOptions{MinSamplingRate: 10 * time.Millisecond, BufferSize: 3})
+	t.Cleanup(engine.Close)
+	return engine
+}
+
+// refresh polls a subscription and returns its values.
+func refresh(t *testing.T, engine *SubscriptionEngine, handle string, waitTime uint) []gopcxmlda.TItem {
+	t.Helper()
+	result, err := engine.SubscriptionPolledRefresh(context.Background(), gopcxmlda.SubscriptionPolledRefreshRequest{
+		WaitTime:         waitTime,
+		ServerSubHandles: []string{handle},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.ItemLists) != 1 {
+		t.Fatalf("unexpected refresh result: %+v", result)
+	}
+	return result.ItemLists[0].Items
+}
+
+func TestEngineSubscribe(t *testing.T) {
+	backend := newTestBackend()
+	engine := newTestEngine(t, backend)
+	ctx := context.Background()
+
+	result, err := engine.Subscribe(ctx, gopcxmlda.SubscribeRequest{
+		ItemList: gopcxmlda.SubscribeRequestItemList{Items: []gopcxmlda.SubscribeRequestItem{
+			{ItemName: "Temperature", ClientItemHandle: "h0", RequestedSamplingRate: 5},
+			{ItemName: "Unknown", ClientItemHandle: "h1"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Items) != 2 || result.Items[0].RevisedSamplingRate != 10 ||
+		result.Items[0].ItemValue.Error != string(gopcxmlda.ResultUnsupportedRate) ||
+		result.Items[1].ItemValue.Error != string(gopcxmlda.ErrUnknownItemName) {
+		t.Errorf("unexpected subscribe result: %+v", result.Items)
+	}
+
+	// without ReturnValuesOnReply the first refresh returns the initial value
+	if items := refresh(t, engine, result.ServerSubHandle, 0); len(items) != 1 || items[0].Value.Value != 21.5 || items[0].ClientItemHandle != "h0" {
+		t.Errorf("unexpected initial values: %+v", items)
+	}
+	start := time.Now()
+	if items := refresh(t, engine, result.ServerSubHandle, 50); len(items) != 0 || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("expected no values after WaitTime, got %+v", items)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		backend.set("Temperature", 23.0)
+	}()
+	if items := refresh(t, engine, result.ServerSubHandle, 5000); len(items) != 1 || items[0].Value.Value != 23.0 {
+		t.Errorf("unexpected changed values: %+v", items)
+	}
+
+	if err := engine.SubscriptionCancel(ctx, result.ServerSubHandle); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.SubscriptionCancel(ctx, result.ServerSubHandle); !errors.Is(err, gopcxmlda.ErrNoSubscription) {
+		t.Errorf("expected ErrNoSubscription, got %v", err)
+	}
+	if engine.Subscriptions() != 0 {
+		t.Errorf("expected no subscriptions, got %d", engine.Subscriptions())
+	}
+}
+
+func TestEngineDeadband(t *testing.T) {
+	backend := newTestBackend()
+	engine := newTestEngine(t, euBackend{backend})
+
+	result, err := engine.Subscribe(context.Background(), gopcxmlda.SubscribeRequest{
+		ReturnValuesOnReply: true,
+		ItemList: gopcxmlda.SubscribeRequestItemList{Items: []gopcxmlda.SubscribeRequestItem{
+			{ItemName: "Temperature", Deadband: 10},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.set("Temperature", 25.0)
+	if items := refresh(t, engine, result.ServerSubHandle, 100); len(items) != 0 {
+		t.Errorf("change within the deadband reported: %+v", items)
+	}
+	backend.set("Temperature", 35.0)
+	if items := refresh(t, engine, result.ServerSubHandle, 5000); len(items) != 1 || items[0].Value.Value != 35.0 {
+		t.Errorf("unexpected values: %+v", items)
+	}
+}
+
+func TestEngineBuffering(t *testing.T) {
+	backend := newTestBackend()
+	engine := newTestEngine(t, backend)
+
+	result, err := engine.Subscribe(context.Background(), gopcxmlda.SubscribeRequest{
+		ReturnValuesOnReply: true,
+		ItemList: gopcxmlda.SubscribeRequestItemList{Items: []gopcxmlda.SubscribeRequestItem{
+			{ItemName: "Temperature", EnableBuffering: true},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		backend.set("Temperature", float64(i))
+		time.Sleep(40 * time.Millisecond)
+	}
+	response, err := engine.SubscriptionPolledRefresh(context.Background(), gopcxmlda.SubscriptionPolledRefreshRequest{
+		ServerSubHandles: []string{result.ServerSubHandle},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := response.ItemLists[0].Items
+	if !response.DataBufferOverflow || len(items) != 3 || items[0].Error != string(gopcxmlda.ResultDataQueueOverflow) || items[2].Value.Value != 5.0 {
+		t.Errorf("unexpected buffered values %+v, overflow %v", items, response.DataBufferOverflow)
+	}
+
+	response, err = engine.SubscriptionPolledRefresh(context.Background(), gopcxmlda.SubscriptionPolledRefreshRequest{
+		ReturnAllItems:   true,
+		ServerSubHandles: []string{result.ServerSubHandle},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := response.ItemLists[0].Items; response.DataBufferOverflow || len(items) != 1 || items[0].Value.Value != 5.0 {
+		t.Errorf("ReturnAllItems: unexpected values %+v", items)
+	}
+}
+
+func TestEngineExpire(t *testing.T) {
+	engine := newTestEngine(t, newTestBackend())
+
+	result, err := engine.Subscribe(context.Background(), gopcxmlda.SubscribeRequest{
+		SubscriptionPingRate: 50,
+		ItemList: gopcxmlda.SubscribeRequestItemList{Items: []gopcxmlda.SubscribeRequestItem{
+			{ItemName: "Temperature"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for engine.Subscriptions() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	response, err := engine.SubscriptionPolledRefresh(context.Background(), gopcxmlda.SubscriptionPolledRefreshRequest{
+		ServerSubHandles: []string{result.ServerSubHandle},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.InvalidServerSubHandles) != 1 || len(response.ItemLists) != 0 {
+		t.Errorf("subscription did not expire: %+v", response)
+	}
+}
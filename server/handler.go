@@ -0,0 +1,475 @@
+// Package server implements the server side of OPC XML-DA. A Handler decodes the SOAP requests
+// and dispatches them to a Backend, which provides the data:
+//
+//	handler := server.NewHandler(myBackend)
+//	log.Fatal(http.ListenAndServe(":8080", handler))
+//
+// The requests are decoded into the request types of the gopcxmlda package, values are encoded
+// with gopcxmlda.TValue, so everything a Backend returns can be read by the gopcxmlda client.
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// envelopeOpen declares the prefixes used by the responses: SOAP-ENV, xsi, xsd and ns0 for OPC XML-DA.
+const envelopeOpen = gopcxmlda.XmlVersion + gopcxmlda.EnvelopeOpen1 + "ns0" + gopcxmlda.EnvelopeOpen2 + "<SOAP-ENV:Body>"
+
+// DefaultMaxRequestBytes is the limit of the request body of a Handler without MaxRequestBytes.
+const DefaultMaxRequestBytes = 4 << 20
+
+// Handler is an http.Handler serving OPC XML-DA requests with the data of a Backend.
+type Handler struct {
+	// MaxRequestBytes limits the size of a request body, larger requests are answered with a
+	// SOAP-ENV:Client fault. DefaultMaxRequestBytes is used if it is zero, no limit if it is negative.
+	MaxRequestBytes int64
+
+	backend Backend
+}
+
+// NewHandler returns a Handler for backend. If backend implements Subscriber, subscriptions are supported.
+func NewHandler(backend Backend) *Handler {
+	return &Handler{backend: backend}
+}
+
+// requestError is a request which could not be decoded, it is answered with a SOAP-ENV:Client fault.
+type requestError struct {
+	err error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// ServeHTTP decodes the operation from the SOAP body, calls the Backend and writes the response.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcvTime := time.Now()
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := cmp.Or(h.MaxRequestBytes, DefaultMaxRequestBytes)
+	if limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	decoder := xml.NewDecoder(r.Body)
+	operation, err := findOperation(decoder)
+	if err != nil {
+		writeFault(w, tooLarge(&requestError{err}))
+		return
+	}
+	response, err := h.dispatch(r.Context(), rcvTime, decoder, operation)
+	if err != nil {
+		writeFault(w, tooLarge(err))
+		return
+	}
+	body, err := xml.Marshal(response)
+	if err != nil {
+		writeFault(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	_, _ = w.Write([]byte(envelopeOpen))
+	_, _ = w.Write(body)
+	_, _ = w.Write([]byte(gopcxmlda.Footer))
+}
+
+// tooLarge returns a requestError if reading the request body failed because of MaxRequestBytes, otherwise err.
+func tooLarge(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &requestError{fmt.Errorf("request body larger than %d bytes", maxBytesErr.Limit)}
+	}
+	return err
+}
+
+// findOperation returns the first element within the SOAP body.
+func findOperation(decoder *xml.Decoder) (xml.StartElement, error) {
+	inBody := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, fmt.Errorf("no operation in SOAP body: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if inBody {
+				return start, nil
+			}
+			inBody = start.Name.Local == "Body"
+		}
+	}
+}
+
+func (h *Handler) dispatch(ctx context.Context, rcvTime time.Time, decoder *xml.Decoder, operation xml.StartElement) (interface{}, error) {
+	decode := func(request interface{}) error {
+		if err := decoder.DecodeElement(request, &operation); err != nil {
+			return &requestError{fmt.Errorf("%s: %w", operation.Name.Local, err)}
+		}
+		return nil
+	}
+	switch operation.Name.Local {
+	case "GetStatus":
+		var request gopcxmlda.GetStatusRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		return h.getStatus(ctx, rcvTime, request)
+	case "Read":
+		var request gopcxmlda.ReadRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		return h.read(ctx, rcvTime, request)
+	case "Write":
+		var request gopcxmlda.WriteRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		return h.write(ctx, rcvTime, request)
+	case "Browse":
+		var request gopcxmlda.BrowseRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		return h.browse(ctx, rcvTime, request)
+	case "GetProperties":
+		var request gopcxmlda.GetPropertiesRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		return h.getProperties(ctx, rcvTime, request)
+	}
+
+	subscriber, ok := h.backend.(Subscriber)
+	switch operation.Name.Local {
+	case "Subscribe", "SubscriptionPolledRefresh", "SubscriptionCancel":
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", operation.Name.Local, gopcxmlda.ErrNotSupported)
+		}
+	}
+	switch operation.Name.Local {
+	case "Subscribe":
+		var request gopcxmlda.SubscribeRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		return h.subscribe(ctx, rcvTime, subscriber, request)
+	case "SubscriptionPolledRefresh":
+		var request gopcxmlda.SubscriptionPolledRefreshRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		return h.subscriptionPolledRefresh(ctx, rcvTime, subscriber, request)
+	case "SubscriptionCancel":
+		var request gopcxmlda.SubscriptionCancelRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		if err := subscriber.SubscriptionCancel(ctx, request.ServerSubHandle); err != nil {
+			return nil, err
+		}
+		return &subscriptionCancelResponse{ClientRequestHandle: request.ClientRequestHandle}, nil
+	default:
+		return nil, &requestError{fmt.Errorf("unknown operation %s", operation.Name.Local)}
+	}
+}
+
+// reply returns the result of a request with the current ServerState of the Backend.
+func (h *Handler) reply(ctx context.Context, rcvTime time.Time, clientRequestHandle string) (replyBase, error) {
+	_, state, err := h.backend.Status(ctx)
+	if err != nil {
+		return replyBase{}, err
+	}
+	return replyBase{
+		RcvTime:             rcvTime,
+		ReplyTime:           time.Now(),
+		ClientRequestHandle: clientRequestHandle,
+		ServerState:         state,
+	}, nil
+}
+
+func (h *Handler) getStatus(ctx context.Context, rcvTime time.Time, request gopcxmlda.GetStatusRequest) (interface{}, error) {
+	s, state, err := h.backend.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := &getStatusResponse{
+		Result: replyBase{
+			RcvTime:             rcvTime,
+			ReplyTime:           time.Now(),
+			ClientRequestHandle: request.ClientRequestHandle,
+			ServerState:         state,
+		},
+		Status: status{
+			StartTime:                  s.StartTime,
+			ProductVersion:             s.ProductVersion,
+			StatusInfo:                 s.StatusInfo,
+			VendorInfo:                 s.VendorInfo,
+			SupportedInterfaceVersions: []string{cmp.Or(s.SupportedInterfaceVersions, "XML_DA_Version_1_0")},
+		},
+	}
+	if s.SupportedLocaleIDs != "" {
+		response.Status.SupportedLocaleIDs = []string{s.SupportedLocaleIDs}
+	}
+	return response, nil
+}
+
+func (h *Handler) read(ctx context.Context, rcvTime time.Time, request gopcxmlda.ReadRequest) (interface{}, error) {
+	items := make([]gopcxmlda.TItem, len(request.ItemList.Items))
+	for i, item := range request.ItemList.Items {
+		items[i] = gopcxmlda.TItem{
+			ItemName:         item.ItemName,
+			ItemPath:         cmp.Or(item.ItemPath, request.ItemList.ItemPath),
+			ClientItemHandle: item.ClientItemHandle,
+		}
+	}
+	results, err := h.backend.Read(ctx, request.Options, items)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.reply(ctx, rcvTime, request.Options.ClientRequestHandle)
+	if err != nil {
+		return nil, err
+	}
+	return &readResponse{
+		Result:   result,
+		ItemList: itemList{Items: newItemValues(results, request.Options)},
+		Errors:   newOpcErrors(results, request.Options.ReturnErrorText),
+	}, nil
+}
+
+func (h *Handler) write(ctx context.Context, rcvTime time.Time, request gopcxmlda.WriteRequest) (interface{}, error) {
+	items := make([]gopcxmlda.TItem, len(request.ItemList.Items))
+	for i, item := range request.ItemList.Items {
+		items[i] = gopcxmlda.TItem{
+			ItemName:         item.ItemName,
+			ItemPath:         cmp.Or(item.ItemPath, request.ItemList.ItemPath),
+			ClientItemHandle: item.ClientItemHandle,
+			Value:            item.Value,
+		}
+		if item.Value.Type == "base64Binary" {
+			// the client decodes base64Binary as its text, a Backend gets the bytes
+			b, err := gopcxmlda.ValueAs[[]byte](item.Value)
+			if err != nil {
+				return nil, &requestError{err}
+			}
+			items[i].Value.Value = b
+		}
+		if item.Timestamp != nil {
+			items[i].Timestamp = *item.Timestamp
+		}
+		if item.Quality != nil {
+			items[i].Quality = *item.Quality
+		}
+	}
+	results, err := h.backend.Write(ctx, request.Options, items)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Value = gopcxmlda.TValue{}
+		results[i].Quality = gopcxmlda.TQuality{}
+	}
+	if request.ReturnValuesOnReply {
+		if err := h.readWritten(ctx, request.Options, results); err != nil {
+			return nil, err
+		}
+	}
+	result, err := h.reply(ctx, rcvTime, request.Options.ClientRequestHandle)
+	if err != nil {
+		return nil, err
+	}
+	return &writeResponse{
+		Result:   result,
+		ItemList: itemList{Items: newItemValues(results, request.Options)},
+		Errors:   newOpcErrors(results, request.Options.ReturnErrorText),
+	}, nil
+}
+
+// readWritten reads the values of the successfully written items into results.
+func (h *Handler) readWritten(ctx context.Context, options gopcxmlda.TRequestOptions, results []gopcxmlda.TItem) error {
+	var indices []int
+	var items []gopcxmlda.TItem
+	for i, item := range results {
+		if item.Error == "" || gopcxmlda.ParseResultCode(item.Error).IsSuccess() {
+			indices = append(indices, i)
+			items = append(items, gopcxmlda.TItem{
+				ItemName:         item.ItemName,
+				ItemPath:         item.ItemPath,
+				ClientItemHandle: item.ClientItemHandle,
+			})
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	values, err := h.backend.Read(ctx, options, items)
+	if err != nil {
+		return err
+	}
+	for i, value := range values {
+		if i >= len(indices) {
+			break
+		}
+		result := &results[indices[i]]
+		result.Value = value.Value
+		result.Quality = value.Quality
+		result.Timestamp = value.Timestamp
+		if result.Error == "" {
+			result.Error = value.Error
+		}
+	}
+	return nil
+}
+
+func (h *Handler) subscribe(ctx context.Context, rcvTime time.Time, subscriber Subscriber, request gopcxmlda.SubscribeRequest) (interface{}, error) {
+	// the attributes of the item list are the defaults of the items
+	list := request.ItemList
+	for i := range list.Items {
+		item := &list.Items[i]
+		item.ItemPath = cmp.Or(item.ItemPath, list.ItemPath)
+		item.ReqType = cmp.Or(item.ReqType, list.ReqType)
+		item.Deadband = cmp.Or(item.Deadband, list.Deadband)
+		item.RequestedSamplingRate = cmp.Or(item.RequestedSamplingRate, list.RequestedSamplingRate)
+		item.EnableBuffering = item.EnableBuffering || list.EnableBuffering
+	}
+	subscription, err := subscriber.Subscribe(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.reply(ctx, rcvTime, request.Options.ClientRequestHandle)
+	if err != nil {
+		return nil, err
+	}
+	response := &subscribeResponse{
+		ServerSubHandle: subscription.ServerSubHandle,
+		Result:          result,
+		ItemList:        subscribeItemList{RevisedSamplingRate: subscription.RevisedSamplingRate},
+	}
+	items := make([]gopcxmlda.TItem, len(subscription.Items))
+	for i, item := range subscription.Items {
+		items[i] = item.ItemValue
+		response.ItemList.Items = append(response.ItemList.Items, subscribeItemValue{
+			RevisedSamplingRate: item.RevisedSamplingRate,
+			ItemValue:           newItemValue(item.ItemValue, request.Options),
+		})
+	}
+	response.Errors = newOpcErrors(items, request.Options.ReturnErrorText)
+	return response, nil
+}
+
+func (h *Handler) subscriptionPolledRefresh(ctx context.Context, rcvTime time.Time, subscriber Subscriber,
+	request gopcxmlda.SubscriptionPolledRefreshRequest) (interface{}, error) {
+	refresh, err := subscriber.SubscriptionPolledRefresh(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.reply(ctx, rcvTime, request.Options.ClientRequestHandle)
+	if err != nil {
+		return nil, err
+	}
+	response := &subscriptionPolledRefreshResponse{
+		DataBufferOverflow:      refresh.DataBufferOverflow,
+		Result:                  result,
+		InvalidServerSubHandles: refresh.InvalidServerSubHandles,
+	}
+	var items []gopcxmlda.TItem
+	for _, list := range refresh.ItemLists {
+		response.ItemLists = append(response.ItemLists, subscribeRItemList{
+			SubscriptionHandle: list.SubscriptionHandle,
+			Items:              newItemValues(list.Items, request.Options),
+		})
+		items = append(items, list.Items...)
+	}
+	response.Errors = newOpcErrors(items, request.Options.ReturnErrorText)
+	return response, nil
+}
+
+func (h *Handler) browse(ctx context.Context, rcvTime time.Time, request gopcxmlda.BrowseRequest) (interface{}, error) {
+	browse, err := h.backend.Browse(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.reply(ctx, rcvTime, request.ClientRequestHandle)
+	if err != nil {
+		return nil, err
+	}
+	response := &browseResponse{
+		ContinuationPoint: browse.ContinuationPoint,
+		MoreElements:      strings.EqualFold(browse.MoreElements, "true"),
+		Result:            result,
+		Elements:          browse.Elements,
+	}
+	if browse.Errors.Id != "" {
+		response.Errors = []opcError{{ID: browse.Errors.Id, Text: browse.Errors.Text}}
+	}
+	return response, nil
+}
+
+func (h *Handler) getProperties(ctx context.Context, rcvTime time.Time, request gopcxmlda.GetPropertiesRequest) (interface{}, error) {
+	lists, err := h.backend.GetProperties(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.reply(ctx, rcvTime, request.ClientRequestHandle)
+	if err != nil {
+		return nil, err
+	}
+	response := &getPropertiesResponse{Result: result}
+	items := make([]gopcxmlda.TItem, len(lists))
+	for i, list := range lists {
+		items[i] = gopcxmlda.TItem{ItemName: list.ItemName, ItemPath: list.ItemPath, Error: list.ResultId}
+		pl := propertyList{ItemPath: list.ItemPath, ItemName: list.ItemName, ResultID: list.ResultId}
+		for _, p := range list.Properties {
+			prop := property{Name: p.Name, Description: p.Description, ItemPath: p.ItemPath, ItemName: p.ItemName}
+			if request.ReturnPropertyValues && p.Value.Value != nil {
+				value := p.Value
+				prop.Value = &value
+			}
+			pl.Properties = append(pl.Properties, prop)
+		}
+		response.PropertyLists = append(response.PropertyLists, pl)
+	}
+	response.Errors = newOpcErrors(items, request.ReturnErrorText)
+	return response, nil
+}
+
+// writeFault writes err as SOAP fault. The fault code is the ResultCode of err, if any,
+// SOAP-ENV:Client for requests which could not be decoded and SOAP-ENV:Server otherwise.
+func writeFault(w http.ResponseWriter, err error) {
+	code := "SOAP-ENV:Server"
+	var resultCode gopcxmlda.ResultCode
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &resultCode):
+		code = "ns0:" + string(resultCode)
+	case errors.As(err, &reqErr):
+		code = "SOAP-ENV:Client"
+	}
+	var body strings.Builder
+	body.WriteString(envelopeOpen)
+	body.WriteString("<SOAP-ENV:Fault><faultcode>")
+	_ = xml.EscapeText(&body, []byte(code))
+	body.WriteString("</faultcode><faultstring>")
+	_ = xml.EscapeText(&body, []byte(err.Error()))
+	body.WriteString("</faultstring></SOAP-ENV:Fault>")
+	body.WriteString(gopcxmlda.Footer)
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write([]byte(body.String()))
+}
@@ -0,0 +1,392 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// testBackend serves a fixed set of items and supports a single subscription.
+type testBackend struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+	state  string
+	sub    string
+}
+
+func newTestBackend() *testBackend {
+	return &testBackend{
+		values: map[string]interface{}{
+			"Temperature": 21.5,
+			"Running":     true,
+			"Name":        "Pump <1> & \"2\"",
+			"Setpoints":   []float32{1.5, 2.5},
+		},
+		state: StateRunning,
+	}
+}
+
+func (b *testBackend) Status(context.Context) (gopcxmlda.TStatus, string, error) {
+	return gopcxmlda.TStatus{
+		ProductVersion:     "1.0.0",
+		StartTime:          "2024-01-01T00:00:00Z",
+		StatusInfo:         "ok",
+		VendorInfo:         "test",
+		SupportedLocaleIDs: "en-US",
+	}, b.state, nil
+}
+
+func (b *testBackend) Read(_ context.Context, _ gopcxmlda.TRequestOptions, items []gopcxmlda.TItem) ([]gopcxmlda.TItem, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range items {
+		value, ok := b.values[items[i].ItemName]
+		if !ok {
+			items[i].Error = string(gopcxmlda.ErrUnknownItemName)
+			continue
+		}
+		items[i].Value = gopcxmlda.TValue{Value: value}
+		items[i].Quality = gopcxmlda.TQuality{QualityField: "good"}
+		items[i].Timestamp = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	}
+	return items, nil
+}
+
+func (b *testBackend) Write(_ context.Context, _ gopcxmlda.TRequestOptions, items []gopcxmlda.TItem) ([]gopcxmlda.TItem, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range items {
+		if _, ok := b.values[items[i].ItemName]; !ok {
+			items[i].Error = string(gopcxmlda.ErrUnknownItemName)
+			continue
+		}
+		b.values[items[i].ItemName] = items[i].Value.Value
+	}
+	return items, nil
+}
+
+func (b *testBackend) Browse(_ context.Context, request gopcxmlda.BrowseRequest) (gopcxmlda.TBrowseResponse, error) {
+	if request.ItemPath != "" {
+		return gopcxmlda.TBrowseResponse{}, gopcxmlda.ErrUnknownItemPath
+	}
+	return gopcxmlda.TBrowseResponse{
+		MoreElements: "false",
+		Elements: []gopcxmlda.TBrowseElement{
+			{Name: "Temperature", ItemName: "Temperature", IsItem: true},
+			{Name: "Folder", ItemName: "Folder", HasChildren: true},
+		},
+	}, nil
+}
+
+func (b *testBackend) GetProperties(_ context.Context, request gopcxmlda.GetPropertiesRequest) ([]gopcxmlda.TPropertyList, error) {
+	var lists []gopcxmlda.TPropertyList
+	for _, id := range request.ItemIDs {
+		list := gopcxmlda.TPropertyList{ItemName: id.ItemName, ItemPath: id.ItemPath}
+		if id.ItemName != "Temperature" {
+			list.ResultId = string(gopcxmlda.ErrUnknownItemName)
+		} else {
+			list.Properties = []gopcxmlda.TProperties{
+				{Name: "dataType", Value: gopcxmlda.TValue{Type: "QName", Value: "xsd:double"}},
+				{Name: "engineeringUnits", Value: gopcxmlda.TValue{Value: "°C"}},
+			}
+		}
+		lists = append(lists, list)
+	}
+	return lists, nil
+}
+
+type testSubscriber struct {
+	*testBackend
+}
+
+func (b testSubscriber) Subscribe(_ context.Context, request gopcxmlda.SubscribeRequest) (SubscribeResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sub = "sub-1"
+	result := SubscribeResult{ServerSubHandle: b.sub, RevisedSamplingRate: 500}
+	for _, item := range request.ItemList.Items {
+		value := gopcxmlda.TItem{ClientItemHandle: item.ClientItemHandle}
+		if request.ReturnValuesOnReply {
+			value.Value = gopcxmlda.TValue{Value: b.values[item.ItemName]}
+		}
+		result.Items = append(result.Items, gopcxmlda.TSubscribeItemValue{ItemValue: value})
+	}
+	return result, nil
+}
+
+func (b testSubscriber) SubscriptionPolledRefresh(_ context.Context, request gopcxmlda.SubscriptionPolledRefreshRequest) (RefreshResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var result RefreshResult
+	for _, handle := range request.ServerSubHandles {
+		if handle != b.sub {
+			result.InvalidServerSubHandles = append(result.InvalidServerSubHandles, handle)
+			continue
+		}
+		result.ItemLists = append(result.ItemLists, gopcxmlda.TItemListSPR{
+			SubscriptionHandle: handle,
+			Items:              []gopcxmlda.TItem{{ClientItemHandle: "h0", Value: gopcxmlda.TValue{Value: 22.5}}},
+		})
+	}
+	return result, nil
+}
+
+func (b testSubscriber) SubscriptionCancel(_ context.Context, serverSubHandle string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if serverSubHandle != b.sub {
+		return gopcxmlda.ErrNoSubscription
+	}
+	b.sub = ""
+	return nil
+}
+
+func newTestClient(t *testing.T, backend Backend) *gopcxmlda.Server {
+	t.Helper()
+	ts := httptest.NewServer(NewHandler(backend))
+	t.Cleanup(ts.Close)
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gopcxmlda.NewServer(u, "en-US")
+}
+
+func TestHandlerGetStatus(t *testing.T) {
+	backend := newTestBackend()
+	s := newTestClient(t, backend)
+	handle := "status-1"
+	status, err := s.GetStatus(context.Background(), &handle, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Response.Result.ServerState != StateRunning || status.Response.Result.ClientRequestHandle != "status-1" {
+		t.Errorf("unexpected result: %+v", status.Response.Result)
+	}
+	if status.Response.Status.ProductVersion != "1.0.0" || status.Response.Status.SupportedLocaleIDs != "en-US" {
+		t.Errorf("unexpected status: %+v", status.Response.Status)
+	}
+	if status.Response.Result.ReceiveTime.IsZero() || status.Response.Result.ReplyTime.Before(status.Response.Result.ReceiveTime) {
+		t.Errorf("unexpected times: %+v", status.Response.Result)
+	}
+}
+
+func TestHandlerRead(t *testing.T) {
+	s := newTestClient(t, newTestBackend())
+	items := []gopcxmlda.TItem{{ItemName: "Temperature"}, {ItemName: "Running"}, {ItemName: "Name"}, {ItemName: "Setpoints"}}
+	var handle string
+	var itemHandles []string
+	read, err := s.Read(context.Background(), items, &handle, &itemHandles, "",
+		gopcxmlda.TRequestOptions{ReturnItemName: true, ReturnItemTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := read.Response.ItemList.Items
+	if len(got) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(got))
+	}
+	if got[0].ItemName != "Temperature" || got[0].Value.Value != 21.5 || got[0].Quality.QualityField != "good" {
+		t.Errorf("unexpected item: %+v", got[0])
+	}
+	if !got[0].Timestamp.Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected timestamp: %v", got[0].Timestamp)
+	}
+	if got[1].Value.Value != true {
+		t.Errorf("unexpected value: %#v", got[1].Value.Value)
+	}
+	if got[2].Value.Value != "Pump <1> & \"2\"" {
+		t.Errorf("value not escaped: %#v", got[2].Value.Value)
+	}
+	if values, ok := got[3].Value.Value.([]interface{}); !ok || len(values) != 2 || values[1] != float32(2.5) {
+		t.Errorf("unexpected array: %#v", got[3].Value.Value)
+	}
+	if got[0].ClientItemHandle != itemHandles[0] {
+		t.Errorf("ClientItemHandle %q, expected %q", got[0].ClientItemHandle, itemHandles[0])
+	}
+}
+
+func TestHandlerReadUnknownItem(t *testing.T) {
+	s := newTestClient(t, newTestBackend())
+	var handle string
+	var itemHandles []string
+	read, err := s.Read(context.Background(), []gopcxmlda.TItem{{ItemName: "Temperature"}, {ItemName: "Missing"}},
+		&handle, &itemHandles, "", gopcxmlda.TRequestOptions{ReturnErrorText: true})
+	if !errors.Is(err, gopcxmlda.ErrUnknownItemName) {
+		t.Fatalf("expected ErrUnknownItemName, got %v", err)
+	}
+	var itemErr *gopcxmlda.ItemError
+	if err := read.ItemErrors(); !errors.As(err, &itemErr) || itemErr.Text == "" {
+		t.Errorf("expected item error with text, got %v", err)
+	}
+	if read.Response.ItemList.Items[0].ItemName != "" {
+		t.Errorf("ItemName returned without ReturnItemName")
+	}
+}
+
+func TestHandlerWrite(t *testing.T) {
+	backend := newTestBackend()
+	s := newTestClient(t, backend)
+	var handle string
+	var itemHandles []string
+	write, err := s.Write(context.Background(), []gopcxmlda.TItem{{ItemName: "Temperature", Value: gopcxmlda.TValue{Value: 30.25}}},
+		&handle, &itemHandles, "", gopcxmlda.TRequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the client always requests the values on reply
+	if got := write.Response.ItemList.Items; len(got) != 1 || got[0].Value.Value != 30.25 {
+		t.Errorf("unexpected items: %+v", got)
+	}
+	if backend.values["Temperature"] != 30.25 {
+		t.Errorf("value not written: %v", backend.values["Temperature"])
+	}
+
+	// base64Binary is decoded as text by the client, the backend gets the bytes
+	if _, err := s.Write(context.Background(), []gopcxmlda.TItem{{ItemName: "Name", Value: gopcxmlda.TValue{Value: []byte("\x00\xff")}}},
+		&handle, &itemHandles, "", gopcxmlda.TRequestOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := backend.values["Name"].([]byte); !ok || string(b) != "\x00\xff" {
+		t.Errorf("base64Binary written as %#v", backend.values["Name"])
+	}
+}
+
+func TestHandlerBrowse(t *testing.T) {
+	s := newTestClient(t, newTestBackend())
+	elements, err := s.BrowseAll(context.Background(), "", "", gopcxmlda.TBrowseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 2 || !elements[0].IsItem || !elements[1].HasChildren {
+		t.Errorf("unexpected elements: %+v", elements)
+	}
+
+	_, err = s.BrowseAll(context.Background(), "Unknown", "", gopcxmlda.TBrowseOptions{})
+	var fault *gopcxmlda.SoapFault
+	if !errors.As(err, &fault) || !errors.Is(err, gopcxmlda.ErrUnknownItemPath) {
+		t.Errorf("expected fault with ErrUnknownItemPath, got %v", err)
+	}
+}
+
+func TestHandlerGetProperties(t *testing.T) {
+	s := newTestClient(t, newTestBackend())
+	var handle string
+	properties, err := s.GetProperties(context.Background(), []gopcxmlda.TItem{{ItemName: "Temperature"}},
+		gopcxmlda.TPropertyOptions{ReturnPropertyValues: true}, &handle, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := properties.Response.PropertyList
+	if len(list) != 1 || len(list[0].Properties) != 2 {
+		t.Fatalf("unexpected property lists: %+v", list)
+	}
+	if list[0].Properties[1].Value.Value != "°C" {
+		t.Errorf("unexpected value: %+v", list[0].Properties[1])
+	}
+}
+
+func TestHandlerSubscription(t *testing.T) {
+	s := newTestClient(t, testSubscriber{newTestBackend()})
+	ctx := context.Background()
+	var handle string
+	var itemHandles []string
+	subscribe, err := s.Subscribe(ctx, []gopcxmlda.TItem{{ItemName: "Temperature"}}, &handle, &itemHandles, "", true, 1000,
+		gopcxmlda.TRequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subscribe.Response.ServerSubHandle != "sub-1" || subscribe.Response.ItemList.RevisedSamplingRate != 500 {
+		t.Errorf("unexpected response: %+v", subscribe.Response)
+	}
+	if items := subscribe.Response.ItemList.Items; len(items) != 1 || items[0].ItemValue.Value.Value != 21.5 {
+		t.Errorf("unexpected items: %+v", items)
+	}
+
+	handle = ""
+	refresh, err := s.SubscriptionPolledRefresh(ctx, "sub-1", 1000, "", &handle, gopcxmlda.TRequestOptions{},
+		gopcxmlda.TServerTime{UseClientTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := refresh.Response.ItemList; list.SubscriptionHandle != "sub-1" || len(list.Items) != 1 || list.Items[0].Value.Value != 22.5 {
+		t.Errorf("unexpected refresh: %+v", list)
+	}
+
+	handle = ""
+	if ok, err := s.SubscriptionCancel(ctx, "sub-1", "", &handle); !ok || err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	handle = ""
+	if _, err := s.SubscriptionCancel(ctx, "sub-1", "", &handle); !errors.Is(err, gopcxmlda.ErrNoSubscription) {
+		t.Errorf("expected ErrNoSubscription, got %v", err)
+	}
+}
+
+func TestHandlerWithoutSubscriber(t *testing.T) {
+	s := newTestClient(t, newTestBackend())
+	var handle string
+	var itemHandles []string
+	_, err := s.Subscribe(context.Background(), []gopcxmlda.TItem{{ItemName: "Temperature"}}, &handle, &itemHandles, "", false, 1000,
+		gopcxmlda.TRequestOptions{})
+	if !errors.Is(err, gopcxmlda.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestHandlerInvalidRequest(t *testing.T) {
+	ts := httptest.NewServer(NewHandler(newTestBackend()))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL, "text/xml", strings.NewReader("<Envelope><Body><Unknown/></Body></Envelope>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || !strings.Contains(string(body), "<faultcode>SOAP-ENV:Client</faultcode>") {
+		t.Errorf("unexpected response %d: %s", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandlerMaxRequestBytes(t *testing.T) {
+	handler := NewHandler(newTestBackend())
+	handler.MaxRequestBytes = 1024
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	request := "<Envelope><Body><GetStatus>" + strings.Repeat("<x/>", 1024) + "</GetStatus></Body></Envelope>"
+	resp, err := http.Post(ts.URL, "text/xml", strings.NewReader(request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || !strings.Contains(string(body), "<faultcode>SOAP-ENV:Client</faultcode>") ||
+		!strings.Contains(string(body), "larger than 1024 bytes") {
+		t.Errorf("unexpected response %d: %s", resp.StatusCode, body)
+	}
+
+	u, _ := url.Parse(ts.URL)
+	var handle string
+	if _, err := gopcxmlda.NewServer(u, "en-US").GetStatus(context.Background(), &handle, ""); err != nil {
+		t.Errorf("small request rejected: %v", err)
+	}
+}
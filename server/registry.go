@@ -0,0 +1,454 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// Variable is an item of a Registry, backed by Go functions.
+type Variable struct {
+	Get        func() interface{}            // returns the current value, required
+	Set        func(value interface{}) error // writes a value, nil for read-only items
+	Type       string                        // xsi:type of the value, derived from the value if empty
+	Properties map[string]interface{}        // further properties, e.g. "engineeringUnits" or "highEU"
+}
+
+// Registry is a Backend serving Go values as a hierarchical address space, in which "/" separates
+// the branches of the item names. Items are registered with Register, or derived from a map or
+// struct tree with RegisterTree. Subscriptions are served by wrapping the Registry in a
+// SubscriptionEngine:
+//
+//	registry := server.NewRegistry()
+//	registry.Register("Plant1/Power", server.Variable{Get: func() interface{} { return meter.Power() }})
+//	registry.RegisterTree("Plant1/Config", &config)
+//	engine := server.NewSubscriptionEngine(registry, server.EngineOptions{})
+//	defer engine.Close()
+//	log.Fatal(http.ListenAndServe(":8080", server.NewHandler(engine)))
+//
+// Get and Set are called with the Registry locked and must not call its methods. Values written to a
+// Variable are converted to the type of its current value, a value that cannot be converted is
+// rejected with E_BADTYPE, one out of the range of the type with E_RANGE. The timestamp of a value
+// is the time its change was first read.
+type Registry struct {
+	mu        sync.Mutex
+	status    gopcxmlda.TStatus
+	state     string
+	variables map[string]*variable
+	names     []string // item names in the order of registration, the order of Browse
+}
+
+type variable struct {
+	Variable
+	last      interface{}
+	timestamp time.Time
+}
+
+// NewRegistry returns an empty Registry in the state StateRunning.
+func NewRegistry() *Registry {
+	return &Registry{
+		status: gopcxmlda.TStatus{
+			ProductVersion: "gopcxmlda",
+			StartTime:      time.Now().UTC().Format(time.RFC3339),
+		},
+		state:     StateRunning,
+		variables: make(map[string]*variable),
+	}
+}
+
+// SetStatus sets the status returned by GetStatus.
+func (r *Registry) SetStatus(status gopcxmlda.TStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// SetState sets the ServerState returned with every response, e.g. StateSuspended.
+func (r *Registry) SetState(state string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state = state
+}
+
+// Register adds an item. It fails if the name is empty or already registered.
+func (r *Registry) Register(name string, v Variable) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.register(name, v)
+}
+
+// register adds an item. r.mu must be held.
+func (r *Registry) register(name string, v Variable) error {
+	if name == "" || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") {
+		return fmt.Errorf("invalid item name %q", name)
+	}
+	if v.Get == nil {
+		return fmt.Errorf("item %q: no Get function", name)
+	}
+	if _, ok := r.variables[name]; ok {
+		return fmt.Errorf("item %q already registered", name)
+	}
+	r.variables[name] = &variable{Variable: v}
+	r.names = append(r.names, name)
+	return nil
+}
+
+// RegisterTree adds the leaves of a map with string keys or a struct as items below name. Maps and
+// structs nested in tree become branches, other values items. The structure is registered when
+// RegisterTree is called, the values are read when requested.
+//
+// Entries of maps and fields of structs passed by pointer are writable, fields of structs passed
+// by value are read-only. Struct fields are named by their "opc" tag, e.g. `opc:"setpoint"`;
+// `opc:"-"` skips a field, `opc:",readonly"` makes it read-only. Unexported fields are skipped.
+// Values changed by other goroutines must be changed within Update.
+func (r *Registry) RegisterTree(name string, tree interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.registerTree(strings.TrimSuffix(name, "/"), reflect.ValueOf(tree), false)
+}
+
+// registerTree adds the leaves of v below name. r.mu must be held.
+func (r *Registry) registerTree(name string, v reflect.Value, readOnly bool) error {
+	if !v.IsValid() {
+		return fmt.Errorf("item %q: nil value", name)
+	}
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct && !isLeaf(v.Elem().Type()) {
+		if v.IsNil() {
+			return fmt.Errorf("item %q: nil value", name)
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			child := childName(name, key.String())
+			entry := v.MapIndex(key)
+			if isNested(entry) {
+				if err := r.registerTree(child, entry, readOnly); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := r.registerLeaf(child, entry.Interface(), func() interface{} {
+				return v.MapIndex(key).Interface()
+			}, func(value reflect.Value) {
+				v.SetMapIndex(key, value)
+			}, readOnly); err != nil {
+				return err
+			}
+		}
+		return nil
+	case v.Kind() == reflect.Struct && !isLeaf(v.Type()):
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldName, options, _ := strings.Cut(field.Tag.Get("opc"), ",")
+			if fieldName == "-" {
+				continue
+			}
+			fieldReadOnly := readOnly || !v.CanAddr() || options == "readonly"
+			child := childName(name, cmp.Or(fieldName, field.Name))
+			fv := v.Field(i)
+			if isNested(fv) {
+				if err := r.registerTree(child, fv, fieldReadOnly); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := r.registerLeaf(child, fv.Interface(), fv.Interface, fv.Set, fieldReadOnly); err != nil {
+				return err
+			}
+		}
+		return nil
+	case v.Kind() == reflect.Pointer && !v.IsNil():
+		// pointer to a single value
+		elem := v.Elem()
+		return r.registerLeaf(name, elem.Interface(), elem.Interface, elem.Set, readOnly)
+	default:
+		return fmt.Errorf("item %q: unsupported tree of type %v", name, v.Type())
+	}
+}
+
+// registerLeaf registers an item read by get and written by set. r.mu must be held.
+func (r *Registry) registerLeaf(name string, value interface{}, get func() interface{}, set func(reflect.Value), readOnly bool) error {
+	if _, err := gopcxmlda.OpcXmlDaType(value); err != nil {
+		return fmt.Errorf("item %q: %w", name, err)
+	}
+	v := Variable{Get: get}
+	if !readOnly {
+		v.Set = func(value interface{}) error {
+			set(reflect.ValueOf(value))
+			return nil
+		}
+	}
+	return r.register(name, v)
+}
+
+// isNested reports whether v is registered by registerTree: a branch or a pointer to a value.
+func isNested(v reflect.Value) bool {
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Pointer {
+		return !v.IsNil()
+	}
+	return v.Kind() == reflect.Map || v.Kind() == reflect.Struct && !isLeaf(v.Type())
+}
+
+// isLeaf reports whether a struct type is a value, not a branch.
+func isLeaf(t reflect.Type) bool {
+	return t == reflect.TypeOf(time.Time{})
+}
+
+func childName(name, child string) string {
+	if name == "" {
+		return child
+	}
+	return name + "/" + child
+}
+
+// Unregister removes an item and all items below it.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := r.names[:0]
+	for _, itemName := range r.names {
+		if itemName == name || strings.HasPrefix(itemName, name+"/") {
+			delete(r.variables, itemName)
+			continue
+		}
+		names = append(names, itemName)
+	}
+	r.names = names
+}
+
+// Update calls fn with the Registry locked, so values registered with RegisterTree can be
+// changed while requests are served.
+func (r *Registry) Update(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn()
+}
+
+func (r *Registry) Status(context.Context) (gopcxmlda.TStatus, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status, r.state, nil
+}
+
+// lookup returns the variable of an item or the ResultID. r.mu must be held.
+func (r *Registry) lookup(item gopcxmlda.TItem) (*variable, gopcxmlda.ResultCode) {
+	if item.ItemPath != "" {
+		return nil, gopcxmlda.ErrUnknownItemPath
+	}
+	v, ok := r.variables[item.ItemName]
+	if !ok {
+		return nil, gopcxmlda.ErrUnknownItemName
+	}
+	return v, ""
+}
+
+// value returns the current value of a variable and updates its timestamp. r.mu must be held.
+func (v *variable) value() (gopcxmlda.TValue, time.Time) {
+	value := v.Get()
+	if v.timestamp.IsZero() || !reflect.DeepEqual(value, v.last) {
+		v.last = clone(value)
+		v.timestamp = time.Now()
+	}
+	return gopcxmlda.TValue{Type: v.Type, Value: value}, v.timestamp
+}
+
+// clone copies slices, so later changes of their elements are detected.
+func clone(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice || v.IsNil() {
+		return value
+	}
+	c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	reflect.Copy(c, v)
+	return c.Interface()
+}
+
+func (r *Registry) Read(_ context.Context, _ gopcxmlda.TRequestOptions, items []gopcxmlda.TItem) ([]gopcxmlda.TItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range items {
+		v, code := r.lookup(items[i])
+		if code != "" {
+			items[i].Error = string(code)
+			continue
+		}
+		items[i].Value, items[i].Timestamp = v.value()
+		items[i].Quality = gopcxmlda.TQuality{QualityField: "good"}
+	}
+	return items, nil
+}
+
+func (r *Registry) Write(_ context.Context, _ gopcxmlda.TRequestOptions, items []gopcxmlda.TItem) ([]gopcxmlda.TItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range items {
+		items[i].Error = string(r.write(items[i]))
+	}
+	return items, nil
+}
+
+// write writes the value of an item. r.mu must be held.
+func (r *Registry) write(item gopcxmlda.TItem) gopcxmlda.ResultCode {
+	v, code := r.lookup(item)
+	if code != "" {
+		return code
+	}
+	if v.Set == nil {
+		return gopcxmlda.ErrReadOnly
+	}
+	value := item.Value.Value
+	if current := v.Get(); current != nil {
+		converted, code := convert(value, reflect.TypeOf(current))
+		if code != "" {
+			return code
+		}
+		value = converted.Interface()
+	}
+	if err := v.Set(value); err != nil {
+		var code gopcxmlda.ResultCode
+		if errors.As(err, &code) {
+			return code
+		}
+		return gopcxmlda.ErrFail
+	}
+	return ""
+}
+
+// convert converts a written value to the type t. Numbers are converted between numeric types
+// if the value is in the range of t, slices element by element.
+func convert(value interface{}, t reflect.Type) (reflect.Value, gopcxmlda.ResultCode) {
+	if value == nil {
+		return reflect.Value{}, gopcxmlda.ErrBadType
+	}
+	v := reflect.ValueOf(value)
+	if v.Type() == t {
+		return v, ""
+	}
+	if v.Kind() == reflect.Slice && t.Kind() == reflect.Slice {
+		c := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem, code := convert(v.Index(i).Interface(), t.Elem())
+			if code != "" {
+				return reflect.Value{}, code
+			}
+			c.Index(i).Set(elem)
+		}
+		return c, ""
+	}
+	f, ok := toFloat(value)
+	if !ok || v.Kind() == reflect.String {
+		return reflect.Value{}, gopcxmlda.ErrBadType
+	}
+	c := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f != math.Trunc(f) {
+			return reflect.Value{}, gopcxmlda.ErrBadType
+		}
+		if f < math.MinInt64 || f >= math.MaxInt64 || c.OverflowInt(int64(f)) {
+			return reflect.Value{}, gopcxmlda.ErrRange
+		}
+		c.SetInt(v.Convert(reflect.TypeOf(int64(0))).Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f != math.Trunc(f) {
+			return reflect.Value{}, gopcxmlda.ErrBadType
+		}
+		if f < 0 || f >= math.MaxUint64 || c.OverflowUint(uint64(f)) {
+			return reflect.Value{}, gopcxmlda.ErrRange
+		}
+		c.SetUint(v.Convert(reflect.TypeOf(uint64(0))).Uint())
+	case reflect.Float32, reflect.Float64:
+		if c.OverflowFloat(f) {
+			return reflect.Value{}, gopcxmlda.ErrRange
+		}
+		c.SetFloat(f)
+	default:
+		return reflect.Value{}, gopcxmlda.ErrBadType
+	}
+	return c, ""
+}
+
+func (r *Registry) Browse(_ context.Context, request gopcxmlda.BrowseRequest) (gopcxmlda.TBrowseResponse, error) {
+	r.mu.Lock()
+	names := append([]string(nil), r.names...)
+	r.mu.Unlock()
+	return BrowseNames(names, request)
+}
+
+func (r *Registry) GetProperties(_ context.Context, request gopcxmlda.GetPropertiesRequest) ([]gopcxmlda.TPropertyList, error) {
+	if request.ItemPath != "" {
+		return nil, gopcxmlda.ErrUnknownItemPath
+	}
+	names := make(map[string]bool)
+	for _, name := range request.PropertyNames {
+		names[name[strings.LastIndex(name, ":")+1:]] = true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lists := make([]gopcxmlda.TPropertyList, len(request.ItemIDs))
+	for i, id := range request.ItemIDs {
+		lists[i] = gopcxmlda.TPropertyList{ItemName: id.ItemName, ItemPath: id.ItemPath}
+		v, code := r.lookup(gopcxmlda.TItem{ItemName: id.ItemName, ItemPath: id.ItemPath})
+		if code != "" {
+			lists[i].ResultId = string(code)
+			continue
+		}
+		for _, p := range v.properties() {
+			if request.ReturnAllProperties || len(names) == 0 || names[p.Name] {
+				lists[i].Properties = append(lists[i].Properties, p)
+			}
+		}
+	}
+	return lists, nil
+}
+
+// properties returns the properties of a variable, the properties of the specification first. r.mu must be held.
+func (v *variable) properties() []gopcxmlda.TProperties {
+	value, timestamp := v.value()
+	dataType := value.Type
+	if dataType == "" && value.Value != nil {
+		dataType, _ = gopcxmlda.OpcXmlDaType(value.Value)
+	}
+	accessRights := gopcxmlda.AccessReadWritable
+	if v.Set == nil {
+		accessRights = gopcxmlda.AccessReadable
+	}
+	properties := []gopcxmlda.TProperties{
+		{Name: gopcxmlda.PropertyDataType, Value: gopcxmlda.TValue{Type: "QName", Value: gopcxmlda.QualifiedType(dataType, "")}},
+		{Name: gopcxmlda.PropertyValue, Value: value},
+		{Name: gopcxmlda.PropertyQuality, Value: gopcxmlda.TValue{Type: "OPCQuality", Value: gopcxmlda.TQuality{QualityField: "good"}}},
+		{Name: gopcxmlda.PropertyTimestamp, Value: gopcxmlda.TValue{Value: timestamp}},
+		{Name: gopcxmlda.PropertyAccessRights, Value: gopcxmlda.TValue{Value: accessRights.String()}},
+	}
+	names := make([]string, 0, len(v.Properties))
+	for name := range v.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		properties = append(properties, gopcxmlda.TProperties{Name: name, Value: gopcxmlda.TValue{Value: v.Properties[name]}})
+	}
+	return properties
+}
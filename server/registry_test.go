@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+type testConfig struct {
+	Setpoint float64 `opc:"setpoint"`
+	Limit    int32   `opc:",readonly"`
+	Mode     string
+	Pump     struct {
+		Running bool
+		Speeds  []float32
+	}
+	internal int
+	Skipped  string `opc:"-"`
+}
+
+func newRegistryClient(t *testing.T) (*Registry, *testConfig, *gopcxmlda.Server) {
+	t.Helper()
+	config := &testConfig{Setpoint: 20, Limit: 80, Mode: "auto"}
+	config.Pump.Speeds = []float32{1, 2}
+	registry := NewRegistry()
+	power := 1200.5
+	if err := registry.Register("Plant1/Power", Variable{
+		Get:        func() interface{} { return power },
+		Properties: map[string]interface{}{"engineeringUnits": "kW", "highEU": 3000.0, "lowEU": 0.0},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterTree("Plant1/Config", config); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RegisterTree("Plant2", map[string]interface{}{
+		"Counter": 7,
+		"Info":    map[string]interface{}{"Name": "plant 2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewSubscriptionEngine(registry, EngineOptions{MinSamplingRate: 10 * time.Millisecond})
+	ts := httptest.NewServer(NewHandler(engine))
+	t.Cleanup(func() {
+		engine.Close()
+		ts.Close()
+	})
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry, config, gopcxmlda.NewServer(u, "en-US")
+}
+
+func TestRegistryRegister(t *testing.T) {
+	registry := NewRegistry()
+	get := func() interface{} { return 1 }
+	if err := registry.Register("A/B", Variable{Get: get}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register("A/B", Variable{Get: get}); err == nil {
+		t.Error("expected error for a duplicate name")
+	}
+	if err := registry.Register("A/", Variable{Get: get}); err == nil {
+		t.Error("expected error for an invalid name")
+	}
+	if err := registry.RegisterTree("C", map[string]interface{}{"D": struct{}{}, "E": complex(1, 2)}); err == nil {
+		t.Error("expected error for an unsupported type")
+	}
+	registry.Unregister("A")
+	if _, err := registry.Browse(context.Background(), gopcxmlda.BrowseRequest{ItemName: "A"}); !errors.Is(err, gopcxmlda.ErrUnknownItemName) {
+		t.Errorf("expected ErrUnknownItemName after Unregister, got %v", err)
+	}
+}
+
+func TestRegistryRead(t *testing.T) {
+	_, _, s := newRegistryClient(t)
+	var handle string
+	var itemHandles []string
+	R, err := s.Read(context.Background(), []gopcxmlda.TItem{
+		{ItemName: "Plant1/Power"}, {ItemName: "Plant1/Config/setpoint"}, {ItemName: "Plant1/Config/Pump/Speeds"},
+		{ItemName: "Plant2/Counter"}, {ItemName: "Plant2/Info/Name"},
+	}, &handle, &itemHandles, "", gopcxmlda.TRequestOptions{ReturnItemTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := R.Response.ItemList.Items
+	if len(items) != 5 || items[0].Value.Value != 1200.5 || items[1].Value.Value != 20.0 || items[3].Value.Value != 7 ||
+		items[4].Value.Value != "plant 2" || items[0].Timestamp.IsZero() {
+		t.Errorf("unexpected items: %+v", items)
+	}
+	if speeds, ok := items[2].Value.Value.([]interface{}); !ok || len(speeds) != 2 || speeds[1] != float32(2) || items[2].Value.Type != "ArrayOfFloat" {
+		t.Errorf("unexpected array: %+v", items[2].Value)
+	}
+}
+
+func TestRegistryWrite(t *testing.T) {
+	registry, config, s := newRegistryClient(t)
+	var handle string
+	var itemHandles []string
+	W, _ := s.Write(context.Background(), []gopcxmlda.TItem{
+		{ItemName: "Plant1/Config/setpoint", Value: gopcxmlda.TValue{Value: 22.5}},
+		{ItemName: "Plant1/Config/Limit", Value: gopcxmlda.TValue{Value: int32(90)}},
+		{ItemName: "Plant1/Config/Mode", Value: gopcxmlda.TValue{Value: 1}},
+		{ItemName: "Plant1/Power", Value: gopcxmlda.TValue{Value: 1.0}},
+		{ItemName: "Plant1/Config/Pump/Running", Value: gopcxmlda.TValue{Value: true}},
+		{ItemName: "Plant2/Counter", Value: gopcxmlda.TValue{Value: int64(9)}},
+		{ItemName: "Plant2/Counter", Value: gopcxmlda.TValue{Value: 1.5}},
+	}, &handle, &itemHandles, "", gopcxmlda.TRequestOptions{})
+	items := W.Response.ItemList.Items
+	if len(items) != 7 {
+		t.Fatalf("expected 7 items, got %+v", items)
+	}
+	if items[0].Error != "" || !errors.Is(items[1].Err(), gopcxmlda.ErrReadOnly) || !errors.Is(items[2].Err(), gopcxmlda.ErrBadType) ||
+		!errors.Is(items[3].Err(), gopcxmlda.ErrReadOnly) || items[4].Error != "" || items[5].Error != "" ||
+		!errors.Is(items[6].Err(), gopcxmlda.ErrBadType) {
+		t.Errorf("unexpected results: %+v", items)
+	}
+	registry.Update(func() {
+		if config.Setpoint != 22.5 || config.Limit != 80 || !config.Pump.Running {
+			t.Errorf("unexpected config: %+v", config)
+		}
+	})
+
+	if _, code := convert(1000, reflect.TypeOf(int8(0))); code != gopcxmlda.ErrRange {
+		t.Errorf("expected E_RANGE, got %q", code)
+	}
+	if v, code := convert([]float64{1, 2}, reflect.TypeOf([]int16(nil))); code != "" || v.Interface().([]int16)[1] != 2 {
+		t.Errorf("unexpected slice conversion: %v, %q", v, code)
+	}
+}
+
+func TestRegistryBrowse(t *testing.T) {
+	_, _, s := newRegistryClient(t)
+	elements, err := s.BrowseAll(context.Background(), "", "", gopcxmlda.TBrowseOptions{ItemName: "Plant1/Config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 4 || elements[0].Name != "setpoint" || elements[3].Name != "Pump" || !elements[3].HasChildren {
+		t.Errorf("unexpected elements: %+v", elements)
+	}
+
+	var handle string
+	P, err := s.GetProperties(context.Background(), []gopcxmlda.TItem{{ItemName: "Plant1/Power"}, {ItemName: "Plant1/Config/Limit"}, {ItemName: "Plant1/Config/Pump/Speeds"}},
+		gopcxmlda.TPropertyOptions{ReturnAllProperties: true, ReturnPropertyValues: true}, &handle, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lists := P.Response.PropertyList
+	if len(lists) != 3 || len(lists[0].Properties) != 8 || lists[0].Properties[5].Name != "engineeringUnits" ||
+		lists[1].Properties[4].Value.Value != "readable" {
+		t.Fatalf("unexpected properties: %+v", lists)
+	}
+	// the OPC XML-DA types are not in the namespace of XML Schema
+	if lists[1].Properties[0].Value.Value != "xsd:int" || lists[2].Properties[0].Value.Value != "ns0:ArrayOfFloat" {
+		t.Errorf("unexpected dataTypes %v, %v", lists[1].Properties[0].Value.Value, lists[2].Properties[0].Value.Value)
+	}
+}
+
+func TestRegistrySubscription(t *testing.T) {
+	registry, config, s := newRegistryClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sub, err := s.StartSubscription(ctx, []gopcxmlda.TItem{{ItemName: "Plant1/Config/setpoint"}}, gopcxmlda.SubscriptionOptions{
+		PingRate:            1000,
+		ReturnValuesOnReply: true,
+		EventBuffer:         10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if event := <-sub.Events(); event.Type != gopcxmlda.EventItems || event.Items[0].Value.Value != 20.0 {
+		t.Fatalf("unexpected first event: %+v", event)
+	}
+	registry.Update(func() { config.Setpoint = 25 })
+	for event := range sub.Events() {
+		if event.Type == gopcxmlda.EventItems && event.Items[0].Value.Value == 25.0 {
+			return
+		}
+	}
+	t.Error("change not received")
+}
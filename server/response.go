@@ -0,0 +1,232 @@
+package server
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/dernate/gopcxmlda"
+)
+
+// The response types are the bodies of the responses in the wire format of OPC XML-DA.
+// The client side decodes them into the T* types of the gopcxmlda package.
+
+type replyBase struct {
+	RcvTime             time.Time `xml:"RcvTime,attr"`
+	ReplyTime           time.Time `xml:"ReplyTime,attr"`
+	ClientRequestHandle string    `xml:"ClientRequestHandle,attr,omitempty"`
+	RevisedLocaleID     string    `xml:"RevisedLocaleID,attr,omitempty"`
+	ServerState         string    `xml:"ServerState,attr"`
+}
+
+type opcError struct {
+	ID   string   `xml:"ID,attr"`
+	Text []string `xml:"Text,omitempty"`
+}
+
+type itemValue struct {
+	ItemPath         string            `xml:"ItemPath,attr,omitempty"`
+	ItemName         string            `xml:"ItemName,attr,omitempty"`
+	ClientItemHandle string            `xml:"ClientItemHandle,attr,omitempty"`
+	Timestamp        string            `xml:"Timestamp,attr,omitempty"`
+	ResultID         string            `xml:"ResultID,attr,omitempty"`
+	Value            *gopcxmlda.TValue `xml:"Value,omitempty"`
+	Quality          *quality          `xml:"Quality,omitempty"`
+}
+
+type quality struct {
+	QualityField string `xml:"QualityField,attr,omitempty"`
+	LimitField   string `xml:"LimitField,attr,omitempty"`
+	VendorField  string `xml:"VendorField,attr,omitempty"`
+}
+
+type itemList struct {
+	Items []itemValue `xml:"Items"`
+}
+
+type getStatusResponse struct {
+	XMLName xml.Name  `xml:"http://opcfoundation.org/webservices/XMLDA/1.0/ GetStatusResponse"`
+	Result  replyBase `xml:"GetStatusResult"`
+	Status  status    `xml:"Status"`
+}
+
+type status struct {
+	StartTime                  string   `xml:"StartTime,attr,omitempty"`
+	ProductVersion             string   `xml:"ProductVersion,attr,omitempty"`
+	StatusInfo                 string   `xml:"StatusInfo,omitempty"`
+	VendorInfo                 string   `xml:"VendorInfo,omitempty"`
+	SupportedLocaleIDs         []string `xml:"SupportedLocaleIDs"`
+	SupportedInterfaceVersions []string `xml:"SupportedInterfaceVersions"`
+}
+
+type readResponse struct {
+	XMLName  xml.Name   `xml:"http://opcfoundation.org/webservices/XMLDA/1.0/ ReadResponse"`
+	Result   replyBase  `xml:"ReadResult"`
+	ItemList itemList   `xml:"RItemList"`
+	Errors   []opcError `xml:"Errors"`
+}
+
+type writeResponse struct {
+	XMLName  xml.Name   `xml:"http://opcfoundation.org/webservices/XMLDA/1.0/ WriteResponse"`
+	Result   replyBase  `xml:"WriteResult"`
+	ItemList itemList   `xml:"RItemList"`
+	Errors   []opcError `xml:"Errors"`
+}
+
+type subscribeResponse struct {
+	XMLName         xml.Name          `xml:"http://opcfoundation.org/webservices/XMLDA/1.0/ SubscribeResponse"`
+	ServerSubHandle string            `xml:"ServerSubHandle,attr,omitempty"`
+	Result          replyBase         `xml:"SubscribeResult"`
+	ItemList        subscribeItemList `xml:"RItemList"`
+	Errors          []opcError        `xml:"Errors"`
+}
+
+type subscribeItemList struct {
+	RevisedSamplingRate int                  `xml:"RevisedSamplingRate,attr,omitempty"`
+	Items               []subscribeItemValue `xml:"Items"`
+}
+
+type subscribeItemValue struct {
+	RevisedSamplingRate int       `xml:"RevisedSamplingRate,attr,omitempty"`
+	ItemValue           itemValue `xml:"ItemValue"`
+}
+
+type subscriptionPolledRefreshResponse struct {
+	XMLName                 xml.Name             `xml:"http://opcfoundation.org/webservices/XMLDA/1.0/ SubscriptionPolledRefreshResponse"`
+	DataBufferOverflow      bool                 `xml:"DataBufferOverflow,attr,omitempty"`
+	Result                  replyBase            `xml:"SubscriptionPolledRefreshResult"`
+	InvalidServerSubHandles []string             `xml:"InvalidServerSubHandles"`
+	ItemLists               []subscribeRItemList `xml:"RItemList"`
+	Errors                  []opcError           `xml:"Errors"`
+}
+
+type subscribeRItemList struct {
+	SubscriptionHandle string      `xml:"SubscriptionHandle,attr,omitempty"`
+	Items              []itemValue `xml:"Items"`
+}
+
+type subscriptionCancelResponse struct {
+	XMLName             xml.Name `xml:"http://opcfoundation.org/webservices/XMLDA/1.0/ SubscriptionCancelResponse"`
+	ClientRequestHandle string   `xml:"ClientRequestHandle,attr,omitempty"`
+}
+
+type browseResponse struct {
+	XMLName           xml.Name                   `xml:"http://opcfoundation.org/webservices/XMLDA/1.0/ BrowseResponse"`
+	ContinuationPoint string                     `xml:"ContinuationPoint,attr,omitempty"`
+	MoreElements      bool                       `xml:"MoreElements,attr"`
+	Result            replyBase                  `xml:"BrowseResult"`
+	Elements          []gopcxmlda.TBrowseElement `xml:"Elements"`
+	Errors            []opcError                 `xml:"Errors"`
+}
+
+type getPropertiesResponse struct {
+	XMLName       xml.Name       `xml:"http://opcfoundation.org/webservices/XMLDA/1.0/ GetPropertiesResponse"`
+	Result        replyBase      `xml:"GetPropertiesResult"`
+	PropertyLists []propertyList `xml:"PropertyLists"`
+	Errors        []opcError     `xml:"Errors"`
+}
+
+type propertyList struct {
+	ItemPath   string     `xml:"ItemPath,attr,omitempty"`
+	ItemName   string     `xml:"ItemName,attr,omitempty"`
+	ResultID   string     `xml:"ResultID,attr,omitempty"`
+	Properties []property `xml:"Properties"`
+}
+
+type property struct {
+	Name        string            `xml:"Name,attr"`
+	Description string            `xml:"Description,attr,omitempty"`
+	ItemPath    string            `xml:"ItemPath,attr,omitempty"`
+	ItemName    string            `xml:"ItemName,attr,omitempty"`
+	Value       *gopcxmlda.TValue `xml:"Value,omitempty"`
+}
+
+// newItemValue converts the result of an item, the options control which attributes are returned.
+func newItemValue(item gopcxmlda.TItem, options gopcxmlda.TRequestOptions) itemValue {
+	v := itemValue{
+		ClientItemHandle: item.ClientItemHandle,
+		ResultID:         item.Error,
+	}
+	if options.ReturnItemName {
+		v.ItemName = item.ItemName
+	}
+	if options.ReturnItemPath {
+		v.ItemPath = item.ItemPath
+	}
+	if options.ReturnItemTime && !item.Timestamp.IsZero() {
+		v.Timestamp = item.Timestamp.Format(time.RFC3339Nano)
+	}
+	if item.Value.Value != nil {
+		value := item.Value
+		v.Value = &value
+	}
+	if item.Quality != (gopcxmlda.TQuality{}) {
+		v.Quality = &quality{
+			QualityField: item.Quality.QualityField,
+			LimitField:   item.Quality.LimitField,
+			VendorField:  item.Quality.VendorField,
+		}
+	}
+	return v
+}
+
+func newItemValues(items []gopcxmlda.TItem, options gopcxmlda.TRequestOptions) []itemValue {
+	values := make([]itemValue, len(items))
+	for i, item := range items {
+		values[i] = newItemValue(item, options)
+	}
+	return values
+}
+
+// newOpcErrors returns an OPCError for every distinct failure ResultID of items.
+// The error texts are only added with returnErrorText.
+func newOpcErrors(items []gopcxmlda.TItem, returnErrorText bool) []opcError {
+	var errs []opcError
+	seen := make(map[gopcxmlda.ResultCode]bool)
+	for _, item := range items {
+		if item.Error == "" {
+			continue
+		}
+		code := gopcxmlda.ParseResultCode(item.Error)
+		if code.IsSuccess() || seen[code] {
+			continue
+		}
+		seen[code] = true
+		e := opcError{ID: string(code)}
+		if returnErrorText {
+			e.Text = []string{errorText(code)}
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// errorTexts are the texts of the result codes sent with ReturnErrorText.
+var errorTexts = map[gopcxmlda.ResultCode]string{
+	gopcxmlda.ErrAccessDenied:             "The server denied access to the item.",
+	gopcxmlda.ErrBusy:                     "The server is busy.",
+	gopcxmlda.ErrFail:                     "Unspecified error.",
+	gopcxmlda.ErrInvalidContinuationPoint: "The continuation point is not valid.",
+	gopcxmlda.ErrInvalidFilter:            "The filter string is not valid.",
+	gopcxmlda.ErrInvalidHoldTime:          "The hold time is not valid.",
+	gopcxmlda.ErrInvalidItemName:          "The item name is not valid.",
+	gopcxmlda.ErrInvalidItemPath:          "The item path is not valid.",
+	gopcxmlda.ErrInvalidPID:               "The property is not valid for the item.",
+	gopcxmlda.ErrNoSubscription:           "The subscription does not exist.",
+	gopcxmlda.ErrNotSupported:             "The operation is not supported.",
+	gopcxmlda.ErrOutOfMemory:              "Not enough memory to complete the request.",
+	gopcxmlda.ErrRange:                    "The value is out of range.",
+	gopcxmlda.ErrReadOnly:                 "The item is read only.",
+	gopcxmlda.ErrServerState:              "The operation is not possible in the current server state.",
+	gopcxmlda.ErrTimedOut:                 "The operation timed out.",
+	gopcxmlda.ErrUnknownItemName:          "The item name is not known.",
+	gopcxmlda.ErrUnknownItemPath:          "The item path is not known.",
+	gopcxmlda.ErrWriteOnly:                "The item is write only.",
+	gopcxmlda.ErrBadType:                  "The value has not the type of the item.",
+}
+
+func errorText(code gopcxmlda.ResultCode) string {
+	if text, ok := errorTexts[code]; ok {
+		return text
+	}
+	return string(code)
+}
@@ -0,0 +1,81 @@
+package gopcxmlda
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewServer(t *testing.T) {
+	s := NewServer(nil, "en-US")
+	if s.Timeout != DefaultTimeout || s.HttpClient != nil {
+		t.Fatalf("unexpected defaults: %+v", s)
+	}
+	transport := &http.Transport{}
+	s = NewServer(nil, "en-US", WithTimeout(time.Second), WithTransport(transport))
+	if s.HttpClient == nil || s.HttpClient.Transport != transport || s.HttpClient.Timeout != time.Second {
+		t.Fatalf("transport not applied: %+v", s.HttpClient)
+	}
+	client := &http.Client{}
+	s = NewServer(nil, "en-US", WithHttpClient(client), WithTransport(transport))
+	if s.HttpClient != client {
+		t.Fatal("http client not applied")
+	}
+}
+
+func TestServerConcurrentUse(t *testing.T) {
+	ts := newUnstartedTestServer(func(action string, body string) string {
+		return soapEnvelope(testGetStatusResponse)
+	})
+	var newConns atomic.Int32
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	ts.Start()
+	s := serverFor(t, ts)
+	s.Timeout = 0
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var ClientRequestHandle string
+			status, err := s.GetStatus(context.Background(), &ClientRequestHandle, "")
+			if err == nil && status.Response.Result.ServerState != "running" {
+				err = fmt.Errorf("unexpected server state %q", status.Response.Result.ServerState)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Timeout != 0 {
+		t.Fatalf("Server was modified: Timeout = %v", s.Timeout)
+	}
+
+	// sequential requests reuse an idle connection
+	before := newConns.Load()
+	for i := 0; i < 5; i++ {
+		var ClientRequestHandle string
+		if _, err := s.GetStatus(context.Background(), &ClientRequestHandle, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if opened := newConns.Load() - before; opened != 0 {
+		t.Fatalf("expected connections to be reused, %d new connections opened", opened)
+	}
+}
@@ -0,0 +1,383 @@
+package gopcxmlda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultSubscriptionPingRate is the SubscriptionPingRate in milliseconds of a Subscription without PingRate.
+const DefaultSubscriptionPingRate = 2000
+
+// SubscriptionEventType is the type of a SubscriptionEvent.
+type SubscriptionEventType int
+
+const (
+	// EventItems delivers changed items, either the initial values or the items of a polled refresh.
+	EventItems SubscriptionEventType = iota
+	// EventError delivers an error of a polled refresh. The subscription keeps running.
+	EventError
+	// EventDataBufferOverflow signals that the server dropped buffered values of the subscription.
+	EventDataBufferOverflow
+	// EventResubscribed signals that the subscription was lost and subscribed again. Err is the
+	// reason it was lost, Gap the time since the last successful refresh, changes within the gap are missing.
+	EventResubscribed
+)
+
+// SubscriptionEvent is delivered by a Subscription for every change, error and buffer overflow.
+type SubscriptionEvent struct {
+	Type  SubscriptionEventType
+	Items []TItem       // changed items for EventItems
+	Err   error         // error for EventError, reason for EventResubscribed
+	Gap   time.Duration // time without refreshes for EventResubscribed
+	Time  time.Time     // reply time of the server, if known
+}
+
+// Backoff defines the delays between the attempts to subscribe again.
+// The first attempt is made after Initial, each following delay is multiplied by Multiplier up to Max.
+type Backoff struct {
+	Initial    time.Duration // 1 second if zero
+	Max        time.Duration // 1 minute if zero
+	Multiplier float64       // 2 if zero
+}
+
+// delay returns the delay before the given attempt, starting at 0.
+func (b Backoff) delay(attempt int) time.Duration {
+	if b.Initial == 0 {
+		b.Initial = time.Second
+	}
+	if b.Max == 0 {
+		b.Max = time.Minute
+	}
+	if b.Multiplier == 0 {
+		b.Multiplier = 2
+	}
+	d := float64(b.Initial)
+	for i := 0; i < attempt && d < float64(b.Max); i++ {
+		d *= b.Multiplier
+	}
+	return min(time.Duration(d), b.Max)
+}
+
+// SubscriptionOptions configures a Subscription created by StartSubscription.
+type SubscriptionOptions struct {
+	Namespace           string          // namespace of the requests, "ns0" if empty
+	PingRate            uint            // SubscriptionPingRate and refresh interval in milliseconds, DefaultSubscriptionPingRate if zero
+	ReturnValuesOnReply bool            // deliver the current values of all items as first event
+	Options             TRequestOptions // options of the Subscribe and SubscriptionPolledRefresh requests
+	ClientItemHandles   []string        // client item handles of the items, generated if empty
+	RetryInterval       time.Duration   // wait time after a failed refresh, PingRate if zero
+	EventBuffer         int             // capacity of the Events channel
+	// Resubscribe subscribes the items again with their original client item handles, if the server
+	// reports invalid ServerSubHandles, a ServerState other than running, or, with StatusInterval,
+	// a new StartTime. Otherwise the subscription ends on invalid ServerSubHandles.
+	Resubscribe    bool
+	StatusInterval time.Duration // interval of GetStatus requests to detect server restarts, disabled if zero
+	Backoff        Backoff       // delays between the attempts to subscribe again
+	// Handler is called for every event, instead of delivering it on the Events channel.
+	// It is called from the refresh loop, so the next refresh waits until it returns.
+	Handler func(SubscriptionEvent)
+}
+
+// Subscription is a subscription on the server which is kept alive by a polled refresh loop.
+// Changes are delivered on the Events channel or to the Handler of the SubscriptionOptions.
+type Subscription struct {
+	server            *Server
+	options           SubscriptionOptions
+	items             []TItem
+	clientItemHandles []string
+	events            chan SubscriptionEvent
+	cancel            context.CancelFunc
+	done              chan struct{}
+
+	mu              sync.Mutex
+	serverSubHandle string
+	err             error
+}
+
+// StartSubscription subscribes the items and starts a loop calling SubscriptionPolledRefresh,
+// until ctx is canceled or Close is called. The subscription is then canceled on the server.
+//
+// Parameters:
+// - ctx (context.Context): The context of the subscription, canceling it ends the subscription.
+// - items ([]TItem): The items to subscribe to.
+// - options (SubscriptionOptions): The options of the subscription.
+//
+// Returns:
+// - (*Subscription): The running subscription.
+// - (error): An error if the items could not be subscribed.
+//
+// Example:
+//
+//	_url, _ := url.Parse("http://opc-addr-or-IP.local:8080")
+//	s := NewServer(_url, "en-US")
+//	sub, err := s.StartSubscription(ctx, []TItem{{ItemName: "My/Item"}}, SubscriptionOptions{PingRate: 1000})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	for event := range sub.Events() {
+//		// do something with the event
+//	}
+func (s *Server) StartSubscription(ctx context.Context, items []TItem, options SubscriptionOptions) (*Subscription, error) {
+	if options.PingRate == 0 {
+		options.PingRate = DefaultSubscriptionPingRate
+	}
+	if options.RetryInterval == 0 {
+		options.RetryInterval = time.Duration(options.PingRate) * time.Millisecond
+	}
+	sub := &Subscription{
+		server:            s,
+		options:           options,
+		items:             append([]TItem(nil), items...),
+		clientItemHandles: append([]string(nil), options.ClientItemHandles...),
+		done:              make(chan struct{}),
+	}
+	if options.Handler == nil {
+		sub.events = make(chan SubscriptionEvent, options.EventBuffer)
+	}
+
+	response, err := sub.subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, sub.cancel = context.WithCancel(ctx)
+	go sub.run(ctx, response)
+	return sub, nil
+}
+
+// Events returns the channel the events are delivered on. It is closed when the subscription ends.
+// It returns nil if a Handler is set.
+func (sub *Subscription) Events() <-chan SubscriptionEvent {
+	return sub.events
+}
+
+// Done returns a channel which is closed when the subscription has ended.
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+// ServerSubHandle returns the current handle of the subscription on the server.
+func (sub *Subscription) ServerSubHandle() string {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.serverSubHandle
+}
+
+// Err returns the error which ended the subscription, if any. It is valid after Done is closed.
+func (sub *Subscription) Err() error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.err
+}
+
+// Close ends the subscription, cancels it on the server and waits for the refresh loop to finish.
+func (sub *Subscription) Close() error {
+	sub.cancel()
+	<-sub.done
+	return sub.Err()
+}
+
+// subscribe sends the Subscribe request and stores the ServerSubHandle.
+func (sub *Subscription) subscribe(ctx context.Context) (TSubscribe, error) {
+	var ClientRequestHandle string
+	response, err := sub.server.Subscribe(ctx, sub.items, &ClientRequestHandle, &sub.clientItemHandles,
+		sub.options.Namespace, sub.options.ReturnValuesOnReply, sub.options.PingRate, sub.options.Options)
+	if err != nil {
+		return response, err
+	}
+	if response.Response.ServerSubHandle == "" {
+		return response, ErrNoSubscription
+	}
+	sub.mu.Lock()
+	sub.serverSubHandle = response.Response.ServerSubHandle
+	sub.mu.Unlock()
+	return response, nil
+}
+
+func (sub *Subscription) run(ctx context.Context, response TSubscribe) {
+	defer close(sub.done)
+	if sub.events != nil {
+		defer close(sub.events)
+	}
+
+	sub.emitValuesOnReply(ctx, response)
+	serverTime := serverTimeOf(response.Response.Result.ReplyTime)
+	lastRefresh := time.Now()
+	var startTime string
+	var lastStatus time.Time
+	for ctx.Err() == nil {
+		var lost error
+		if sub.options.Resubscribe && sub.options.StatusInterval > 0 && time.Since(lastStatus) >= sub.options.StatusInterval {
+			lastStatus = time.Now()
+			lost = sub.checkStatus(ctx, &startTime)
+		}
+		if lost == nil {
+			var ok bool
+			ok, lost = sub.refresh(ctx, &serverTime)
+			if ok {
+				lastRefresh = time.Now()
+			}
+		}
+		if lost == nil || ctx.Err() != nil {
+			continue
+		}
+		if !sub.options.Resubscribe {
+			sub.mu.Lock()
+			sub.err = lost
+			sub.mu.Unlock()
+			return
+		}
+
+		response, ok := sub.resubscribe(ctx, lost)
+		if !ok {
+			break
+		}
+		sub.emit(ctx, SubscriptionEvent{
+			Type: EventResubscribed,
+			Err:  lost,
+			Gap:  time.Since(lastRefresh),
+			Time: response.Response.Result.ReplyTime,
+		})
+		sub.emitValuesOnReply(ctx, response)
+		serverTime = serverTimeOf(response.Response.Result.ReplyTime)
+		lastRefresh = time.Now()
+	}
+
+	// the subscription context is canceled, the cancel request gets its own
+	if err := sub.cancelOnServer(ctx); err != nil {
+		sub.mu.Lock()
+		sub.err = err
+		sub.mu.Unlock()
+	}
+}
+
+// refresh sends one SubscriptionPolledRefresh and delivers its events. ok is true if a response
+// was received. lost is set if the subscription is no longer valid on the server.
+func (sub *Subscription) refresh(ctx context.Context, serverTime *TServerTime) (ok bool, lost error) {
+	var ClientRequestHandle string
+	refresh, err := sub.server.SubscriptionPolledRefresh(ctx, sub.ServerSubHandle(), sub.options.PingRate,
+		sub.options.Namespace, &ClientRequestHandle, sub.options.Options, *serverTime)
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	replyTime := refresh.Response.Result.ReplyTime
+	if err != nil {
+		sub.emit(ctx, SubscriptionEvent{Type: EventError, Err: err, Time: replyTime})
+		if errors.Is(err, ErrNoSubscription) {
+			return false, err
+		}
+		if replyTime.IsZero() {
+			// no response received, wait before the next attempt
+			select {
+			case <-ctx.Done():
+			case <-time.After(sub.options.RetryInterval):
+			}
+			*serverTime = TServerTime{UseClientTime: true}
+			return false, nil
+		}
+	}
+	*serverTime = serverTimeOf(replyTime)
+	if state := refresh.Response.Result.ServerState; sub.options.Resubscribe && state != "" && state != "running" {
+		return true, fmt.Errorf("server state %s: %w", state, ErrServerState)
+	}
+
+	if refresh.Response.DataBufferOverflow {
+		sub.emit(ctx, SubscriptionEvent{Type: EventDataBufferOverflow, Time: replyTime})
+	}
+	if len(refresh.Response.ItemList.Items) > 0 {
+		sub.emit(ctx, SubscriptionEvent{Type: EventItems, Items: refresh.Response.ItemList.Items, Time: replyTime})
+	}
+	return true, nil
+}
+
+// checkStatus requests the status of the server and reports the subscription as lost,
+// if the server is not running or was restarted since the last check.
+func (sub *Subscription) checkStatus(ctx context.Context, startTime *string) error {
+	var ClientRequestHandle string
+	status, err := sub.server.GetStatus(ctx, &ClientRequestHandle, sub.options.Namespace)
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		sub.emit(ctx, SubscriptionEvent{Type: EventError, Err: err})
+		return nil
+	}
+	if state := status.Response.Result.ServerState; state != "" && state != "running" {
+		return fmt.Errorf("server state %s: %w", state, ErrServerState)
+	}
+	previous := *startTime
+	*startTime = status.Response.Status.StartTime
+	if previous != "" && previous != *startTime {
+		return fmt.Errorf("server restarted at %s: %w", *startTime, ErrNoSubscription)
+	}
+	return nil
+}
+
+// resubscribe subscribes the original items with their client item handles again, retrying with
+// the configured backoff. It returns false if ctx was canceled before it succeeded.
+func (sub *Subscription) resubscribe(ctx context.Context, cause error) (TSubscribe, bool) {
+	if !errors.Is(cause, ErrNoSubscription) {
+		// the server may still know the subscription
+		_ = sub.cancelOnServer(ctx)
+	}
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return TSubscribe{}, false
+		case <-time.After(sub.options.Backoff.delay(attempt)):
+		}
+		response, err := sub.subscribe(ctx)
+		if ctx.Err() != nil {
+			return TSubscribe{}, false
+		}
+		if err == nil {
+			return response, true
+		}
+		sub.emit(ctx, SubscriptionEvent{Type: EventError, Err: err})
+	}
+}
+
+// cancelOnServer cancels the subscription on the server, even if ctx is already canceled.
+func (sub *Subscription) cancelOnServer(ctx context.Context) error {
+	cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sub.server.timeout())
+	defer cancel()
+	var ClientRequestHandle string
+	_, err := sub.server.SubscriptionCancel(cancelCtx, sub.ServerSubHandle(), sub.options.Namespace, &ClientRequestHandle)
+	return err
+}
+
+// emitValuesOnReply delivers the values of a Subscribe response, if requested.
+func (sub *Subscription) emitValuesOnReply(ctx context.Context, response TSubscribe) {
+	if !sub.options.ReturnValuesOnReply || len(response.Response.ItemList.Items) == 0 {
+		return
+	}
+	items := make([]TItem, len(response.Response.ItemList.Items))
+	for i, item := range response.Response.ItemList.Items {
+		items[i] = item.ItemValue
+	}
+	sub.emit(ctx, SubscriptionEvent{Type: EventItems, Items: items, Time: response.Response.Result.ReplyTime})
+}
+
+// emit delivers an event to the Handler or the Events channel.
+func (sub *Subscription) emit(ctx context.Context, event SubscriptionEvent) {
+	if sub.options.Handler != nil {
+		sub.options.Handler(event)
+		return
+	}
+	select {
+	case sub.events <- event:
+	case <-ctx.Done():
+	}
+}
+
+// serverTimeOf returns the TServerTime for the next refresh, falling back to the client time
+// if the server did not send a reply time.
+func serverTimeOf(replyTime time.Time) TServerTime {
+	if replyTime.IsZero() {
+		return TServerTime{UseClientTime: true}
+	}
+	return TServerTime{ServerTime: replyTime}
+}
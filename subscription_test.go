@@ -0,0 +1,187 @@
+package gopcxmlda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscription(t *testing.T) {
+	var mu sync.Mutex
+	refreshes := 0
+	canceled := ""
+	s, _ := newTestServer(t, func(action string, body string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		switch action {
+		case "Subscribe":
+			return soapEnvelope(fmt.Sprintf(testSubscribeResponse, "sub1"))
+		case "SubscriptionPolledRefresh":
+			refreshes++
+			time.Sleep(5 * time.Millisecond)
+			return soapEnvelope(fmt.Sprintf(testRefreshResponse, refreshes == 2, "sub1", refreshes))
+		case "SubscriptionCancel":
+			if i := strings.Index(body, `ServerSubHandle="`); i >= 0 {
+				canceled = strings.SplitN(body[i+len(`ServerSubHandle="`):], `"`, 2)[0]
+			}
+			return soapEnvelope(testSubscriptionCancelResponse)
+		}
+		return ""
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := s.StartSubscription(ctx, []TItem{{ItemName: "My/Item"}}, SubscriptionOptions{
+		PingRate:            100,
+		ReturnValuesOnReply: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ServerSubHandle() != "sub1" {
+		t.Fatalf("unexpected ServerSubHandle %q", sub.ServerSubHandle())
+	}
+
+	var values []interface{}
+	overflow := false
+	for event := range sub.Events() {
+		switch event.Type {
+		case EventItems:
+			values = append(values, event.Items[0].Value.Value)
+		case EventDataBufferOverflow:
+			overflow = true
+		case EventError:
+			t.Fatal(event.Err)
+		}
+		if len(values) == 4 {
+			cancel()
+		}
+	}
+	<-sub.Done()
+	if sub.Err() != nil {
+		t.Fatal(sub.Err())
+	}
+	if fmt.Sprint(values[:4]) != "[0 1 2 3]" {
+		t.Fatalf("unexpected values %v", values)
+	}
+	if !overflow {
+		t.Fatal("expected DataBufferOverflow event")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if canceled != "sub1" {
+		t.Fatalf("subscription not canceled on the server: %q", canceled)
+	}
+}
+
+func TestSubscriptionInvalidHandle(t *testing.T) {
+	s, _ := newTestServer(t, func(action string, body string) string {
+		switch action {
+		case "Subscribe":
+			return soapEnvelope(fmt.Sprintf(testSubscribeResponse, "sub1"))
+		case "SubscriptionPolledRefresh":
+			return soapEnvelope(fmt.Sprintf(testInvalidHandleResponse, "sub1"))
+		}
+		return soapEnvelope(testSubscriptionCancelResponse)
+	})
+	var events []SubscriptionEvent
+	sub, err := s.StartSubscription(context.Background(), []TItem{{ItemName: "My/Item"}}, SubscriptionOptions{
+		Handler: func(event SubscriptionEvent) {
+			events = append(events, event)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-sub.Done()
+	if !errors.Is(sub.Err(), ErrNoSubscription) {
+		t.Fatalf("expected ErrNoSubscription, got %v", sub.Err())
+	}
+	if len(events) != 1 || events[0].Type != EventError {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
+
+func TestSubscriptionResubscribe(t *testing.T) {
+	var mu sync.Mutex
+	subscribes := 0
+	refreshes := 0
+	startTime := "2024-01-01T00:00:00Z"
+	s, _ := newTestServer(t, func(action string, body string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		handle := fmt.Sprintf("sub%d", subscribes)
+		switch action {
+		case "GetStatus":
+			return soapEnvelope(strings.Replace(testGetStatusResponse, `StartTime="2024-01-01T00:00:00Z"`,
+				`StartTime="`+startTime+`"`, 1))
+		case "Subscribe":
+			if !strings.Contains(body, `ClientItemHandle="my-handle"`) {
+				return soapEnvelope(`<soap:Fault><faultcode>E_FAIL</faultcode></soap:Fault>`)
+			}
+			subscribes++
+			return soapEnvelope(fmt.Sprintf(testSubscribeResponse, fmt.Sprintf("sub%d", subscribes)))
+		case "SubscriptionPolledRefresh":
+			refreshes++
+			time.Sleep(5 * time.Millisecond)
+			switch refreshes {
+			case 2:
+				// server lost the subscription
+				return soapEnvelope(fmt.Sprintf(testInvalidHandleResponse, handle))
+			case 4:
+				// server was restarted, detected by GetStatus
+				startTime = "2024-01-02T00:00:00Z"
+			}
+			return soapEnvelope(fmt.Sprintf(testRefreshResponse, false, handle, refreshes))
+		}
+		return soapEnvelope(testSubscriptionCancelResponse)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := s.StartSubscription(ctx, []TItem{{ItemName: "My/Item"}}, SubscriptionOptions{
+		ClientItemHandles: []string{"my-handle"},
+		Resubscribe:       true,
+		StatusInterval:    time.Millisecond,
+		Backoff:           Backoff{Initial: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resubscribed []SubscriptionEvent
+	for event := range sub.Events() {
+		if event.Type == EventResubscribed {
+			resubscribed = append(resubscribed, event)
+			if len(resubscribed) == 2 {
+				cancel()
+			}
+		}
+	}
+	if len(resubscribed) != 2 {
+		t.Fatalf("expected two resubscriptions, got %d", len(resubscribed))
+	}
+	if !errors.Is(resubscribed[0].Err, ErrNoSubscription) || resubscribed[0].Gap <= 0 {
+		t.Fatalf("unexpected first resubscription %+v", resubscribed[0])
+	}
+	if !strings.Contains(resubscribed[1].Err.Error(), "restarted") {
+		t.Fatalf("expected restart to be detected, got %v", resubscribed[1].Err)
+	}
+	if sub.ServerSubHandle() != "sub3" {
+		t.Fatalf("unexpected ServerSubHandle %q", sub.ServerSubHandle())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 5 * time.Second}
+	var delays []time.Duration
+	for attempt := 0; attempt < 5; attempt++ {
+		delays = append(delays, b.delay(attempt))
+	}
+	if fmt.Sprint(delays) != "[1s 2s 4s 5s 5s]" {
+		t.Fatalf("unexpected delays %v", delays)
+	}
+}
@@ -1,15 +1,23 @@
 package gopcxmlda
 
 import (
+	"net/http"
 	"net/url"
 	"time"
 )
 
 // Server represents a server connection with address, port, locale ID, and timeout.
+// A Server is safe for concurrent use by multiple goroutines as long as its fields
+// are not modified after the first request.
 type Server struct {
-	Url      *url.URL      // URL of the server
-	LocaleID string        // Locale ID of the server
-	Timeout  time.Duration // Timeout duration for the connection
+	Url             *url.URL         // URL of the server
+	LocaleID        string           // Locale ID of the server
+	Timeout         time.Duration    // Timeout duration for the connection, DefaultTimeout if zero
+	HttpClient      *http.Client     // HTTP client used for all requests, a pooled default client if nil
+	Authenticator   Authenticator    // sets the credentials of all requests if not nil
+	HeaderProviders []HeaderProvider // add elements to the SOAP header of all requests
+
+	validator *writeValidator // validates writes if created WithWriteValidation
 }
 
 type TBaseResult struct {
@@ -80,7 +88,7 @@ type TItem struct {
 type TValue struct {
 	Type      string `xml:"type,attr"` // Can be set manually to force a specific type
 	Value     interface{}
-	Namespace string
+	Namespace string // prefix of the OPC XML-DA types like ArrayOfInt, "ns0" if empty
 }
 
 // TQuality represents the structure for the quality of an item.
@@ -161,7 +169,7 @@ type TSubscribeItemValue struct {
 
 type TSubscriptionCancel struct {
 	TBodyBase
-	Response TResponseSC `xml:"Body>https://opcfoundation.org/webservices/XMLDA/1.0/ SubscriptionCancelResponse"`
+	Response TResponseSC `xml:"Body>SubscriptionCancelResponse"`
 }
 
 type TResponseSC struct {
@@ -238,3 +246,19 @@ type TPropertyOptions struct {
 	ReturnPropertyValues bool
 	ReturnErrorText      bool
 }
+
+// TRequestOptions represents the RequestOptions of a Read, Write, Subscribe or
+// SubscriptionPolledRefresh request. ClientRequestHandle and LocaleID are taken
+// from the request and the Server if left empty. Servers return error texts by default, so
+// ReturnErrorText is only sent if ReturnErrorText or OmitErrorText is set.
+type TRequestOptions struct {
+	ReturnErrorText      bool      `xml:"ReturnErrorText,attr"`
+	OmitErrorText        bool      `xml:"-"` // sends ReturnErrorText="false" unless ReturnErrorText is set
+	ReturnDiagnosticInfo bool      `xml:"ReturnDiagnosticInfo,attr"`
+	ReturnItemTime       bool      `xml:"ReturnItemTime,attr"`
+	ReturnItemPath       bool      `xml:"ReturnItemPath,attr"`
+	ReturnItemName       bool      `xml:"ReturnItemName,attr"`
+	RequestDeadline      time.Time `xml:"RequestDeadline,attr,omitempty"`
+	ClientRequestHandle  string    `xml:"ClientRequestHandle,attr,omitempty"`
+	LocaleID             string    `xml:"LocaleID,attr,omitempty"`
+}
@@ -1,9 +1,13 @@
 package gopcxmlda
 
 import (
+	"cmp"
 	"encoding/xml"
 	"fmt"
+	"math/big"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -13,91 +17,83 @@ import (
 // either single or array values.
 // Array values are handled by the decodeArrayOf function, whereas single values
 // are handled by the switch statement that handles the different types.
+// A value with xsi:nil is decoded as nil, a value without xsi:type as string.
 func (v *TValue) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	split := strings.Split(start.Attr[0].Value, ":")
-	v.Namespace = split[0]
-	v.Type = split[1]
-	switch v.Type {
-	case "string", "base64Binary", "QName":
-		var data string
-		if err := d.DecodeElement(&data, &start); err != nil {
-			return err
-		}
-		v.Value = data
-	case "boolean":
-		var data bool
-		if err := d.DecodeElement(&data, &start); err != nil {
-			return err
-		}
-		v.Value = data
-	case "dateTime", "time", "date", "duration":
-		var data time.Time
-		if err := d.DecodeElement(&data, &start); err != nil {
-			return err
-		}
-		v.Value = data
-	case "int":
-		var data int
-		if err := d.DecodeElement(&data, &start); err != nil {
-			return err
-		}
-		v.Value = data
-	case "long":
-		var data int64
-		if err := d.DecodeElement(&data, &start); err != nil {
-			return err
-		}
-		v.Value = data
-	case "unsignedLong", "unsignedInt":
-		var data uint64
-		if err := d.DecodeElement(&data, &start); err != nil {
-			return err
+	if xsiType, ok := xsiAttr(start, "type"); ok {
+		if namespace, valueType, ok := strings.Cut(xsiType, ":"); ok {
+			v.Namespace, v.Type = namespace, valueType
+		} else {
+			v.Type = xsiType
 		}
-		v.Value = data
-	case "short", "byte":
-		var data int16
+	}
+	if isNil, _ := xsiAttr(start, "nil"); isNil == "true" || isNil == "1" {
+		v.Value = nil
+		return d.Skip()
+	}
+	switch {
+	case v.Type == "OPCQuality": // used at GetProperties
+		var data TQuality
 		if err := d.DecodeElement(&data, &start); err != nil {
 			return err
 		}
 		v.Value = data
-	case "unsignedShort", "unsignedByte":
-		var data uint16
-		if err := d.DecodeElement(&data, &start); err != nil {
+	case strings.HasPrefix(v.Type, "ArrayOf"):
+		return v.decodeArrayOf(d, &start)
+	default:
+		var text string
+		if err := d.DecodeElement(&text, &start); err != nil {
 			return err
 		}
-		v.Value = data
-	case "float":
-		var data float32
-		if err := d.DecodeElement(&data, &start); err != nil {
-			return err
+		if v.Type == "" {
+			v.Value = text
+			return nil
 		}
-		v.Value = data
-	case "double", "decimal":
-		var data float64
-		if err := d.DecodeElement(&data, &start); err != nil {
+		value, err := parseXsd(text, v.Type)
+		if err != nil {
 			return err
 		}
-		v.Value = data
-	case "OPCQuality": // used at GetProperties
-		var data TQuality
-		if err := d.DecodeElement(&data, &start); err != nil {
-			return err
-		}
-		v.Value = data
-	default:
-		switch v.Type {
-		case "ArrayOfString", "ArrayOfBoolean", "ArrayOfDateTime", "ArrayOfLong", "ArrayOfInt", "ArrayOfUnsignedLong", "ArrayOfUnsignedInt", "ArrayOfShort", "ArrayOfByte", "ArrayOfUnsignedShort", "ArrayOfUnsignedByte", "ArrayOfFloat", "ArrayOfDouble", "ArrayOfDecimal":
-			return v.decodeArrayOf(d, &start)
-		default:
-			return fmt.Errorf("unknown type: %s", v.Type)
-		}
+		v.Value = singleValue(value, text)
 	}
 	return nil
 }
 
+// singleValue returns a single value parsed by parseXsd with the Go type single values were always
+// decoded as: uint64 for unsignedInt, int16 for byte, uint16 for unsignedByte and the base64 text
+// for base64Binary. The elements of arrays keep the types of parseXsd.
+func singleValue(value interface{}, text string) interface{} {
+	switch value := value.(type) {
+	case uint:
+		return uint64(value)
+	case int8:
+		return int16(value)
+	case uint8:
+		return uint16(value)
+	case []byte:
+		return text
+	}
+	return value
+}
+
+// xsiAttr returns the value of the attribute of the XML Schema instance namespace with the given name.
+// The prefix xsi is accepted without declaration.
+func xsiAttr(start xml.StartElement, name string) (string, bool) {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name && (attr.Name.Space == xsiNamespace || attr.Name.Space == "xsi") {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
 // Helper function to decode array values into a TValue struct.
+// The elements of ArrayOfAnyType are decoded as TValue with their own xsi:type, elements with
+// xsi:nil as nil.
 func (v *TValue) decodeArrayOf(d *xml.Decoder, start *xml.StartElement) error {
-	var tempSlice []interface{}
+	elemType := arrayElementType(v.Type)
+	if elemType == "" {
+		return fmt.Errorf("unknown type: %s", v.Type)
+	}
+	tempSlice := []interface{}{}
 	for {
 		var t xml.Token
 		var err error
@@ -107,109 +103,28 @@ func (v *TValue) decodeArrayOf(d *xml.Decoder, start *xml.StartElement) error {
 
 		switch se := t.(type) {
 		case xml.StartElement:
-			var value interface{}
-
-			switch v.Type {
-			case "ArrayOfString", "ArrayOfQName":
-				var s string
-				err := d.DecodeElement(&s, &se)
-				if err != nil {
-					return err
-				}
-				value = s
-			case "ArrayOfBoolean":
-				var b bool
-				err := d.DecodeElement(&b, &se)
-				if err != nil {
-					return err
-				}
-				value = b
-			case "ArrayOfDateTime":
-				var t time.Time
-				err := d.DecodeElement(&t, &se)
-				if err != nil {
-					return err
-				}
-				value = t
-			case "ArrayOfLong":
-				var l int64
-				err := d.DecodeElement(&l, &se)
-				if err != nil {
-					return err
-				}
-				value = l
-			case "ArrayOfInt":
-				var i int
-				err := d.DecodeElement(&i, &se)
-				if err != nil {
-					return err
-				}
-				value = i
-			case "ArrayOfUnsignedLong":
-				var l uint64
-				err := d.DecodeElement(&l, &se)
-				if err != nil {
-					return err
-				}
-				value = l
-			case "ArrayOfUnsignedInt":
-				var i uint
-				err := d.DecodeElement(&i, &se)
-				if err != nil {
-					return err
-				}
-				value = i
-			case "ArrayOfShort":
-				var s int16
-				err := d.DecodeElement(&s, &se)
-				if err != nil {
-					return err
-				}
-				value = s
-			case "ArrayOfByte":
-				var b int8
-				err := d.DecodeElement(&b, &se)
-				if err != nil {
-					return err
-				}
-				value = b
-			case "ArrayOfUnsignedShort":
-				var s uint16
-				err := d.DecodeElement(&s, &se)
-				if err != nil {
-					return err
-				}
-				value = s
-			case "ArrayOfUnsignedByte":
-				var b uint8
-				err := d.DecodeElement(&b, &se)
-				if err != nil {
-					return err
-				}
-				value = b
-			case "ArrayOfFloat":
-				var f float32
-				err := d.DecodeElement(&f, &se)
-				if err != nil {
-					return err
-				}
-				value = f
-			case "ArrayOfDouble":
-				var db float64
-				err := d.DecodeElement(&db, &se)
-				if err != nil {
+			if isNil, _ := xsiAttr(se, "nil"); isNil == "true" || isNil == "1" {
+				if err := d.Skip(); err != nil {
 					return err
 				}
-				value = db
-			case "ArrayOfDecimal":
-				var dc float64
-				err := d.DecodeElement(&dc, &se)
-				if err != nil {
+				tempSlice = append(tempSlice, nil)
+				continue
+			}
+			if elemType == "anyType" {
+				var elem TValue
+				if err := d.DecodeElement(&elem, &se); err != nil {
 					return err
 				}
-				value = dc
-			default:
-				return fmt.Errorf("unknown type: %s", v.Type)
+				tempSlice = append(tempSlice, elem.Value)
+				continue
+			}
+			var text string
+			if err := d.DecodeElement(&text, &se); err != nil {
+				return err
+			}
+			value, err := parseXsd(text, elemType)
+			if err != nil {
+				return err
 			}
 			tempSlice = append(tempSlice, value)
 		case xml.EndElement:
@@ -222,14 +137,112 @@ func (v *TValue) decodeArrayOf(d *xml.Decoder, start *xml.StartElement) error {
 	return fmt.Errorf("error in decoding ArrayOf* types")
 }
 
-func valueIsArrayOrSlice(value interface{}) bool {
-	valueType := reflect.TypeOf(value)
+// MarshalXML encodes the value with its xsi:type, the counterpart of UnmarshalXML.
+// If Type is empty or anyType, it is derived from the Go type of Value. The XML Schema types have the
+// prefix "xsd", the types of OPC XML-DA like ArrayOfFloat the prefix Namespace, or "ns0" if it is empty.
+// The prefixes have to be declared by the enclosing document.
+// A nil Value is encoded with xsi:nil, as are nil elements of ArrayOfAnyType.
+func (v TValue) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if v.Value == nil {
+		start.Attr = append([]xml.Attr{{Name: xml.Name{Local: "xsi:nil"}, Value: "true"}}, start.Attr...)
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	}
+	valueType := v.Type
+	if valueType == "" || valueType == "anyType" {
+		var err error
+		if valueType, err = getOpcXmlDaType(v.Value); err != nil {
+			return err
+		}
+	}
+	// UnmarshalXML expects the type as first attribute
+	start.Attr = append([]xml.Attr{{Name: xml.Name{Local: "xsi:type"}, Value: QualifiedType(valueType, v.Namespace)}}, start.Attr...)
 
-	if valueType.Kind() == reflect.Slice || valueType.Kind() == reflect.Array {
-		return true
-	} else {
-		return false
+	if quality, ok := v.Value.(TQuality); ok {
+		return e.EncodeElement(quality, start)
+	}
+	elementType := arrayElementType(valueType)
+	if elementType == "" {
+		text, err := formatXsd(v.Value, valueType)
+		if err != nil {
+			return err
+		}
+		return e.EncodeElement(text, start)
+	}
+	vo := reflect.ValueOf(v.Value)
+	if vo.Kind() != reflect.Slice && vo.Kind() != reflect.Array {
+		return fmt.Errorf("%s: value of type %T is no array", valueType, v.Value)
 	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for i := 0; i < vo.Len(); i++ {
+		element := xml.StartElement{Name: xml.Name{Local: elementType}}
+		if elementType == "anyType" {
+			// the elements use the prefix of the array for their types
+			elem := TValue{Value: vo.Index(i).Interface(), Namespace: v.Namespace}
+			if err := e.EncodeElement(elem, element); err != nil {
+				return fmt.Errorf("%s element %d: %w", valueType, i, err)
+			}
+			continue
+		}
+		text, err := formatXsd(vo.Index(i).Interface(), elementType)
+		if err != nil {
+			return fmt.Errorf("%s element %d: %w", valueType, i, err)
+		}
+		if err := e.EncodeElement(text, element); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// MarshalXML encodes the options as attributes in the order of the RequestOptions type of the
+// specification. ReturnErrorText is sent as true if it is set, as false if OmitErrorText is set
+// and omitted otherwise, so the default of the specification applies. RequestDeadline is
+// omitted if it is zero.
+func (o TRequestOptions) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	attr := func(name string, value string) {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+	}
+	switch {
+	case o.ReturnErrorText:
+		attr("ReturnErrorText", "true")
+	case o.OmitErrorText:
+		attr("ReturnErrorText", "false")
+	}
+	attr("ReturnDiagnosticInfo", strconv.FormatBool(o.ReturnDiagnosticInfo))
+	attr("ReturnItemTime", strconv.FormatBool(o.ReturnItemTime))
+	attr("ReturnItemPath", strconv.FormatBool(o.ReturnItemPath))
+	attr("ReturnItemName", strconv.FormatBool(o.ReturnItemName))
+	if !o.RequestDeadline.IsZero() {
+		attr("RequestDeadline", o.RequestDeadline.Format(time.RFC3339Nano))
+	}
+	if o.ClientRequestHandle != "" {
+		attr("ClientRequestHandle", o.ClientRequestHandle)
+	}
+	if o.LocaleID != "" {
+		attr("LocaleID", o.LocaleID)
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML decodes the options of a request. A missing ReturnErrorText is true, the default
+// of the specification, OmitErrorText is set if it is false.
+func (o *TRequestOptions) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type requestOptions TRequestOptions // without the methods of TRequestOptions
+	decoded := requestOptions{ReturnErrorText: true}
+	if err := d.DecodeElement(&decoded, &start); err != nil {
+		return err
+	}
+	*o = TRequestOptions(decoded)
+	o.OmitErrorText = !o.ReturnErrorText
+	return nil
 }
 
 func setOpcXmlDaTypes(items []TItem) []TItem {
@@ -247,12 +260,44 @@ func setOpcXmlDaTypes(items []TItem) []TItem {
 	return items
 }
 
+// OpcXmlDaType returns the xsi:type used for a Go value, e.g. "double" for float64
+// or "ArrayOfString" for []string, as used for TValue.Type if it is empty.
+func OpcXmlDaType(value interface{}) (string, error) {
+	if value == nil {
+		return "", fmt.Errorf("no type for nil")
+	}
+	return getOpcXmlDaType(value)
+}
+
+// QualifiedType returns the QName of a type as used for xsi:type and the dataType property: the XML Schema
+// types have the prefix "xsd", the types of OPC XML-DA like ArrayOfInt and OPCQuality the prefix namespace,
+// or "ns0" if it is empty.
+func QualifiedType(valueType string, namespace string) string {
+	if strings.HasPrefix(valueType, "ArrayOf") || valueType == "OPCQuality" {
+		return cmp.Or(namespace, "ns0") + ":" + valueType
+	}
+	return "xsd:" + valueType
+}
+
 func getOpcXmlDaType(value interface{}) (string, error) {
+	if _, ok := value.([]byte); ok {
+		return "base64Binary", nil
+	}
 	var arrayType bool
 	var elemType reflect.Type
 	vo := reflect.ValueOf(value)
-	if vo.Kind() == reflect.Slice && vo.Len() > 0 {
-		elemType = vo.Index(0).Type()
+	if elements, ok := value.([]interface{}); ok {
+		// decoded arrays are []interface{}, their type is the type of the elements,
+		// if they have different types or are nil, the type is ArrayOfAnyType
+		for i, element := range elements {
+			if element == nil || i > 0 && reflect.TypeOf(element) != elemType {
+				return "ArrayOfAnyType", nil
+			}
+			elemType = reflect.TypeOf(element)
+		}
+		if elemType == nil {
+			return "ArrayOfAnyType", nil
+		}
 		arrayType = true
 	} else if vo.Kind() == reflect.Slice {
 		elemType = vo.Type().Elem()
@@ -292,6 +337,18 @@ func getOpcXmlDaType(value interface{}) (string, error) {
 		} else {
 			return "dateTime", nil
 		}
+	case reflect.TypeOf(time.Duration(0)):
+		if arrayType {
+			return "ArrayOfDuration", nil
+		} else {
+			return "duration", nil
+		}
+	case reflect.TypeOf(Decimal("")), reflect.TypeOf(&big.Rat{}):
+		if arrayType {
+			return "ArrayOfDecimal", nil
+		} else {
+			return "decimal", nil
+		}
 	case reflect.TypeOf(int8(0)):
 		if arrayType {
 			return "ArrayOfByte", nil
@@ -344,3 +401,76 @@ func getOpcXmlDaType(value interface{}) (string, error) {
 		return "", fmt.Errorf("unknown type: %v", reflect.TypeOf(value))
 	}
 }
+
+// RequestOptionsFromMap converts the options map used by earlier versions of Read, Write,
+// Subscribe and SubscriptionPolledRefresh into TRequestOptions. Keys must match the
+// attribute names of TRequestOptions exactly, unknown keys and values of the wrong
+// type are reported as error.
+//
+// Deprecated: use TRequestOptions directly.
+func RequestOptionsFromMap(options map[string]interface{}) (TRequestOptions, error) {
+	var o TRequestOptions
+	var unknown []string
+	for key, value := range options {
+		var err error
+		switch key {
+		case "ReturnErrorText":
+			o.ReturnErrorText, err = optionBool(value)
+			o.OmitErrorText = err == nil && !o.ReturnErrorText
+		case "ReturnDiagnosticInfo":
+			o.ReturnDiagnosticInfo, err = optionBool(value)
+		case "ReturnItemTime":
+			o.ReturnItemTime, err = optionBool(value)
+		case "ReturnItemPath":
+			o.ReturnItemPath, err = optionBool(value)
+		case "ReturnItemName":
+			o.ReturnItemName, err = optionBool(value)
+		case "RequestDeadline":
+			o.RequestDeadline, err = optionTime(value)
+		case "ClientRequestHandle":
+			o.ClientRequestHandle, err = optionString(value)
+		case "LocaleID":
+			o.LocaleID, err = optionString(value)
+		default:
+			unknown = append(unknown, key)
+			continue
+		}
+		if err != nil {
+			return TRequestOptions{}, fmt.Errorf("option %s: %w", key, err)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return TRequestOptions{}, fmt.Errorf("unknown options: %s", strings.Join(unknown, ", "))
+	}
+	return o, nil
+}
+
+func optionBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	default:
+		return false, fmt.Errorf("expected bool, got %T", value)
+	}
+}
+
+func optionTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	default:
+		return time.Time{}, fmt.Errorf("expected time.Time, got %T", value)
+	}
+}
+
+func optionString(value interface{}) (string, error) {
+	if v, ok := value.(string); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("expected string, got %T", value)
+}
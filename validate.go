@@ -0,0 +1,248 @@
+package gopcxmlda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// validationPropertyNames are the properties requested to validate writes.
+var validationPropertyNames = []string{PropertyDataType, PropertyAccessRights, PropertyHighIR, PropertyLowIR}
+
+// WithWriteValidation validates the items of every Write with ValidateWrite before the request is sent.
+// The properties of the items are cached for maxAge, or until InvalidateProperties is called if maxAge is zero.
+func WithWriteValidation(maxAge time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.validator = &writeValidator{maxAge: maxAge}
+	}
+}
+
+// writeValidator caches the properties of the items needed to validate writes.
+type writeValidator struct {
+	maxAge time.Duration
+	mu     sync.Mutex
+	cache  map[itemKey]cachedProperties
+}
+
+type itemKey struct {
+	itemPath string
+	itemName string
+}
+
+type cachedProperties struct {
+	properties ItemProperties
+	fetched    time.Time
+}
+
+// ValidateWrite checks the items of a Write against their properties, which are requested with
+// GetProperties, and returns the items as they are sent by Write:
+//
+//   - items which are not writable by their accessRights are rejected with ErrReadOnly,
+//   - values which can not be converted to the dataType of the item are rejected with ErrBadType,
+//     the other values are converted to the dataType, e.g. an int written to a double item,
+//   - numeric values outside of lowIR and highIR are rejected with ErrRange.
+//
+// Items unknown to the server are rejected with the ResultID of GetProperties. The errors of the
+// items are returned as joined *ItemError. Properties are cached if the Server was created
+// WithWriteValidation.
+func (s *Server) ValidateWrite(ctx context.Context, items []TItem, namespace string) ([]TItem, error) {
+	validator := s.validator
+	if validator == nil {
+		validator = &writeValidator{}
+	}
+	properties, err := validator.properties(ctx, s, items, namespace)
+	if err != nil {
+		logError(err, "ValidateWrite")
+		return nil, err
+	}
+	validated := make([]TItem, len(items))
+	var errReturn error
+	for i, item := range items {
+		validated[i] = item
+		p := properties[itemKey{item.ItemPath, item.ItemName}]
+		result, text := validateItem(&validated[i].Value, p)
+		if result != "" {
+			errReturn = errors.Join(errReturn, &ItemError{
+				ItemName:         item.ItemName,
+				ItemPath:         item.ItemPath,
+				ClientItemHandle: item.ClientItemHandle,
+				ResultID:         result,
+				Text:             text,
+			})
+		}
+	}
+	if errReturn != nil {
+		logError(errReturn, "ValidateWrite")
+		return nil, errReturn
+	}
+	return validated, nil
+}
+
+// InvalidateProperties removes the properties cached for the validation of writes, e.g. after
+// the configuration of the server has changed.
+func (s *Server) InvalidateProperties() {
+	if s.validator == nil {
+		return
+	}
+	s.validator.mu.Lock()
+	defer s.validator.mu.Unlock()
+	s.validator.cache = nil
+}
+
+// properties returns the properties of the items, from the cache or requested with GetProperties.
+func (v *writeValidator) properties(ctx context.Context, s *Server, items []TItem, namespace string) (map[itemKey]ItemProperties, error) {
+	properties := make(map[itemKey]ItemProperties, len(items))
+	var missing []TItem
+	now := time.Now()
+	v.mu.Lock()
+	for _, item := range items {
+		key := itemKey{item.ItemPath, item.ItemName}
+		if _, ok := properties[key]; ok {
+			continue
+		}
+		if cached, ok := v.cache[key]; ok && (v.maxAge == 0 || now.Sub(cached.fetched) < v.maxAge) {
+			properties[key] = cached.properties
+			continue
+		}
+		properties[key] = ItemProperties{}
+		missing = append(missing, TItem{ItemName: item.ItemName, ItemPath: item.ItemPath})
+	}
+	v.mu.Unlock()
+	if len(missing) == 0 {
+		return properties, nil
+	}
+
+	var ClientRequestHandle string
+	response, err := s.GetProperties(ctx, missing, TPropertyOptions{
+		PropertyNames:        validationPropertyNames,
+		ReturnPropertyValues: true,
+	}, &ClientRequestHandle, namespace)
+	if len(response.Response.PropertyList) != len(missing) {
+		return nil, errors.Join(err, fmt.Errorf("GetProperties returned %d of %d items", len(response.Response.PropertyList), len(missing)))
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.cache == nil {
+		v.cache = make(map[itemKey]cachedProperties)
+	}
+	for i, list := range response.Response.PropertyList {
+		// properties of the wrong type are ignored, they are left zero
+		p, _ := NewItemProperties(list)
+		key := itemKey{missing[i].ItemPath, missing[i].ItemName}
+		properties[key] = p
+		if p.Error == "" {
+			v.cache[key] = cachedProperties{properties: p, fetched: now}
+		}
+	}
+	return properties, nil
+}
+
+// validateItem checks value against the properties of its item and converts it to the dataType.
+// It returns the ResultID and a text if the value is rejected.
+func validateItem(value *TValue, p ItemProperties) (ResultCode, string) {
+	if p.Error != "" {
+		return ParseResultCode(p.Error), "properties not available"
+	}
+	if p.AccessRights != AccessUnknown && !p.AccessRights.CanWrite() {
+		return ErrReadOnly, "item is " + p.AccessRights.String()
+	}
+	dataType := p.DataType[strings.LastIndex(p.DataType, ":")+1:]
+	converted, err := convertToType(value.Value, dataType)
+	if err != nil {
+		result := ErrBadType
+		if errors.Is(err, ErrRange) {
+			result = ErrRange
+		}
+		return result, fmt.Sprintf("%v can not be written as %s: %v", value.Value, dataType, err)
+	}
+	if converted != nil {
+		*value = TValue{Type: dataType, Value: converted, Namespace: value.Namespace}
+	}
+	if p.LowIR == nil && p.HighIR == nil {
+		return "", ""
+	}
+	values := []interface{}{value.Value}
+	if arrayElementType(dataType) != "" {
+		values, _ = AsSlice[interface{}](*value)
+	}
+	for _, v := range values {
+		f, err := ValueAs[float64](TValue{Value: v})
+		if err != nil {
+			continue
+		}
+		if p.LowIR != nil && f < *p.LowIR {
+			return ErrRange, fmt.Sprintf("%v is below lowIR %v", v, *p.LowIR)
+		}
+		if p.HighIR != nil && f > *p.HighIR {
+			return ErrRange, fmt.Sprintf("%v is above highIR %v", v, *p.HighIR)
+		}
+	}
+	return "", ""
+}
+
+// goTypes are the Go types values are converted to for a dataType, see getOpcXmlDaType.
+var goTypes = map[string]reflect.Type{
+	"boolean":       reflect.TypeOf(false),
+	"string":        reflect.TypeOf(""),
+	"QName":         reflect.TypeOf(""),
+	"float":         reflect.TypeOf(float32(0)),
+	"double":        reflect.TypeOf(float64(0)),
+	"decimal":       decimalType,
+	"byte":          reflect.TypeOf(int8(0)),
+	"unsignedByte":  reflect.TypeOf(uint8(0)),
+	"short":         reflect.TypeOf(int16(0)),
+	"unsignedShort": reflect.TypeOf(uint16(0)),
+	"int":           reflect.TypeOf(int32(0)),
+	"unsignedInt":   reflect.TypeOf(uint32(0)),
+	"long":          reflect.TypeOf(int64(0)),
+	"unsignedLong":  reflect.TypeOf(uint64(0)),
+	"dateTime":      timeType,
+	"duration":      durationType,
+	"base64Binary":  reflect.TypeOf([]byte(nil)),
+}
+
+// convertToType converts value to the Go type of dataType, as by ValueAs. Only strings are accepted
+// for string types. It returns nil without error if dataType is unknown or anyType.
+func convertToType(value interface{}, dataType string) (interface{}, error) {
+	if value == nil {
+		return nil, fmt.Errorf("%w: no value", ErrBadType)
+	}
+	goType, ok := goTypes[dataType]
+	if elemType, isArray := goTypes[arrayElementType(dataType)]; isArray {
+		goType, ok = reflect.SliceOf(elemType), true
+	}
+	if !ok {
+		return nil, nil
+	}
+	if (goType == goTypes["string"] || goType == reflect.SliceOf(goTypes["string"])) && !isText(value) {
+		// ValueAs formats any value as string, which the server would not do
+		return nil, fmt.Errorf("%w: %T is no string", ErrBadType, value)
+	}
+	converted := reflect.New(goType).Elem()
+	if err := convertValue(value, converted); err != nil {
+		return nil, err
+	}
+	return converted.Interface(), nil
+}
+
+// isText reports whether value is a string or a slice of strings.
+func isText(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if !isText(v.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,52 @@
+package gopcxmlda
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConvertToType(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		dataType string
+		want     interface{}
+	}{
+		{1, "double", 1.0},
+		{int64(7), "unsignedByte", uint8(7)},
+		{2.0, "int", int32(2)},
+		{"x", "string", "x"},
+		{[]string{"a"}, "ArrayOfString", []string{"a"}},
+		{[]interface{}{1, 2.0}, "ArrayOfFloat", []float32{1, 2}},
+		{0.5, "decimal", Decimal("0.5")},
+		{time.Second, "duration", time.Second},
+		{[]byte("x"), "base64Binary", []byte("x")},
+		{1, "anyType", nil},
+		{1, "", nil},
+	}
+	for _, test := range tests {
+		got, err := convertToType(test.value, test.dataType)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%#v as %s: got %#v, %v", test.value, test.dataType, got, err)
+		}
+	}
+	for _, test := range []struct {
+		value    interface{}
+		dataType string
+		err      error
+	}{
+		{1, "string", ErrBadType},
+		{[]interface{}{"a", 1}, "ArrayOfString", ErrBadType},
+		{1.5, "int", ErrBadType},
+		{true, "double", ErrBadType},
+		{1, "boolean", ErrBadType},
+		{1, "ArrayOfInt", ErrBadType},
+		{256, "unsignedByte", ErrRange},
+		{nil, "double", ErrBadType},
+	} {
+		if _, err := convertToType(test.value, test.dataType); !errors.Is(err, test.err) {
+			t.Errorf("%#v as %s: got %v, want %v", test.value, test.dataType, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package gopcxmlda
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+// ValueAs returns the value of v as T. Values of type T are returned unchanged, otherwise:
+//
+//   - integers, floats, Decimal and time.Duration (as nanoseconds) convert to all integer and
+//     float types. Conversions to integers fail with ErrBadType if the value has a fraction and
+//     with ErrRange if it does not fit, conversions to floats round to the nearest value and fail
+//     only if it overflows, like the writes of the Registry of the server package.
+//   - integers, finite floats and Decimal convert to Decimal and *big.Rat.
+//   - scalar values convert to string in their lexical form of XML Schema, e.g. "1.5E+21" or "true".
+//   - arrays convert to slices of any type their elements convert to.
+//   - base64Binary values, which are decoded as their base64 text, convert to []byte.
+//
+// Booleans only convert to bool and string, strings are not parsed. Other conversions fail with ErrBadType.
+func ValueAs[T any](v TValue) (T, error) {
+	var result T
+	if value, ok := v.Value.(T); ok {
+		return value, nil
+	}
+	if v.Value == nil {
+		return result, fmt.Errorf("%w: no value", ErrBadType)
+	}
+	if text, ok := v.Value.(string); ok && v.Type == "base64Binary" {
+		if target, ok := any(&result).(*[]byte); ok {
+			b, err := parseXsd(text, v.Type)
+			if err != nil {
+				return result, fmt.Errorf("%w: %w", ErrBadType, err)
+			}
+			*target = b.([]byte)
+			return result, nil
+		}
+	}
+	if err := convertValue(v.Value, reflect.ValueOf(&result).Elem()); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// AsSlice returns the elements of an array value as []T, converted as by ValueAs.
+func AsSlice[T any](v TValue) ([]T, error) {
+	return ValueAs[[]T](v)
+}
+
+// AsFloat64 returns the value as float64, see ValueAs.
+func (v TValue) AsFloat64() (float64, error) {
+	return ValueAs[float64](v)
+}
+
+// AsInt64 returns the value as int64, see ValueAs.
+func (v TValue) AsInt64() (int64, error) {
+	return ValueAs[int64](v)
+}
+
+// AsString returns a string value or the lexical form of other scalar values, see ValueAs.
+func (v TValue) AsString() (string, error) {
+	return ValueAs[string](v)
+}
+
+// AsBool returns a boolean value, see ValueAs.
+func (v TValue) AsBool() (bool, error) {
+	return ValueAs[bool](v)
+}
+
+// AsTime returns a dateTime value.
+func (v TValue) AsTime() (time.Time, error) {
+	return ValueAs[time.Time](v)
+}
+
+// AsFloat64 returns the value of the item as float64, or the error of the item if it failed.
+func (i TItem) AsFloat64() (float64, error) {
+	return itemValueAs[float64](i)
+}
+
+// AsInt64 returns the value of the item as int64, or the error of the item if it failed.
+func (i TItem) AsInt64() (int64, error) {
+	return itemValueAs[int64](i)
+}
+
+// AsString returns the value of the item as string, or the error of the item if it failed.
+func (i TItem) AsString() (string, error) {
+	return itemValueAs[string](i)
+}
+
+// AsBool returns the boolean value of the item, or the error of the item if it failed.
+func (i TItem) AsBool() (bool, error) {
+	return itemValueAs[bool](i)
+}
+
+// AsTime returns the dateTime value of the item, or the error of the item if it failed.
+func (i TItem) AsTime() (time.Time, error) {
+	return itemValueAs[time.Time](i)
+}
+
+func itemValueAs[T any](i TItem) (T, error) {
+	if err := i.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+	value, err := ValueAs[T](i.Value)
+	if err != nil {
+		return value, fmt.Errorf("%s: %w", i.ItemName, err)
+	}
+	return value, nil
+}
+
+var (
+	decimalType  = reflect.TypeOf(Decimal(""))
+	ratType      = reflect.TypeOf(&big.Rat{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// convertValue sets target to value following the rules of ValueAs.
+func convertValue(value interface{}, target reflect.Value) error {
+	vo := reflect.ValueOf(value)
+	if vo.Type().AssignableTo(target.Type()) {
+		target.Set(vo)
+		return nil
+	}
+	badType := fmt.Errorf("%w: %T to %s", ErrBadType, value, target.Type())
+	switch target.Type() {
+	case decimalType:
+		if vo.Kind() == reflect.String && vo.Type() != decimalType || vo.Kind() == reflect.Bool {
+			return badType
+		}
+		text, err := formatXsdDecimal(value)
+		if err != nil {
+			return badType
+		}
+		target.SetString(text)
+		return nil
+	case ratType:
+		r, err := valueRat(value)
+		if err != nil {
+			return fmt.Errorf("%w to %s", err, target.Type())
+		}
+		target.Set(reflect.ValueOf(r))
+		return nil
+	case durationType, timeType:
+		return badType
+	}
+	switch target.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := valueInt(value)
+		if err != nil {
+			return fmt.Errorf("%w to %s", err, target.Type())
+		}
+		if !i.IsInt64() || target.OverflowInt(i.Int64()) {
+			return fmt.Errorf("%w: %v does not fit into %s", ErrRange, value, target.Type())
+		}
+		target.SetInt(i.Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := valueInt(value)
+		if err != nil {
+			return fmt.Errorf("%w to %s", err, target.Type())
+		}
+		if !i.IsUint64() || target.OverflowUint(i.Uint64()) {
+			return fmt.Errorf("%w: %v does not fit into %s", ErrRange, value, target.Type())
+		}
+		target.SetUint(i.Uint64())
+	case reflect.Float32, reflect.Float64:
+		f, err := valueFloat(value)
+		if err != nil {
+			return fmt.Errorf("%w to %s", err, target.Type())
+		}
+		if target.OverflowFloat(f) {
+			return fmt.Errorf("%w: %v does not fit into %s", ErrRange, value, target.Type())
+		}
+		target.SetFloat(f)
+	case reflect.Bool:
+		if vo.Kind() != reflect.Bool {
+			return badType
+		}
+		target.SetBool(vo.Bool())
+	case reflect.String:
+		valueType, err := getOpcXmlDaType(value)
+		if err != nil || arrayElementType(valueType) != "" {
+			return badType
+		}
+		text, err := formatXsd(value, valueType)
+		if err != nil {
+			return err
+		}
+		target.SetString(text)
+	case reflect.Slice:
+		if vo.Kind() != reflect.Slice && vo.Kind() != reflect.Array {
+			return badType
+		}
+		slice := reflect.MakeSlice(target.Type(), vo.Len(), vo.Len())
+		for i := 0; i < vo.Len(); i++ {
+			elem := vo.Index(i)
+			if elem.Kind() == reflect.Interface {
+				if elem.IsNil() {
+					return fmt.Errorf("element %d: %w: no value", i, ErrBadType)
+				}
+				elem = elem.Elem()
+			}
+			if err := convertValue(elem.Interface(), slice.Index(i)); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		target.Set(slice)
+	default:
+		return badType
+	}
+	return nil
+}
+
+// valueInt returns an integral numeric value as big.Int.
+func valueInt(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case Decimal, *big.Rat:
+		r, err := valueRat(v)
+		if err != nil {
+			return nil, err
+		}
+		if !r.IsInt() {
+			return nil, fmt.Errorf("%w: %s has a fraction", ErrBadType, r.FloatString(3))
+		}
+		return r.Num(), nil
+	}
+	vo := reflect.ValueOf(value)
+	switch vo.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(vo.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(vo.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := vo.Float()
+		if math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%w: %v is no integer", ErrRange, f)
+		}
+		if f != math.Trunc(f) {
+			return nil, fmt.Errorf("%w: %v is no integer", ErrBadType, f)
+		}
+		i, _ := big.NewFloat(f).Int(nil)
+		return i, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrBadType, value)
+	}
+}
+
+// valueFloat returns a numeric value as float64.
+func valueFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case Decimal:
+		return v.Float64()
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f, nil
+	}
+	vo := reflect.ValueOf(value)
+	switch vo.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(vo.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(vo.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return vo.Float(), nil
+	default:
+		return 0, fmt.Errorf("%w: %T", ErrBadType, value)
+	}
+}
+
+// valueRat returns the exact value of an integer, finite float or decimal.
+func valueRat(value interface{}) (*big.Rat, error) {
+	switch v := value.(type) {
+	case Decimal:
+		return v.Rat()
+	case *big.Rat:
+		return v, nil
+	}
+	vo := reflect.ValueOf(value)
+	switch vo.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, _ := valueInt(value)
+		return new(big.Rat).SetInt(i), nil
+	case reflect.Float32, reflect.Float64:
+		f := vo.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%w: %v is not finite", ErrRange, f)
+		}
+		return new(big.Rat).SetFloat64(f), nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrBadType, value)
+	}
+}
@@ -0,0 +1,103 @@
+package gopcxmlda
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValueAs(t *testing.T) {
+	check := func(got interface{}, err error, want interface{}) {
+		t.Helper()
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("got %#v, %v, want %#v", got, err, want)
+		}
+	}
+	v := func(value interface{}) TValue { return TValue{Value: value} }
+
+	i8, err := ValueAs[int8](v(uint64(127)))
+	check(i8, err, int8(127))
+	u16, err := ValueAs[uint16](v(3.0))
+	check(u16, err, uint16(3))
+	i, err := ValueAs[int](v(Decimal("-42.000")))
+	check(i, err, -42)
+	u64, err := ValueAs[uint64](v(float64(1 << 63)))
+	check(u64, err, uint64(1<<63))
+	f32, err := ValueAs[float32](v(int16(-7)))
+	check(f32, err, float32(-7))
+	f, err := v(Decimal("0.1")).AsFloat64()
+	check(f, err, 0.1)
+	f, err = v(math.Inf(1)).AsFloat64()
+	check(f, err, math.Inf(1))
+	ns, err := v(time.Second).AsInt64()
+	check(ns, err, int64(1e9))
+	d, err := ValueAs[Decimal](v(0.1))
+	check(d, err, Decimal("0.1"))
+	r, err := ValueAs[*big.Rat](v(Decimal("2.5")))
+	check(r, err, big.NewRat(5, 2))
+	bytes, err := ValueAs[[]byte](TValue{Type: "base64Binary", Value: "AP9o\nZWxsbw=="})
+	check(bytes, err, []byte("\x00\xffhello"))
+	s, err := v(1e21).AsString()
+	check(s, err, "1E+21")
+	s, err = v(90 * time.Second).AsString()
+	check(s, err, "PT1M30S")
+	b, err := v(true).AsBool()
+	check(b, err, true)
+	tm, err := v(time.Unix(0, 0)).AsTime()
+	check(tm, err, time.Unix(0, 0))
+	floats, err := AsSlice[float64](v([]interface{}{1, uint8(2), 2.5}))
+	check(floats, err, []float64{1, 2, 2.5})
+	ints, err := AsSlice[int](v([]int16{1, 2}))
+	check(ints, err, []int{1, 2})
+	x, err := ValueAs[interface{}](v("x"))
+	check(x, err, "x")
+
+	for _, test := range []struct {
+		convert func() error
+		err     error
+	}{
+		{func() error { _, err := ValueAs[uint8](v(256)); return err }, ErrRange},
+		{func() error { _, err := ValueAs[uint](v(-1)); return err }, ErrRange},
+		{func() error { _, err := ValueAs[int](v(1.5)); return err }, ErrBadType},
+		{func() error { _, err := ValueAs[int64](v(math.NaN())); return err }, ErrBadType},
+		{func() error { _, err := ValueAs[int64](v(math.Inf(1))); return err }, ErrRange},
+		{func() error { _, err := ValueAs[int64](v(uint64(math.MaxUint64))); return err }, ErrRange},
+		{func() error { _, err := ValueAs[int](v(Decimal("0.5"))); return err }, ErrBadType},
+		{func() error { _, err := ValueAs[float32](v(1e39)); return err }, ErrRange},
+		{func() error { _, err := ValueAs[*big.Rat](v(math.Inf(-1))); return err }, ErrRange},
+		{func() error { _, err := AsSlice[uint8](v([]interface{}{1, 300})); return err }, ErrRange},
+		{func() error { _, err := v("1").AsFloat64(); return err }, ErrBadType},
+		{func() error { _, err := v(1).AsBool(); return err }, ErrBadType},
+		{func() error { _, err := v(true).AsInt64(); return err }, ErrBadType},
+		{func() error { _, err := v([]interface{}{"a"}).AsString(); return err }, ErrBadType},
+		{func() error { _, err := v(TQuality{}).AsString(); return err }, ErrBadType},
+		{func() error { _, err := v(1).AsTime(); return err }, ErrBadType},
+		{func() error { _, err := ValueAs[time.Duration](v(5)); return err }, ErrBadType},
+		{func() error { _, err := ValueAs[Decimal](v("1")); return err }, ErrBadType},
+		{func() error { _, err := AsSlice[int](v(1)); return err }, ErrBadType},
+		{func() error { _, err := AsSlice[int](v([]interface{}{1, nil})); return err }, ErrBadType},
+		{func() error { _, err := TValue{}.AsFloat64(); return err }, ErrBadType},
+	} {
+		if err := test.convert(); !errors.Is(err, test.err) {
+			t.Errorf("got %v, want %v", err, test.err)
+		}
+	}
+}
+
+func TestItemAs(t *testing.T) {
+	item := TItem{ItemName: "P", Value: TValue{Value: 1200.5}}
+	if f, err := item.AsFloat64(); err != nil || f != 1200.5 {
+		t.Errorf("got %v, %v", f, err)
+	}
+	if _, err := item.AsInt64(); !errors.Is(err, ErrBadType) {
+		t.Errorf("got %v", err)
+	}
+	item.Error = string(ErrUnknownItemName)
+	var itemErr *ItemError
+	if _, err := item.AsFloat64(); !errors.As(err, &itemErr) || itemErr.ItemName != "P" {
+		t.Errorf("got %v", err)
+	}
+}
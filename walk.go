@@ -0,0 +1,175 @@
+package gopcxmlda
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// SkipBranch can be returned by a WalkFunc to not descend into the children of the element.
+var SkipBranch = errors.New("skip this branch")
+
+// WalkFunc is called by Walk for every visited element. depth is 1 for the children of the root.
+// Calls are serialized, but with a Concurrency above 1 the order of the branches is not defined.
+// Returning SkipBranch skips the children of the element, any other error stops the walk.
+type WalkFunc func(element TBrowseElement, depth int) error
+
+// WalkOptions configures Walk and BrowseTree.
+type WalkOptions struct {
+	// BrowseOptions are used for all Browse requests. ItemName is the item name of the root,
+	// MaxElementsReturned the page size. BrowseFilter, ElementNameFilter and VendorFilter are passed
+	// to the server, so they also restrict the branches which are descended into.
+	BrowseOptions TBrowseOptions
+	MaxDepth      int // maximum depth of the visited elements, unlimited if zero
+	Concurrency   int // number of parallel Browse requests, 1 if zero
+}
+
+// BrowseNode is an element of the tree returned by BrowseTree.
+type BrowseNode struct {
+	Element  TBrowseElement
+	Depth    int
+	Children []*BrowseNode
+}
+
+// Walk browses the address space below itemPath recursively and calls fn for every element.
+// It descends into every element with children, up to options.MaxDepth. Elements which were
+// already visited are skipped, so address spaces with cycles terminate.
+//
+// Parameters:
+// - ctx (context.Context): The context of the requests, canceling it stops the walk.
+// - itemPath (string): The path of the root item.
+// - namespace (string): The namespace to use for the requests.
+// - options (WalkOptions): The options of the walk.
+// - fn (WalkFunc): The function called for every element.
+//
+// Returns:
+// - (error): The first error of a Browse request or fn.
+//
+// Example:
+//
+//	err := s.Walk(ctx, "Loc/Wec/Plant1", "", WalkOptions{Concurrency: 4}, func(element TBrowseElement, depth int) error {
+//		if element.IsItem {
+//			fmt.Println(element.ItemName)
+//		}
+//		return nil
+//	})
+func (s *Server) Walk(ctx context.Context, itemPath string, namespace string, options WalkOptions, fn WalkFunc) error {
+	_, err := s.walk(ctx, itemPath, namespace, options, fn)
+	return err
+}
+
+// BrowseTree browses the address space below itemPath recursively like Walk and returns it as tree.
+// The root node has no Name and contains the itemPath and the ItemName of the browse options.
+// The children of each node are in the order returned by the server.
+func (s *Server) BrowseTree(ctx context.Context, itemPath string, namespace string, options WalkOptions) (*BrowseNode, error) {
+	return s.walk(ctx, itemPath, namespace, options, func(TBrowseElement, int) error { return nil })
+}
+
+type walker struct {
+	server    *Server
+	namespace string
+	options   WalkOptions
+	fn        WalkFunc
+	sem       chan struct{}
+	wg        sync.WaitGroup
+	cancel    context.CancelFunc
+
+	mu      sync.Mutex
+	visited map[string]bool
+	err     error
+}
+
+func (s *Server) walk(ctx context.Context, itemPath string, namespace string, options WalkOptions, fn WalkFunc) (*BrowseNode, error) {
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := &walker{
+		server:    s,
+		namespace: namespace,
+		options:   options,
+		fn:        fn,
+		sem:       make(chan struct{}, concurrency),
+		cancel:    cancel,
+		visited:   make(map[string]bool),
+	}
+
+	root := &BrowseNode{Element: TBrowseElement{
+		ItemPath:    itemPath,
+		ItemName:    options.BrowseOptions.ItemName,
+		HasChildren: true,
+	}}
+	rootKey := elementKey("", root.Element)
+	w.visited[rootKey] = true
+	w.wg.Add(1)
+	go w.visit(ctx, root, rootKey)
+	w.wg.Wait()
+
+	if w.err == nil && ctx.Err() != nil {
+		w.err = ctx.Err()
+	}
+	return root, w.err
+}
+
+// visit browses the children of node and starts a visit for every child with children.
+func (w *walker) visit(ctx context.Context, node *BrowseNode, key string) {
+	defer w.wg.Done()
+	select {
+	case w.sem <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	options := w.options.BrowseOptions
+	options.ItemName = node.Element.ItemName
+	options.ContinuationPoint = ""
+	elements, err := w.server.BrowseAll(ctx, node.Element.ItemPath, w.namespace, options)
+	<-w.sem
+	if err != nil {
+		w.fail(err)
+		return
+	}
+
+	for _, element := range elements {
+		childKey := elementKey(key, element)
+		w.mu.Lock()
+		if w.visited[childKey] || w.err != nil {
+			w.mu.Unlock()
+			continue
+		}
+		w.visited[childKey] = true
+		err := w.fn(element, node.Depth+1)
+		w.mu.Unlock()
+
+		if err != nil && !errors.Is(err, SkipBranch) {
+			w.fail(err)
+			return
+		}
+		child := &BrowseNode{Element: element, Depth: node.Depth + 1}
+		node.Children = append(node.Children, child)
+		if element.HasChildren && err == nil && (w.options.MaxDepth == 0 || child.Depth < w.options.MaxDepth) {
+			w.wg.Add(1)
+			go w.visit(ctx, child, childKey)
+		}
+	}
+}
+
+// fail stores the first error and stops the walk.
+func (w *walker) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+		w.cancel()
+	}
+}
+
+// elementKey identifies an element for the cycle protection. Elements without ItemName
+// are identified by their name below the parent.
+func elementKey(parentKey string, element TBrowseElement) string {
+	if element.ItemName == "" {
+		return parentKey + "/" + element.Name
+	}
+	return element.ItemPath + "\x00" + element.ItemName
+}
@@ -0,0 +1,100 @@
+package gopcxmlda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testTree maps the ItemName of a branch to its children, "+" marks children with children.
+var testTree = map[string][]string{
+	"":              {"+Plant1", "+Plant2"},
+	"Plant1":        {"Plant1/P", "+Plant1/Status"},
+	"Plant1/Status": {"Plant1/Status/St", "+Plant1"}, // cycle back to Plant1
+	"Plant2":        {"Plant2/P"},
+}
+
+func newTreeServer(t *testing.T) (*Server, func() []string) {
+	var mu sync.Mutex
+	var browsed []string
+	s, _ := newTestServer(t, func(action string, body string) string {
+		if action == "GetProperties" {
+			return soapEnvelope(testPropertiesResponse(body))
+		}
+		name := attrOf(body, "ItemName")
+		mu.Lock()
+		browsed = append(browsed, name)
+		mu.Unlock()
+		var elements strings.Builder
+		for _, child := range testTree[name] {
+			hasChildren := strings.HasPrefix(child, "+")
+			child = strings.TrimPrefix(child, "+")
+			elements.WriteString(fmt.Sprintf(`<Elements Name="%s" ItemName="%s" IsItem="%t" HasChildren="%t"/>`,
+				child[strings.LastIndex(child, "/")+1:], child, !hasChildren, hasChildren))
+		}
+		return soapEnvelope(`<BrowseResponse xmlns="http://opcfoundation.org/webservices/XMLDA/1.0/">` +
+			`<BrowseResult RcvTime="2024-01-01T00:00:00Z" ReplyTime="2024-01-01T00:00:00Z" ServerState="running"/>` +
+			elements.String() + `</BrowseResponse>`)
+	})
+	return s, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), browsed...)
+	}
+}
+
+func TestBrowseTree(t *testing.T) {
+	s, browsed := newTreeServer(t)
+	root, err := s.BrowseTree(context.Background(), "", "", WalkOptions{Concurrency: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	var print func(node *BrowseNode)
+	print = func(node *BrowseNode) {
+		for _, child := range node.Children {
+			lines = append(lines, fmt.Sprintf("%d:%s", child.Depth, child.Element.ItemName))
+			print(child)
+		}
+	}
+	print(root)
+	expected := "1:Plant1 2:Plant1/P 2:Plant1/Status 3:Plant1/Status/St 1:Plant2 2:Plant2/P"
+	if strings.Join(lines, " ") != expected {
+		t.Fatalf("unexpected tree:\n%s\nexpected:\n%s", strings.Join(lines, " "), expected)
+	}
+	if len(browsed()) != 4 {
+		t.Fatalf("expected 4 browse requests, got %v", browsed())
+	}
+}
+
+func TestWalk(t *testing.T) {
+	s, _ := newTreeServer(t)
+	var visited []string
+	err := s.Walk(context.Background(), "", "", WalkOptions{MaxDepth: 2}, func(element TBrowseElement, depth int) error {
+		visited = append(visited, element.ItemName)
+		if element.ItemName == "Plant2" {
+			return SkipBranch
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(visited) != 4 {
+		t.Fatalf("unexpected visited elements %v", visited)
+	}
+
+	stop := errors.New("stop")
+	err = s.Walk(context.Background(), "", "", WalkOptions{}, func(element TBrowseElement, depth int) error {
+		if depth == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected walk to stop, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package gopcxmlda
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"time"
+)
+
+// Namespaces and URIs of the WS-Security UsernameToken Profile 1.0.
+const (
+	WsseNamespace    = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	WsuNamespace     = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+	WssePasswordText = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	// WssePasswordDigest is the type of a password sent as Base64(SHA-1(nonce + created + password)).
+	WssePasswordDigest = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+	WsseBase64Binary   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+)
+
+// SoapEnvelopeNamespace is the namespace of the SOAP 1.1 envelope, of the attribute mustUnderstand.
+const SoapEnvelopeNamespace = "http://schemas.xmlsoap.org/soap/envelope/"
+
+// UsernameToken adds a WS-Security header with the credentials to every request. The password is sent
+// as text, or as digest with a new nonce and the created timestamp, which the server uses to detect
+// replayed requests. The password text should only be sent with HTTPS.
+type UsernameToken struct {
+	Username string
+	Password string
+	Digest   bool             // send the PasswordDigest instead of the password text
+	Now      func() time.Time // time of the created timestamp, time.Now if nil
+}
+
+// wsseSecurity is the Security element of the SOAP header.
+type wsseSecurity struct {
+	XMLName        xml.Name          `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+	MustUnderstand string            `xml:"http://schemas.xmlsoap.org/soap/envelope/ mustUnderstand,attr"`
+	UsernameToken  wsseUsernameToken `xml:"UsernameToken"`
+}
+
+type wsseUsernameToken struct {
+	Username string       `xml:"Username"`
+	Password wsseEncoded  `xml:"Password"`
+	Nonce    *wsseEncoded `xml:"Nonce,omitempty"`
+	Created  *wsuCreated  `xml:"Created,omitempty"`
+}
+
+type wsseEncoded struct {
+	Type         string `xml:"Type,attr,omitempty"`
+	EncodingType string `xml:"EncodingType,attr,omitempty"`
+	Value        string `xml:",chardata"`
+}
+
+type wsuCreated struct {
+	XMLName xml.Name `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+	Value   string   `xml:",chardata"`
+}
+
+// SOAPHeader returns the Security element with the UsernameToken, the same for all operations.
+func (t UsernameToken) SOAPHeader(string) (interface{}, error) {
+	token := wsseUsernameToken{
+		Username: t.Username,
+		Password: wsseEncoded{Type: WssePasswordText, Value: t.Password},
+	}
+	if t.Digest {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		now := time.Now
+		if t.Now != nil {
+			now = t.Now
+		}
+		created := now().UTC().Format("2006-01-02T15:04:05.000Z")
+		token.Password = wsseEncoded{Type: WssePasswordDigest, Value: PasswordDigest(nonce, created, t.Password)}
+		token.Nonce = &wsseEncoded{EncodingType: WsseBase64Binary, Value: base64.StdEncoding.EncodeToString(nonce)}
+		token.Created = &wsuCreated{Value: created}
+	}
+	return wsseSecurity{MustUnderstand: "1", UsernameToken: token}, nil
+}
+
+// PasswordDigest returns the digest of a UsernameToken, Base64(SHA-1(nonce + created + password)).
+func PasswordDigest(nonce []byte, created, password string) string {
+	digest := sha1.New()
+	digest.Write(nonce)
+	digest.Write([]byte(created))
+	digest.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(digest.Sum(nil))
+}
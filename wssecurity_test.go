@@ -0,0 +1,72 @@
+package gopcxmlda
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeSecurity decodes the Security element in the SOAP header of payload.
+func decodeSecurity(t *testing.T, payload string) wsseSecurity {
+	t.Helper()
+	var envelope struct {
+		Header struct {
+			Security []wsseSecurity `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+		} `xml:"http://schemas.xmlsoap.org/soap/envelope/ Header"`
+	}
+	if err := xml.Unmarshal([]byte(payload), &envelope); err != nil {
+		t.Fatalf("invalid payload: %v\n%s", err, payload)
+	}
+	if len(envelope.Header.Security) != 1 {
+		t.Fatalf("%d Security elements in\n%s", len(envelope.Header.Security), payload)
+	}
+	return envelope.Header.Security[0]
+}
+
+func TestUsernameToken(t *testing.T) {
+	handle := "1"
+	s := &Server{HeaderProviders: []HeaderProvider{UsernameToken{Username: "operator", Password: hostile}}}
+	payload, err := buildGetStatusPayload(s, "ns0", &handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	security := decodeSecurity(t, payload)
+	token := security.UsernameToken
+	if security.MustUnderstand != "1" || token.Username != "operator" || token.Password != (wsseEncoded{Type: WssePasswordText, Value: hostile}) ||
+		token.Nonce != nil || token.Created != nil {
+		t.Errorf("unexpected token: %+v", security)
+	}
+	var status GetStatusRequest
+	decodeBody(t, payload, &status)
+	if status.ClientRequestHandle != "1" {
+		t.Errorf("unexpected request: %+v", status)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	s.HeaderProviders = []HeaderProvider{UsernameToken{Username: "operator", Password: "secret", Digest: true, Now: func() time.Time { return now }}}
+	payload, err = buildGetStatusPayload(s, "ns0", &handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token = decodeSecurity(t, payload).UsernameToken
+	if token.Nonce == nil || token.Created == nil || token.Nonce.EncodingType != WsseBase64Binary || token.Created.Value != "2024-05-01T10:00:00.000Z" {
+		t.Fatalf("unexpected token: %+v", token)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(token.Nonce.Value)
+	if err != nil || len(nonce) != 16 {
+		t.Fatalf("invalid nonce %q: %v", token.Nonce.Value, err)
+	}
+	if token.Password != (wsseEncoded{Type: WssePasswordDigest, Value: PasswordDigest(nonce, token.Created.Value, "secret")}) {
+		t.Errorf("unexpected password: %+v", token.Password)
+	}
+	if strings.Contains(payload, "secret") {
+		t.Error("password text sent with digest")
+	}
+	// a new nonce for every request
+	payload, _ = buildGetStatusPayload(s, "ns0", &handle)
+	if decodeSecurity(t, payload).UsernameToken.Nonce.Value == token.Nonce.Value {
+		t.Error("nonce reused")
+	}
+}
@@ -0,0 +1,349 @@
+package gopcxmlda
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// xsiNamespace is the namespace of xsi:type and xsi:nil.
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// formatXsd returns the lexical form of XML Schema of a single value of the type xsdType, the counterpart
+// of parseXsd: "true" and "false" for booleans, decimal integers, floats in the shortest form that is parsed
+// to the same value with "INF", "-INF" and "NaN" for the special values, xsd:dateTime with nanoseconds and
+// the offset of the time and base64 for []byte. A duration is written as "PT1H30M", a decimal without
+// exponent. Strings are returned unchanged, they must not contain characters which are not allowed in XML.
+func formatXsd(value interface{}, xsdType string) (string, error) {
+	switch xsdType {
+	case "duration":
+		return formatXsdDuration(value)
+	case "decimal":
+		return formatXsdDecimal(value)
+	}
+	switch v := value.(type) {
+	case time.Time:
+		text, err := v.MarshalText()
+		if err != nil {
+			return "", fmt.Errorf("dateTime: %w", err)
+		}
+		return string(text), nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case Decimal, *big.Rat:
+		return formatXsdDecimal(v)
+	}
+	vo := reflect.ValueOf(value)
+	switch vo.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(vo.Bool()), nil
+	case reflect.String:
+		s := vo.String()
+		if i := strings.IndexFunc(s, func(r rune) bool { return !isXmlChar(r) }); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(s[i:])
+			return "", fmt.Errorf("string: character %U not allowed in XML", r)
+		}
+		return s, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(vo.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(vo.Uint(), 10), nil
+	case reflect.Float32:
+		return formatXsdFloat(vo.Float(), 32), nil
+	case reflect.Float64:
+		return formatXsdFloat(vo.Float(), 64), nil
+	default:
+		return "", fmt.Errorf("no XML Schema type for %T", value)
+	}
+}
+
+// formatXsdFloat returns the lexical form of xsd:float (bitSize 32) or xsd:double (bitSize 64).
+func formatXsdFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "INF"
+	case math.IsInf(f, -1):
+		return "-INF"
+	}
+	// XML Schema accepts e and E, E is used by its canonical representation
+	return strings.Replace(strconv.FormatFloat(f, 'g', -1, bitSize), "e", "E", 1)
+}
+
+// isXmlChar reports whether r is allowed in XML 1.0 documents.
+func isXmlChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+// parseXsd parses the lexical form of a single value of the XML Schema type xsdType. The values
+// have the Go types returned for them by getOpcXmlDaType, so formatXsd and parseXsd round-trip:
+// int for int, uint for unsignedInt, []byte for base64Binary, time.Duration for duration and
+// Decimal for decimal. The text of anyType is returned as string. TValue keeps the Go types of
+// earlier versions for single values, see singleValue.
+func parseXsd(text string, xsdType string) (interface{}, error) {
+	value, err := parseXsdValue(text, xsdType)
+	if err == errUnknownType {
+		return nil, fmt.Errorf("unknown type: %s", xsdType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", xsdType, text, err)
+	}
+	return value, nil
+}
+
+func parseXsdValue(text string, xsdType string) (interface{}, error) {
+	if xsdType != "string" && xsdType != "QName" && xsdType != "anyType" {
+		// whitespace is collapsed for all types except strings
+		text = strings.TrimSpace(text)
+	}
+	switch xsdType {
+	case "string", "QName", "anyType":
+		return text, nil
+	case "base64Binary":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	case "boolean":
+		return strconv.ParseBool(text)
+	case "dateTime", "time", "date":
+		return parseXsdTime(text, xsdTimeLayouts[xsdType])
+	case "duration":
+		return parseXsdDuration(text)
+	case "float":
+		// ParseFloat accepts INF, -INF and NaN
+		f, err := strconv.ParseFloat(text, 32)
+		return float32(f), err
+	case "double":
+		return strconv.ParseFloat(text, 64)
+	case "decimal":
+		return ParseDecimal(text)
+	case "byte":
+		i, err := strconv.ParseInt(text, 10, 8)
+		return int8(i), err
+	case "short":
+		i, err := strconv.ParseInt(text, 10, 16)
+		return int16(i), err
+	case "int":
+		i, err := strconv.ParseInt(text, 10, 32)
+		return int(i), err
+	case "long":
+		return strconv.ParseInt(text, 10, 64)
+	case "unsignedByte":
+		u, err := strconv.ParseUint(text, 10, 8)
+		return uint8(u), err
+	case "unsignedShort":
+		u, err := strconv.ParseUint(text, 10, 16)
+		return uint16(u), err
+	case "unsignedInt":
+		u, err := strconv.ParseUint(text, 10, 32)
+		return uint(u), err
+	case "unsignedLong":
+		return strconv.ParseUint(text, 10, 64)
+	default:
+		return nil, errUnknownType
+	}
+}
+
+var errUnknownType = errors.New("unknown type")
+
+// xsdTimeLayouts are the layouts of the XML Schema date and time types without the optional zone.
+// Fractional seconds are optional when parsing.
+var xsdTimeLayouts = map[string]string{
+	"dateTime": "2006-01-02T15:04:05.999999999",
+	"date":     "2006-01-02",
+	"time":     "15:04:05.999999999",
+}
+
+// parseXsdTime parses a date or time with layout and an optional zone. Values without zone are UTC,
+// the date of a time is January 1 of year 0.
+func parseXsdTime(text string, layout string) (time.Time, error) {
+	if t, err := time.Parse(layout+"Z07:00", text); err == nil {
+		return t, nil
+	}
+	return time.Parse(layout, text)
+}
+
+// arrayElementType returns the type of the elements of an ArrayOf* type, e.g. "dateTime" for
+// "ArrayOfDateTime". It returns "" if arrayType is no array type.
+func arrayElementType(arrayType string) string {
+	elemType, ok := strings.CutPrefix(arrayType, "ArrayOf")
+	if !ok || elemType == "" {
+		return ""
+	}
+	if elemType == "QName" {
+		return elemType
+	}
+	return strings.ToLower(elemType[:1]) + elemType[1:]
+}
+
+// Decimal is the lexical form of an xsd:decimal, like "-12.50". Decimal values are decoded as Decimal
+// to keep their precision, for writing a *big.Rat with a finite decimal expansion can be used as well.
+type Decimal string
+
+var decimalPattern = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+
+// ParseDecimal returns s as Decimal, if it is a valid xsd:decimal. Surrounding whitespace is removed.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if !decimalPattern.MatchString(s) {
+		return "", fmt.Errorf("invalid decimal %q", s)
+	}
+	return Decimal(s), nil
+}
+
+// Rat returns the exact value of d.
+func (d Decimal) Rat() (*big.Rat, error) {
+	if !decimalPattern.MatchString(string(d)) {
+		return nil, fmt.Errorf("invalid decimal %q", string(d))
+	}
+	r, _ := new(big.Rat).SetString(strings.TrimPrefix(string(d), "+"))
+	return r, nil
+}
+
+// Float64 returns the nearest float64 value of d.
+func (d Decimal) Float64() (float64, error) {
+	r, err := d.Rat()
+	if err != nil {
+		return 0, err
+	}
+	f, _ := r.Float64()
+	return f, nil
+}
+
+// formatXsdDecimal returns the lexical form of xsd:decimal of a Decimal, *big.Rat, string, integer or
+// finite float.
+func formatXsdDecimal(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case Decimal:
+		d, err := ParseDecimal(string(v))
+		return string(d), err
+	case string:
+		d, err := ParseDecimal(v)
+		return string(d), err
+	case *big.Rat:
+		return formatRat(v)
+	}
+	vo := reflect.ValueOf(value)
+	switch vo.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(vo.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(vo.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := vo.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("decimal: %v has no decimal form", f)
+		}
+		bitSize := 64
+		if vo.Kind() == reflect.Float32 {
+			bitSize = 32
+		}
+		return strconv.FormatFloat(f, 'f', -1, bitSize), nil
+	default:
+		return "", fmt.Errorf("decimal: value of type %T is no decimal", value)
+	}
+}
+
+// formatRat returns r with as many fraction digits as needed, if its denominator has no other
+// prime factors than 2 and 5.
+func formatRat(r *big.Rat) (string, error) {
+	if r == nil {
+		return "", fmt.Errorf("decimal: nil *big.Rat")
+	}
+	denom := new(big.Int).Set(r.Denom())
+	var digits int
+	for _, factor := range []int64{2, 5} {
+		var count int
+		f := big.NewInt(factor)
+		for m := new(big.Int); m.Mod(denom, f).Sign() == 0; count++ {
+			denom.Quo(denom, f)
+		}
+		digits = max(digits, count)
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return "", fmt.Errorf("decimal: %s has no finite decimal expansion", r.RatString())
+	}
+	return r.FloatString(digits), nil
+}
+
+var durationPattern = regexp.MustCompile(
+	`^(-)?P(?:([0-9]+)Y)?(?:([0-9]+)M)?(?:([0-9]+)D)?(?:T(?:([0-9]+)H)?(?:([0-9]+)M)?(?:([0-9]+)(?:\.([0-9]*))?S)?)?$`)
+
+// parseXsdDuration parses an xsd:duration like "PT1H30M" or "-P2DT0.5S". Years and months have no
+// fixed length, so durations with them are rejected, a day is 24 hours.
+func parseXsdDuration(text string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(text)
+	if m == nil || strings.HasSuffix(text, "P") || strings.HasSuffix(text, "T") {
+		return 0, fmt.Errorf("invalid duration %q", text)
+	}
+	if m[2] != "" && strings.Trim(m[2], "0") != "" || m[3] != "" && strings.Trim(m[3], "0") != "" {
+		return 0, fmt.Errorf("duration %q in years or months has no fixed length", text)
+	}
+	nanos := new(big.Int)
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if n, ok := new(big.Int).SetString(cmp.Or(m[4+i], "0"), 10); ok {
+			nanos.Add(nanos, n.Mul(n, big.NewInt(int64(unit))))
+		}
+	}
+	// fractions smaller than a nanosecond are truncated
+	fraction := (m[8] + "000000000")[:9]
+	n, _ := strconv.ParseInt(fraction, 10, 64)
+	nanos.Add(nanos, big.NewInt(n))
+	if m[1] == "-" {
+		nanos.Neg(nanos)
+	}
+	if !nanos.IsInt64() {
+		return 0, fmt.Errorf("duration %q out of range", text)
+	}
+	return time.Duration(nanos.Int64()), nil
+}
+
+// formatXsdDuration returns the lexical form of xsd:duration of a time.Duration in hours, minutes and
+// seconds, e.g. "PT1H30M" or "-PT0.5S". Strings are returned if they are valid durations.
+func formatXsdDuration(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		if !durationPattern.MatchString(v) || strings.HasSuffix(v, "P") || strings.HasSuffix(v, "T") {
+			return "", fmt.Errorf("invalid duration %q", v)
+		}
+		return v, nil
+	case time.Duration:
+		var b strings.Builder
+		// the absolute value of math.MinInt64 only fits into an uint64
+		abs := uint64(v)
+		if v < 0 {
+			b.WriteByte('-')
+			abs = -abs
+		}
+		b.WriteString("PT")
+		hours, minutes := abs/uint64(time.Hour), abs%uint64(time.Hour)/uint64(time.Minute)
+		seconds, nanos := abs%uint64(time.Minute)/uint64(time.Second), abs%uint64(time.Second)
+		if hours > 0 {
+			b.WriteString(strconv.FormatUint(hours, 10) + "H")
+		}
+		if minutes > 0 {
+			b.WriteString(strconv.FormatUint(minutes, 10) + "M")
+		}
+		if seconds > 0 || nanos > 0 || abs == 0 {
+			b.WriteString(strconv.FormatUint(seconds, 10))
+			if nanos > 0 {
+				b.WriteString(strings.TrimRight(fmt.Sprintf(".%09d", nanos), "0"))
+			}
+			b.WriteString("S")
+		}
+		return b.String(), nil
+	default:
+		return "", fmt.Errorf("duration: value of type %T is no time.Duration", value)
+	}
+}
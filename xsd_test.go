@@ -0,0 +1,305 @@
+package gopcxmlda
+
+import (
+	"encoding/xml"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTrip encodes value as TValue and decodes it again.
+func roundTrip(t *testing.T, value TValue) (string, TValue) {
+	t.Helper()
+	type document struct {
+		XMLName xml.Name `xml:"doc"`
+		Value   TValue   `xml:"Value"`
+	}
+	data, err := xml.Marshal(document{Value: value})
+	if err != nil {
+		t.Fatalf("%#v: %v", value.Value, err)
+	}
+	var decoded document
+	if err := xml.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("%s: %v", data, err)
+	}
+	return string(data), decoded.Value
+}
+
+func TestXsdRoundTrip(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	tests := []struct {
+		value interface{}
+		text  string // expected content of the Value element
+	}{
+		{true, ">true<"},
+		{"a < b & \"c\"", ">a &lt; b &amp; &#34;c&#34;<"},
+		{float32(0.1), ">0.1<"},
+		{float32(math.Inf(1)), ">INF<"},
+		{1e21, ">1E+21<"},
+		{-2.5e-7, ">-2.5E-07<"},
+		{math.Inf(-1), ">-INF<"},
+		{123456789.125, ">1.23456789125E+08<"},
+		{time.Date(2024, 1, 2, 3, 4, 5, 123456789, cet), ">2024-01-02T03:04:05.123456789+01:00<"},
+		{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), ">2024-01-02T03:04:05Z<"},
+		{int16(-32768), ">-32768<"},
+		{uint16(65535), ">65535<"},
+		{-2147483648, ">-2147483648<"},
+		{int64(math.MinInt64), ">-9223372036854775808<"},
+		{uint64(math.MaxUint64), ">18446744073709551615<"},
+		{[]interface{}{1.5, math.Inf(1)}, "><double>1.5</double><double>INF</double><"},
+		{[]interface{}{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, "><dateTime>2024-01-02T03:04:05Z</dateTime><"},
+		{[]interface{}{"<", ""}, "><string>&lt;</string><string></string><"},
+		{[]interface{}{uint8(1), uint8(2)}, "><unsignedByte>1</unsignedByte><unsignedByte>2</unsignedByte><"},
+		{90 * time.Minute, ">PT1H30M<"},
+		{time.Duration(math.MinInt64), ">-PT2562047H47M16.854775808S<"},
+		{time.Duration(0), ">PT0S<"},
+		{-1500 * time.Millisecond, ">-PT1.5S<"},
+		{Decimal("-0012.340"), ">-0012.340<"},
+		{[]interface{}{Decimal("1"), Decimal(".5")}, "><decimal>1</decimal><decimal>.5</decimal><"},
+		{[]interface{}{1, "a", nil, 2.5, []interface{}{true}}, `><anyType xsi:type="xsd:int">1</anyType><anyType xsi:type="xsd:string">a</anyType>` +
+			`<anyType xsi:nil="true"></anyType><anyType xsi:type="xsd:double">2.5</anyType>` +
+			`<anyType xsi:type="ns0:ArrayOfBoolean"><boolean>true</boolean></anyType><`},
+		{[]interface{}{}, `xsi:type="ns0:ArrayOfAnyType"></Value>`},
+	}
+	for _, test := range tests {
+		data, decoded := roundTrip(t, TValue{Value: test.value})
+		if !strings.Contains(data, test.text) {
+			t.Errorf("%#v encoded as %s, want %s", test.value, data, test.text)
+		}
+		if !reflect.DeepEqual(decoded.Value, test.value) {
+			if tm, ok := test.value.(time.Time); ok && tm.Equal(decoded.Value.(time.Time)) {
+				continue
+			}
+			t.Errorf("%#v decoded as %#v", test.value, decoded.Value)
+		}
+	}
+
+	// single values keep the Go types of earlier versions, the elements of arrays round-trip
+	decodedTypes := []struct {
+		value   interface{}
+		text    string
+		decoded interface{}
+	}{
+		{int8(-128), ">-128<", int16(-128)},
+		{uint8(255), ">255<", uint16(255)},
+		{uint(4294967295), ">4294967295<", uint64(4294967295)},
+		{[]byte("\x00\xffhello"), ">AP9oZWxsbw==<", "AP9oZWxsbw=="},
+		{[]interface{}{int8(-1), uint(1), []byte("x")}, `><anyType xsi:type="xsd:byte">-1</anyType>` +
+			`<anyType xsi:type="xsd:unsignedInt">1</anyType><anyType xsi:type="xsd:base64Binary">eA==</anyType><`,
+			[]interface{}{int16(-1), uint64(1), "eA=="}},
+		{[]interface{}{int8(-1)}, "><byte>-1</byte><", []interface{}{int8(-1)}},
+		{[]interface{}{uint(1)}, "><unsignedInt>1</unsignedInt><", []interface{}{uint(1)}},
+	}
+	for _, test := range decodedTypes {
+		data, decoded := roundTrip(t, TValue{Value: test.value})
+		if !strings.Contains(data, test.text) {
+			t.Errorf("%#v encoded as %s, want %s", test.value, data, test.text)
+		}
+		if !reflect.DeepEqual(decoded.Value, test.decoded) {
+			t.Errorf("%#v decoded as %#v, want %#v", test.value, decoded.Value, test.decoded)
+		}
+	}
+
+	// XML Schema types keep the prefix xsd, only the OPC XML-DA types use Namespace
+	prefixes := []struct {
+		value     interface{}
+		valueType string
+		xsType    string
+	}{
+		{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), "", "xsd:dateTime"},
+		{[]byte("x"), "", "xsd:base64Binary"},
+		{2.5, "", "xsd:double"},
+		{[]int32{1}, "", "ns1:ArrayOfInt"},
+		{TQuality{QualityField: "good"}, "OPCQuality", "ns1:OPCQuality"},
+	}
+	for _, test := range prefixes {
+		if data, decoded := roundTrip(t, TValue{Type: test.valueType, Value: test.value, Namespace: "ns1"}); !strings.Contains(data, `xsi:type="`+test.xsType+`"`) ||
+			decoded.Namespace+":"+decoded.Type != test.xsType {
+			t.Errorf("%#v encoded as %s", test.value, data)
+		}
+	}
+	handle := "1"
+	payload, err := buildWritePayload(&Server{}, "ns1", []TItem{{ItemName: "t", Value: TValue{Value: time.Unix(0, 0)}},
+		{ItemName: "b", Value: TValue{Value: []byte("x")}}, {ItemName: "a", Value: TValue{Value: []float64{1}}}}, &handle, &[]string{"1", "2", "3"}, TRequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, xsType := range []string{"xsd:dateTime", "xsd:base64Binary", "ns1:ArrayOfDouble"} {
+		if !strings.Contains(payload, `xsi:type="`+xsType+`"`) {
+			t.Errorf("no %s in %s", xsType, payload)
+		}
+	}
+
+	_, decoded := roundTrip(t, TValue{Value: math.NaN()})
+	if f, ok := decoded.Value.(float64); !ok || !math.IsNaN(f) {
+		t.Errorf("NaN decoded as %#v", decoded.Value)
+	}
+	if data, decoded := roundTrip(t, TValue{}); decoded.Value != nil || !strings.Contains(data, `<Value xsi:nil="true"></Value>`) {
+		t.Errorf("nil encoded as %s, decoded as %#v", data, decoded.Value)
+	}
+	if data, decoded := roundTrip(t, TValue{Value: big.NewRat(-5, 8)}); decoded.Value != Decimal("-0.625") {
+		t.Errorf("*big.Rat encoded as %s, decoded as %#v", data, decoded.Value)
+	}
+	if data, decoded := roundTrip(t, TValue{Type: "decimal", Value: 1e21}); decoded.Value != Decimal("1000000000000000000000") {
+		t.Errorf("float64 as decimal encoded as %s, decoded as %#v", data, decoded.Value)
+	}
+	if data, decoded := roundTrip(t, TValue{Type: "ArrayOfQName", Value: []string{"xsd:int", "ns1:Item"}}); !reflect.DeepEqual(decoded.Value, []interface{}{"xsd:int", "ns1:Item"}) {
+		t.Errorf("ArrayOfQName encoded as %s, decoded as %#v", data, decoded.Value)
+	}
+	if _, decoded := roundTrip(t, TValue{Value: []int32{1, 2}}); !reflect.DeepEqual(decoded.Value, []interface{}{1, 2}) {
+		t.Errorf("[]int32 decoded as %#v", decoded.Value)
+	}
+}
+
+func TestXsdEncodeErrors(t *testing.T) {
+	for _, value := range []TValue{
+		{Value: "a\x00b"},
+		{Value: struct{}{}},
+		{Type: "ArrayOfInt", Value: []interface{}{1, nil}},
+		{Type: "duration", Value: 1.5},
+		{Type: "decimal", Value: math.NaN()},
+		{Value: big.NewRat(1, 3)},
+		{Value: Decimal("1e3")},
+		{Value: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Type: "ArrayOfInt", Value: 1},
+	} {
+		if _, err := xml.Marshal(value); err == nil {
+			t.Errorf("no error for %#v", value)
+		}
+	}
+}
+
+func TestDecodeNilArrayElements(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []interface{}
+	}{
+		{`<Value xsi:type="ArrayOfAnyType"><anyType xsi:type="xsd:int">1</anyType><anyType xsi:nil="true"/>` +
+			`<anyType xsi:type="xsd:string">a</anyType></Value>`, []interface{}{1, nil, "a"}},
+		{`<Value xsi:type="ArrayOfDouble"><double xsi:nil="true"></double><double>1.5</double></Value>`, []interface{}{nil, 1.5}},
+		{`<Value xsi:type="ArrayOfString"><string xsi:nil="1"/><string/></Value>`, []interface{}{nil, ""}},
+	}
+	for _, test := range tests {
+		var decoded struct {
+			Value TValue `xml:"Value"`
+		}
+		doc := `<doc xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` + test.value + `</doc>`
+		if err := xml.Unmarshal([]byte(doc), &decoded); err != nil {
+			t.Errorf("%s: %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded.Value.Value, test.want) {
+			t.Errorf("%s decoded as %#v", test.value, decoded.Value.Value)
+		}
+	}
+}
+
+func TestXsdDecode(t *testing.T) {
+	cest := time.FixedZone("CEST", 2*3600)
+	tests := []struct {
+		xsdType string
+		text    string
+		value   interface{}
+	}{
+		{"double", " -INF ", math.Inf(-1)},
+		{"float", "1.5e3", float32(1500)},
+		{"boolean", "1", true},
+		{"int", "+42", 42},
+		{"string", " x ", " x "},
+		{"base64Binary", "AP9o\n ZWxsbw==", []byte("\x00\xffhello")},
+		{"dateTime", "2024-01-02T03:04:05.5Z", time.Date(2024, 1, 2, 3, 4, 5, 5e8, time.UTC)},
+		{"dateTime", "2024-01-02T03:04:05+02:00", time.Date(2024, 1, 2, 3, 4, 5, 0, cest)},
+		{"dateTime", "2024-01-02T03:04:05.123", time.Date(2024, 1, 2, 3, 4, 5, 123e6, time.UTC)},
+		{"date", "2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"date", "2024-01-02Z", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"date", "2024-01-02+02:00", time.Date(2024, 1, 2, 0, 0, 0, 0, cest)},
+		{"time", "12:00:00", time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"time", "12:00:00.25-05:00", time.Date(0, 1, 1, 12, 0, 0, 25e7, time.FixedZone("", -5*3600))},
+		{"time", " 12:00:00Z ", time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"duration", "-P1DT2H3M4.0000000059S", -(26*time.Hour + 3*time.Minute + 4*time.Second + 5)},
+		{"duration", "P0Y0M1D", 24 * time.Hour},
+		{"duration", "PT1.S", time.Second},
+		{"decimal", " +1.50 ", Decimal("+1.50")},
+		{"anyType", " x ", " x "},
+	}
+	for _, test := range tests {
+		value, err := parseXsd(test.text, test.xsdType)
+		if tm, ok := test.value.(time.Time); ok && err == nil {
+			if parsed, ok := value.(time.Time); ok && parsed.Equal(tm) {
+				_, offset := parsed.Zone()
+				if _, want := tm.Zone(); offset == want {
+					continue
+				}
+			}
+		}
+		if err != nil || !reflect.DeepEqual(value, test.value) {
+			t.Errorf("%s %q: got %#v, %v", test.xsdType, test.text, value, err)
+		}
+	}
+	for _, test := range []struct{ xsdType, text string }{
+		{"int", "2147483648"},
+		{"unsignedByte", "-1"},
+		{"byte", "1.0"},
+		{"boolean", "yes"},
+		{"base64Binary", "%%"},
+		{"foo", "1"},
+		{"duration", "P"},
+		{"duration", "P1DT"},
+		{"duration", "P1M"},
+		{"duration", "PT1H2S3M"},
+		{"duration", "PT9999999999H"},
+		{"decimal", "1e3"},
+		{"date", "2024-01-02T03:04:05Z"},
+		{"time", "2024-01-02"},
+		{"dateTime", "2024-01-02"},
+		{"decimal", "."},
+	} {
+		if value, err := parseXsd(test.text, test.xsdType); err == nil {
+			t.Errorf("%s %q: no error, got %#v", test.xsdType, test.text, value)
+		}
+	}
+}
+
+func TestDecodeValue(t *testing.T) {
+	tests := []struct {
+		xml   string
+		value TValue
+	}{
+		{`<Value xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:x="http://www.w3.org/2001/XMLSchema" ` +
+			`ItemName="a" xsi:type="x:int">3</Value>`, TValue{Type: "int", Namespace: "x", Value: 3}},
+		{`<Value xsi:type="int">3</Value>`, TValue{Type: "int", Value: 3}},
+		{`<Value>text</Value>`, TValue{Value: "text"}},
+		{`<Value/>`, TValue{Value: ""}},
+		{`<Value xsi:type="xsd:double" xsi:nil="true"/>`, TValue{Type: "double", Namespace: "xsd"}},
+		{`<Value xsi:type="ns0:ArrayOfAnyType"><anyType xsi:type="xsd:duration">PT1M</anyType>` +
+			`<anyType xsi:nil="1"/><anyType>s</anyType></Value>`,
+			TValue{Type: "ArrayOfAnyType", Namespace: "ns0", Value: []interface{}{time.Minute, nil, "s"}}},
+	}
+	for _, test := range tests {
+		var value TValue
+		if err := xml.Unmarshal([]byte(test.xml), &value); err != nil {
+			t.Errorf("%s: %v", test.xml, err)
+		} else if !reflect.DeepEqual(value, test.value) {
+			t.Errorf("%s: got %#v", test.xml, value)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	d, err := ParseDecimal(" -1.25 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := d.Rat(); err != nil || r.Cmp(big.NewRat(-5, 4)) != 0 {
+		t.Errorf("Rat() = %v, %v", r, err)
+	}
+	if f, err := d.Float64(); err != nil || f != -1.25 {
+		t.Errorf("Float64() = %v, %v", f, err)
+	}
+	if _, err := Decimal("1/3").Rat(); err == nil {
+		t.Error("no error for 1/3")
+	}
+}